@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/zsoftly/ztigit/internal/config"
+	"github.com/zsoftly/ztigit/internal/mirror"
+)
+
+// Clean command
+var cleanCmd = &cobra.Command{
+	Use:   "clean <dir>",
+	Short: "Find broken clones and stray temporary files",
+	Long: `Find what interrupted mirror runs leave behind in a mirror directory: clones
+that git no longer accepts as a repository, and the temporary files of unfinished
+state file writes and release downloads.
+
+Nothing is removed unless --delete is given. A removed clone is cloned again by
+the next mirror run.
+
+Examples:
+  ztigit clean ~/company
+  ztigit clean ~/company --delete`,
+	Args: cobra.ExactArgs(1),
+	RunE: runClean,
+}
+
+var cleanDelete bool
+
+func init() {
+	cleanCmd.Flags().BoolVar(&cleanDelete, "delete", false, "Remove the broken clones and temporary files found")
+	rootCmd.AddCommand(cleanCmd)
+}
+
+func runClean(cmd *cobra.Command, args []string) error {
+	if err := mirror.CheckGitInstalled(); err != nil {
+		return err
+	}
+	baseDir := config.ExpandPath(args[0])
+
+	fmt.Printf("%s Scanning %s...\n\n", cyan("→"), bold(baseDir))
+	leftovers, err := mirror.FindLeftovers(context.Background(), baseDir)
+	if err != nil {
+		return err
+	}
+	mirror.PrintLeftovers(leftovers, baseDir)
+	if len(leftovers) == 0 {
+		return nil
+	}
+
+	if !cleanDelete {
+		fmt.Printf("\n%s Run again with --delete to remove them\n", yellow("!"))
+		return nil
+	}
+	removed, err := mirror.RemoveLeftovers(leftovers)
+	fmt.Printf("\n%s Removed %d of %d\n", green("✓"), removed, len(leftovers))
+	return err
+}
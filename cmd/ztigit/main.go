@@ -4,14 +4,25 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/zsoftly/ztigit/internal/config"
 	"github.com/zsoftly/ztigit/internal/mirror"
 	"github.com/zsoftly/ztigit/internal/protect"
@@ -21,6 +32,7 @@ import (
 var (
 	cyan   = color.New(color.FgCyan).SprintFunc()
 	green  = color.New(color.FgGreen).SprintFunc()
+	red    = color.New(color.FgRed).SprintFunc()
 	yellow = color.New(color.FgYellow).SprintFunc()
 	bold   = color.New(color.Bold).SprintFunc()
 )
@@ -30,27 +42,104 @@ var (
 	cfg     *config.Config
 )
 
+// exitTokenExpired is the exit status when the provider token expired or was
+// revoked during a run, so automation can tell it apart from other failures
+const exitTokenExpired = 3
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
+		var expired *tokenExpiredError
+		if errors.As(err, &expired) {
+			os.Exit(exitTokenExpired)
+		}
 		os.Exit(1)
 	}
 }
 
+// tokenExpiredError replaces the raw 401 errors of a token that stopped working mid-run
+type tokenExpiredError struct {
+	provider provider.ProviderType
+	err      error
+}
+
+func (e *tokenExpiredError) Error() string {
+	name := string(e.provider)
+	switch e.provider {
+	case provider.ProviderGitHub:
+		name = "GitHub"
+	case provider.ProviderGitLab:
+		name = "GitLab"
+	case provider.ProviderBitbucket:
+		name = "Bitbucket"
+	}
+	return fmt.Sprintf(`Your %s token appears to have expired or been revoked
+
+  The %s API rejected the token partway through the run (%v).
+  Create a new token, then save it with:
+      export %s_TOKEN=<new-token>
+      ztigit auth login -p %s
+  and run the command again.`, name, name, e.err, strings.ToUpper(string(e.provider)), e.provider)
+}
+
+func (e *tokenExpiredError) Unwrap() error {
+	return e.err
+}
+
+// checkTokenExpired turns a 401 from the provider API into a tokenExpiredError
+func checkTokenExpired(providerType provider.ProviderType, err error) error {
+	if err != nil && provider.IsUnauthorized(err) {
+		return &tokenExpiredError{provider: providerType, err: err}
+	}
+	return err
+}
+
 var rootCmd = &cobra.Command{
 	Use:     "ztigit",
 	Short:   "ZSoftly Tools for Git - Multi-platform Git hosting CLI",
 	Long:    `ztigit is a cross-platform CLI tool for managing GitLab and GitHub repositories.`,
 	Version: version,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if envFile != "" {
+			ignored, err := config.LoadEnvFile(config.ExpandPath(envFile))
+			if err != nil {
+				return err
+			}
+			if len(ignored) > 0 {
+				fmt.Fprintf(os.Stderr, "%s %s: ignored %s (only ZTIGIT_ variables and provider tokens and URLs are loaded)\n",
+					yellow("!"), envFile, strings.Join(ignored, ", "))
+			}
+		}
+
 		var err error
 		cfg, err = config.Load()
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
+
+		// Log every API call to stderr (flag or config)
+		if debugFlag || cfg.Debug {
+			provider.DebugOutput = os.Stderr
+		}
 		return nil
 	},
 }
 
+var (
+	envFile   string
+	debugFlag bool
+
+	// noTestConnection skips the upfront TestConnection of commands that make API calls
+	noTestConnection bool
+)
+
+// noTestConnectionUsage is the help text of --no-test-connection, shared by the commands that have it
+const noTestConnectionUsage = "Skip the upfront API auth check; the first real API call reports auth errors"
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&envFile, "env-file", "", "Load tokens and URLs from a .env file (e.g., .env)")
+	rootCmd.PersistentFlags().BoolVar(&debugFlag, "debug", false, "Log every API request (method, URL, status, duration) to stderr")
+}
+
 // Mirror command
 var mirrorCmd = &cobra.Command{
 	Use:   "mirror <url-or-org>",
@@ -74,6 +163,9 @@ Examples:
   # Include older repos (default skips repos not updated in 12 months)
   ztigit mirror zsoftly -p github --max-age 24
 
+  # Repos matching a search query (e.g., a topic across orgs)
+  ztigit mirror --search "topic:terraform org:zsoftly" -p github
+
 Repositories are cloned to $HOME/<org>/ by default.
 Skips archived repos and repos not updated within --max-age months.
 Authentication: Expects GITHUB_TOKEN/GITLAB_TOKEN env vars for API access.
@@ -85,41 +177,377 @@ Git operations use your existing git credentials (HTTPS or SSH).`,
 var (
 	mirrorProvider      string
 	mirrorDir           string
-	mirrorParallel      int
+	mirrorParallel      string
 	mirrorVerbose       bool
+	mirrorQuiet         bool
 	mirrorMaxAge        int
 	mirrorSkipPreflight bool
 	mirrorSSH           bool
 	mirrorGroups        string
+	mirrorSearch        string
+	mirrorStripPrefix   string
+	mirrorReleases      bool
+	mirrorFailFast      bool
+	mirrorSSHPrivate    bool
+	mirrorSubmodules    bool
+	mirrorSubmoduleJobs int
+	mirrorProtocolV2    bool
+	mirrorFetchJobs     int
+	mirrorOutput        string
+	mirrorSummaryFormat string
+	mirrorFlatten       bool
+	mirrorRefreshHead   bool
+	mirrorUpdateRemotes bool
+	mirrorCheckPaths    bool
+	mirrorCountOnly     bool
+	mirrorCheckOnly     bool
+	mirrorStrictAge     bool
+	mirrorDirMode       string
+	mirrorGitConfig     []string
+	mirrorSSHConfig     string
+	mirrorOnCollision   string
+	mirrorBare          bool
+	mirrorTrackBranches string
+	mirrorPruneTags     bool
+	mirrorResume        bool
+	mirrorMarkArchived  bool
+	mirrorRedirects     bool
+	mirrorMembers       bool
+	mirrorYes           bool
+	mirrorLockfile      string
+	mirrorSummaryFile   string
+	mirrorSummaryRepos  bool
+	mirrorMaxRuntime    time.Duration
+	mirrorStaleOnly     time.Duration
+	mirrorDryRun        bool
+	mirrorGrep          string
+	mirrorPathPrefix    string
+	mirrorExclPersonal  bool
+	mirrorNameRegex     string
+	mirrorNameExclude   string
+	mirrorLanguage      string
+	mirrorMaxSize       string
+	mirrorExcludeEmpty  bool
+	mirrorProbeSize     bool
+	mirrorLogFile       string
+	mirrorAuditLog      string
+	mirrorFilter        string
+	mirrorDepth         int
+	mirrorSnapshot      bool
+	mirrorLinkPrevious  bool
+	mirrorKeepSnapshots int
+	mirrorSinceLastRun  bool
+	mirrorCheckpoint    time.Duration
+	mirrorMarkerFile    string
+	mirrorTargetsFile   string
+	mirrorFailuresFile  string
 )
 
 func init() {
-	mirrorCmd.Flags().StringVarP(&mirrorProvider, "provider", "p", "", "Provider type: gitlab or github (auto-detected from URL)")
+	mirrorCmd.Flags().StringVarP(&mirrorProvider, "provider", "p", "", "Provider type: gitlab, github, or bitbucket (auto-detected from URL)")
 	mirrorCmd.Flags().StringVarP(&mirrorDir, "dir", "d", "", "Base directory (default: $HOME/<org>)")
-	mirrorCmd.Flags().IntVar(&mirrorParallel, "parallel", 4, "Number of parallel clone/pull operations")
+	mirrorCmd.Flags().StringVar(&mirrorDirMode, "dir-mode", "", "Octal mode for created directories and clones (e.g., 0750; default: 0755 minus umask)")
+	mirrorCmd.Flags().StringVar(&mirrorParallel, "parallel", "4", "Number of parallel clone/pull operations, or 'auto' to size from the CPU count")
 	mirrorCmd.Flags().BoolVarP(&mirrorVerbose, "verbose", "v", false, "Verbose output")
+	mirrorCmd.Flags().BoolVarP(&mirrorQuiet, "quiet", "q", false, "Skip the connection lines before the run, so output starts with the repos")
 	mirrorCmd.Flags().IntVar(&mirrorMaxAge, "max-age", 12, "Skip repos not updated in this many months (0 = no limit)")
+	mirrorCmd.Flags().BoolVar(&mirrorStrictAge, "strict-age", false, "Age repos by the default branch's last commit (one API call per repo) instead of provider activity")
 	mirrorCmd.Flags().BoolVar(&mirrorSkipPreflight, "skip-preflight", false, "Skip git credential validation before cloning")
+	mirrorCmd.Flags().BoolVar(&noTestConnection, "no-test-connection", false, noTestConnectionUsage)
 	mirrorCmd.Flags().BoolVar(&mirrorSSH, "ssh", false, "Use SSH URLs instead of HTTPS for git operations")
 	mirrorCmd.Flags().StringVar(&mirrorGroups, "groups", "", "Space-separated list of groups to mirror (e.g., \"group1 group2 group3\")")
+	mirrorCmd.Flags().StringVar(&mirrorSearch, "search", "", "Mirror repos matching a provider search query instead of a group")
+	mirrorCmd.Flags().StringVar(&mirrorTargetsFile, "targets-file", "", "Mirror the repos listed in this file (full paths or clone URLs, one per line) instead of a group")
+	mirrorCmd.Flags().StringVar(&mirrorFailuresFile, "report-failures-file", "", "Write the full paths of failed, aborted, and timed-out repos to this file, for a retry with --targets-file")
+	mirrorCmd.Flags().StringVar(&mirrorPathPrefix, "path-prefix", "", "Only mirror repos at or below this group path (e.g., company/infra)")
+	mirrorCmd.Flags().BoolVar(&mirrorExclPersonal, "exclude-personal", false, "Skip repos in the authenticated user's personal namespace")
+	mirrorCmd.Flags().StringVar(&mirrorGrep, "grep", "", "Only mirror repos whose name, path, or description contains this text (case-insensitive)")
+	mirrorCmd.Flags().StringVar(&mirrorNameRegex, "name-regex", "", "Only mirror repos whose name matches this Go regular expression")
+	mirrorCmd.Flags().StringVar(&mirrorNameExclude, "name-regex-exclude", "", "Skip repos whose name matches this Go regular expression (wins over --name-regex)")
+	mirrorCmd.Flags().StringVar(&mirrorLanguage, "language", "", "Only mirror repos whose primary language is one of these (comma-separated, e.g., \"Go,Python\")")
+	mirrorCmd.Flags().StringVar(&mirrorMaxSize, "max-size", "", "Skip repos larger than this (e.g., 500MB, 2GB); repos with no reported size are kept")
+	mirrorCmd.Flags().BoolVar(&mirrorExcludeEmpty, "exclude-size-zero", false, "Skip repos listed with a size of 0, which are usually empty (repos listed without a size are kept)")
+	mirrorCmd.Flags().BoolVar(&mirrorProbeSize, "probe-size", false, "With --max-size or --exclude-size-zero, look up sizes the listing did not include (one API call per such repo)")
+	mirrorCmd.Flags().StringVar(&mirrorStripPrefix, "strip-prefix", "", "Leading path segments to drop from the local layout (e.g., \"company/division\")")
+	mirrorCmd.Flags().BoolVar(&mirrorReleases, "mirror-releases", false, "Download release assets into <repo>/.ztigit-releases/<tag>/")
+	mirrorCmd.Flags().BoolVar(&mirrorFailFast, "fail-fast", false, "Stop starting new repos after the first failure and exit non-zero")
+	mirrorCmd.Flags().BoolVar(&mirrorSSHPrivate, "prefer-ssh-for-private", false, "Clone private repos over SSH and public repos over HTTPS")
+	mirrorCmd.Flags().BoolVar(&mirrorSubmodules, "recurse-submodules", false, "Clone and update submodules recursively")
+	mirrorCmd.Flags().IntVar(&mirrorSubmoduleJobs, "submodule-jobs", 2, "Parallel submodule fetches per repo (with --recurse-submodules)")
+	mirrorCmd.Flags().StringVar(&mirrorFilter, "filter", "", "Partial clone filter for new clones (e.g., blob:none); also applied to submodules with --recurse-submodules")
+	mirrorCmd.Flags().IntVar(&mirrorDepth, "depth", 0, "Shallow clones with only the last N commits, kept at that depth on update (0 = full history)")
+	mirrorCmd.Flags().BoolVar(&mirrorProtocolV2, "protocol-v2", false, "Use git wire protocol v2 for every git command (protocol.version=2)")
+	mirrorCmd.Flags().IntVar(&mirrorFetchJobs, "fetch-jobs", 0, "Parallel remote/submodule fetches within one repo update (fetch.parallel; 0 = git default; alias: --concurrent-fetch-objects)")
+	mirrorCmd.Flags().StringVarP(&mirrorOutput, "output", "o", "text", "Output format: text or github-actions (adds workflow annotations; default when GITHUB_ACTIONS=true)")
+	mirrorCmd.Flags().StringVar(&mirrorSummaryFormat, "summary-format", mirror.SummaryVerbose, "Summary after the results: verbose, compact (one key=value line), or none")
+	mirrorCmd.Flags().BoolVar(&mirrorFlatten, "flatten", false, "Clone to <dir>/<repo-name> instead of preserving the group hierarchy")
+	mirrorCmd.Flags().StringVar(&mirrorOnCollision, "on-collision", mirror.CollisionSuffix, "Repos with the same local path: suffix, skip, or fail")
+	mirrorCmd.Flags().BoolVar(&mirrorRefreshHead, "refresh-default-branch", false, "Update origin/HEAD to the provider's default branch on update")
+	mirrorCmd.Flags().BoolVar(&mirrorUpdateRemotes, "update-remotes", false, "Repoint origin of existing clones when the provider's clone URL changed")
+	mirrorCmd.Flags().BoolVar(&mirrorRedirects, "allow-redirects", false, "Move existing clones of repos that were renamed or transferred upstream")
+	mirrorCmd.Flags().BoolVar(&mirrorCheckPaths, "check-paths", false, "List repos and check local paths against OS and Windows limits without cloning")
+	mirrorCmd.Flags().BoolVar(&mirrorCountOnly, "count-only", false, "Print only the number of repos that would be mirrored (after filters) and exit")
+	mirrorCmd.Flags().BoolVar(&mirrorCheckOnly, "check-only", false, "Check the token and that each group can be listed (fetching a single repo), then exit (alias: --verify-connection-only)")
+	mirrorCmd.Flags().BoolVar(&mirrorDryRun, "dry-run", false, "Show which repos would be cloned, updated, or skipped without running git")
+	mirrorCmd.Flags().BoolVar(&mirrorBare, "bare", false, "Keep bare mirrors (git clone --mirror) at <dir>/<path>.git with HEAD on the default branch")
+	mirrorCmd.Flags().BoolVar(&mirrorMarkArchived, "mark-archived", false, "Stop updating clones of repos archived upstream and write an ARCHIVED marker into them")
+	mirrorCmd.Flags().BoolVar(&mirrorResume, "resume-partial", false, "Repair clones left by an interrupted run: remove stale index.lock files and re-clone unfinished clones")
+	mirrorCmd.Flags().BoolVar(&mirrorPruneTags, "prune-tags", false, "On update, delete local tags and remote branches that were deleted upstream (always on with --bare)")
+	mirrorCmd.Flags().StringVar(&mirrorTrackBranches, "track-branches", "", "Keep a local branch for every remote branch matching this glob (e.g., \"release/*\"), fast-forwarded on update")
+	mirrorCmd.Flags().BoolVar(&mirrorMembers, "include-members-repos", false, "Also mirror repos owned by each org member into member/<user>/<repo> (asks for confirmation)")
+	mirrorCmd.Flags().StringVar(&mirrorMarkerFile, "marker-file", "", "Only mirror repos updated after the timestamp in this file, and advance it after a successful run")
+	mirrorCmd.Flags().BoolVar(&mirrorSinceLastRun, "since-last-run", false, "Only update existing clones of repos updated since the last successful run (new repos are still cloned)")
+	mirrorCmd.Flags().DurationVar(&mirrorStaleOnly, "refresh-stale-only", 0, "Only update existing clones last fetched longer ago than this (e.g., 24h), for rolling refreshes (new repos are still cloned)")
+	mirrorCmd.Flags().DurationVar(&mirrorCheckpoint, "checkpoint-interval", 0, "Save the repos synced so far to the state file this often (e.g., 10m), so --since-last-run can resume a crashed run")
+	mirrorCmd.Flags().DurationVar(&mirrorMaxRuntime, "max-runtime", 0, "Stop syncing this long after the run starts (e.g., 2h30m): in-flight repos are cancelled, none are started; listing time counts")
+	mirrorCmd.Flags().StringVar(&mirrorLogFile, "log-file", "", "Append the output of every git command to this file")
+	mirrorCmd.Flags().StringVar(&mirrorAuditLog, "audit-log", "", "Append an NDJSON record of every git command run (credentials redacted) to this file")
+	mirrorCmd.Flags().BoolVar(&mirrorSnapshot, "snapshot", false, "Mirror into a new <dir>/<YYYY-MM-DD-HHMMSS>/ directory for point-in-time backups")
+	mirrorCmd.Flags().BoolVar(&mirrorLinkPrevious, "link-previous", false, "With --snapshot, hardlink unchanged objects from the previous snapshot instead of cloning")
+	mirrorCmd.Flags().IntVar(&mirrorKeepSnapshots, "keep-snapshots", 0, "With --snapshot, remove the oldest snapshots so only this many remain (0 = keep all)")
+	mirrorCmd.Flags().StringVar(&mirrorLockfile, "lockfile", "", "Write the commit SHA captured for each repo to this file (JSON)")
+	mirrorCmd.Flags().StringVar(&mirrorSummaryFile, "summary-file", "", "Write the run's summary, start and end time, and arguments to this file (JSON), whatever the output format")
+	mirrorCmd.Flags().BoolVar(&mirrorSummaryRepos, "summary-results", false, "With --summary-file, also write the result of every repo")
+	mirrorCmd.Flags().BoolVarP(&mirrorYes, "yes", "y", false, "Skip confirmation prompts")
+	mirrorCmd.Flags().StringArrayVar(&mirrorGitConfig, "git-config", nil, "Git config for this run only, as key=value (repeatable; not written to disk)")
+	mirrorCmd.Flags().StringVar(&mirrorSSHConfig, "ssh-config", "", "SSH config file to use for git over SSH instead of ~/.ssh/config")
+	mirrorCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		switch name {
+		case "concurrent-fetch-objects":
+			name = "fetch-jobs"
+		case "json-summary-file", "summary-json-file":
+			name = "summary-file"
+		case "verify-connection-only":
+			name = "check-only"
+		}
+		return pflag.NormalizedName(name)
+	})
 	rootCmd.AddCommand(mirrorCmd)
 }
 
 func runMirror(cmd *cobra.Command, args []string) error {
-	// Check git is installed before doing anything else
-	if err := mirror.CheckGitInstalled(); err != nil {
-		return err
+	// --count-only output is meant for shell arithmetic: status messages go to stderr,
+	// so stdout carries only the count
+	var status io.Writer = os.Stdout
+	if mirrorCountOnly {
+		if mirrorCheckPaths {
+			return fmt.Errorf("--count-only cannot be combined with --check-paths")
+		}
+		status = os.Stderr
+	}
+
+	if mirrorCheckOnly && (mirrorCountOnly || mirrorCheckPaths || mirrorDryRun) {
+		return fmt.Errorf("--check-only cannot be combined with --count-only, --check-paths, or --dry-run")
+	}
+
+	// Check git is installed before doing anything else (--count-only, --check-only, and --dry-run run no git commands)
+	if !mirrorCountOnly && !mirrorCheckOnly && !mirrorDryRun {
+		if err := mirror.CheckGitInstalled(); err != nil {
+			return err
+		}
 	}
 
 	ctx := context.Background()
+	runStarted := time.Now()
+
+	// The time budget starts now, so time spent listing repos counts against it. Only
+	// syncing is cut off at the deadline: listing is not interrupted, and no repo starts
+	// after it.
+	var deadline time.Time
+	if mirrorStaleOnly < 0 {
+		return fmt.Errorf("--refresh-stale-only must not be negative")
+	}
+	if mirrorMaxRuntime < 0 {
+		return fmt.Errorf("--max-runtime must not be negative")
+	}
+	if mirrorMaxRuntime > 0 {
+		deadline = time.Now().Add(mirrorMaxRuntime)
+	}
+
+	// Apply persisted mirror settings where flags were not given (flag > config > built-in)
+	applyMirrorConfigDefaults(cmd)
+
+	// Annotate results automatically inside GitHub Actions; --output text turns it off
+	if !cmd.Flags().Changed("output") && os.Getenv("GITHUB_ACTIONS") == "true" {
+		mirrorOutput = "github-actions"
+	}
+
+	// Expand ~ and environment variables the shell did not (quoted values)
+	mirrorDir = config.ExpandPath(mirrorDir)
+	mirrorLockfile = config.ExpandPath(mirrorLockfile)
+	mirrorSummaryFile = config.ExpandPath(mirrorSummaryFile)
+	mirrorMarkerFile = config.ExpandPath(mirrorMarkerFile)
+	mirrorTargetsFile = config.ExpandPath(mirrorTargetsFile)
+	mirrorFailuresFile = config.ExpandPath(mirrorFailuresFile)
+	mirrorLogFile = config.ExpandPath(mirrorLogFile)
+	mirrorAuditLog = config.ExpandPath(mirrorAuditLog)
+
+	if mirrorOutput != "text" && mirrorOutput != "github-actions" {
+		return fmt.Errorf("invalid output format: %q (must be 'text' or 'github-actions')", mirrorOutput)
+	}
+	switch mirrorSummaryFormat {
+	case mirror.SummaryVerbose, mirror.SummaryCompact, mirror.SummaryNone:
+	default:
+		return fmt.Errorf("invalid summary format: %q (must be 'verbose', 'compact', or 'none')", mirrorSummaryFormat)
+	}
+	switch mirrorOnCollision {
+	case mirror.CollisionSuffix, mirror.CollisionSkip, mirror.CollisionFail:
+	default:
+		return fmt.Errorf("invalid --on-collision: %q (must be 'suffix', 'skip', or 'fail')", mirrorOnCollision)
+	}
+	if mirrorFlatten && mirrorStripPrefix != "" {
+		return fmt.Errorf("--flatten cannot be combined with --strip-prefix")
+	}
+	gitConfig, err := parseGitConfigFlags(mirrorProtocolV2, mirrorGitConfig)
+	if err != nil {
+		return err
+	}
+	if mirrorSSHConfig != "" {
+		// Absolute, since git runs ssh from inside each clone
+		abs, err := filepath.Abs(config.ExpandPath(mirrorSSHConfig))
+		if err != nil {
+			return fmt.Errorf("invalid --ssh-config: %w", err)
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return fmt.Errorf("invalid --ssh-config: %w", err)
+		}
+		mirrorSSHConfig = abs
+	}
+	if mirrorSSHPrivate && cmd.Flags().Changed("ssh") {
+		return fmt.Errorf("--prefer-ssh-for-private cannot be combined with --ssh")
+	}
+	if cmd.Flags().Changed("submodule-jobs") && !mirrorSubmodules {
+		return fmt.Errorf("--submodule-jobs requires --recurse-submodules")
+	}
+	if mirrorFetchJobs < 0 {
+		return fmt.Errorf("--fetch-jobs must not be negative")
+	}
+	if mirrorSubmoduleJobs < 1 {
+		return fmt.Errorf("--submodule-jobs must be at least 1")
+	}
+	if mirrorDepth < 0 {
+		return fmt.Errorf("--depth must not be negative")
+	}
+	if mirrorBare && mirrorDepth > 0 {
+		return fmt.Errorf("--bare cannot be combined with --depth")
+	}
+	if mirrorBare && mirrorSubmodules {
+		return fmt.Errorf("--bare cannot be combined with --recurse-submodules")
+	}
+	if mirrorBare && mirrorReleases {
+		return fmt.Errorf("--bare cannot be combined with --mirror-releases")
+	}
+	if mirrorBare && mirrorTrackBranches != "" {
+		return fmt.Errorf("--bare cannot be combined with --track-branches (bare mirrors already keep every branch)")
+	}
+	if _, err := path.Match(mirrorTrackBranches, ""); err != nil {
+		return fmt.Errorf("invalid --track-branches pattern %q: %w", mirrorTrackBranches, err)
+	}
+	var nameRegex, nameExclude *regexp.Regexp
+	if mirrorNameRegex != "" {
+		re, err := regexp.Compile(mirrorNameRegex)
+		if err != nil {
+			return fmt.Errorf("invalid --name-regex: %w", err)
+		}
+		nameRegex = re
+	}
+	if mirrorNameExclude != "" {
+		re, err := regexp.Compile(mirrorNameExclude)
+		if err != nil {
+			return fmt.Errorf("invalid --name-regex-exclude: %w", err)
+		}
+		nameExclude = re
+	}
+	var maxSize int64
+	if mirrorMaxSize != "" {
+		size, err := mirror.ParseSize(mirrorMaxSize)
+		if err != nil {
+			return fmt.Errorf("--max-size: %w", err)
+		}
+		maxSize = size
+	}
+	if mirrorProbeSize && maxSize == 0 && !mirrorExcludeEmpty {
+		return fmt.Errorf("--probe-size requires --max-size or --exclude-size-zero")
+	}
+	var dirMode os.FileMode
+	if mirrorDirMode != "" {
+		mode, err := mirror.ParseDirMode(mirrorDirMode)
+		if err != nil {
+			return err
+		}
+		dirMode = mode
+	}
+	// Submodule filtering needs git 2.36; older git still gets a partial superproject
+	filterSubmodules := mirrorFilter != "" && mirrorSubmodules
+	if filterSubmodules {
+		if ok, err := mirror.GitAtLeast(2, 36); !ok {
+			reason := "git 2.36 or later is required"
+			if err != nil {
+				reason = err.Error()
+			}
+			fmt.Fprintf(status, "%s Submodules will be cloned in full (%s)\n\n", yellow("!"), reason)
+			filterSubmodules = false
+		}
+	}
+	if mirrorStrictAge && mirrorMaxAge == 0 {
+		return fmt.Errorf("--strict-age requires --max-age")
+	}
+	if (mirrorLinkPrevious || mirrorKeepSnapshots != 0) && !mirrorSnapshot {
+		return fmt.Errorf("--link-previous and --keep-snapshots require --snapshot")
+	}
+	if mirrorCheckpoint < 0 {
+		return fmt.Errorf("--checkpoint-interval must not be negative")
+	}
+	if mirrorKeepSnapshots < 0 {
+		return fmt.Errorf("--keep-snapshots must not be negative")
+	}
+	parallel, cloneParallel, updateParallel, err := parseParallel(mirrorParallel)
+	if err != nil {
+		return err
+	}
+	if mirrorParallel == "auto" && mirrorVerbose {
+		fmt.Fprintf(status, "%s Parallel: %d clones, %d updates (%d CPUs)\n", cyan("→"), cloneParallel, updateParallel, runtime.NumCPU())
+	}
 
 	// Determine groups to mirror
-	var groups []string
+	var groups, targets []string
 	var baseURL string
 	var providerType provider.ProviderType
 
-	// Case 1: --groups flag provided (space-separated)
-	if mirrorGroups != "" {
+	if mirrorTargetsFile != "" {
+		// Repos listed in a file, e.g. the failures of an earlier run
+		if mirrorSearch != "" || mirrorGroups != "" || len(args) > 0 {
+			return fmt.Errorf("--targets-file cannot be combined with groups or --search")
+		}
+		if mirrorMarkerFile != "" {
+			return fmt.Errorf("--targets-file cannot be combined with --marker-file")
+		}
+		if mirrorProvider == "" {
+			return fmt.Errorf("--provider required when using --targets-file")
+		}
+		targets, err = mirror.ReadTargets(mirrorTargetsFile)
+		if err != nil {
+			return err
+		}
+		// --report-failures-file leaves an empty file after a clean run
+		if len(targets) == 0 {
+			fmt.Fprintf(status, "%s Nothing to retry: %s lists no repos\n", green("✓"), mirrorTargetsFile)
+			return nil
+		}
+		providerType = provider.ProviderType(mirrorProvider)
+		baseURL = cfg.GetBaseURL(string(providerType))
+	} else if mirrorSearch != "" {
+		// Case 0: --search query replaces group listing
+		if mirrorGroups != "" || len(args) > 0 {
+			return fmt.Errorf("--search cannot be combined with groups")
+		}
+		if mirrorProvider == "" {
+			return fmt.Errorf("--provider required when using --search")
+		}
+		providerType = provider.ProviderType(mirrorProvider)
+		baseURL = cfg.GetBaseURL(string(providerType))
+	} else if mirrorGroups != "" {
+		// Case 1: --groups flag provided (space-separated)
 		groups = strings.Fields(mirrorGroups)
 
 		if len(groups) == 0 {
@@ -192,7 +620,7 @@ func runMirror(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get token from environment or config (optional for public repos)
-	token := cfg.GetToken(string(providerType))
+	token := cfg.GetToken(string(providerType), baseURL)
 
 	// Security: reject HTTP URLs when token is present
 	if err := validateURLSecurity(baseURL, token); err != nil {
@@ -200,43 +628,139 @@ func runMirror(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create provider
-	var p provider.Provider
-	var err error
-
-	switch providerType {
-	case provider.ProviderGitLab:
-		p, err = provider.NewGitLabProvider(token, baseURL)
-	case provider.ProviderGitHub:
-		p, err = provider.NewGitHubProvider(token, baseURL)
-	default:
-		return fmt.Errorf("unknown provider: %s (use 'gitlab' or 'github')", providerType)
+	p, err := newProvider(providerType, token, baseURL)
+	if err != nil {
+		return err
+	}
+	if mirrorReleases && !p.Capabilities().Releases {
+		return errNotSupported("--mirror-releases", providerType)
+	}
+	if mirrorCheckOnly {
+		if len(groups) == 0 {
+			return fmt.Errorf("--check-only requires groups (not --search or --targets-file)")
+		}
+		fmt.Fprintf(status, "%s Connecting to %s\n", cyan("→"), bold(baseURL))
+		return checkTokenExpired(providerType, runMirrorCheckOnly(ctx, p, token, groups))
+	}
+	if mirrorExcludeEmpty && !mirrorProbeSize && providerType == provider.ProviderGitLab {
+		fmt.Fprintf(status, "%s GitLab lists sizes only with Reporter access: repos listed without one are kept (add --probe-size)\n\n", yellow("!"))
 	}
 
-	if err != nil {
-		return fmt.Errorf("failed to create provider: %w", err)
+	// The connection lines are progress, not results: stderr, or nowhere with --quiet
+	var connectOut io.Writer = os.Stderr
+	if mirrorQuiet {
+		connectOut = io.Discard
 	}
 
-	// Test connection (skip auth test if no token)
-	fmt.Printf("%s Connecting to %s\n", cyan("→"), bold(baseURL))
-	if token != "" {
+	// Test connection (skip auth test if no token). The user is looked up at most once,
+	// for the connection line and --exclude-personal.
+	var user string
+	var userErr error
+	userKnown := false
+	fmt.Fprintf(connectOut, "%s Connecting to %s\n", cyan("→"), bold(baseURL))
+	switch {
+	case token == "":
+		fmt.Fprintf(os.Stderr, "%s No token - public repos only\n", yellow("!"))
+		fmt.Fprintln(connectOut)
+	case noTestConnection:
+		fmt.Fprintln(connectOut)
+	default:
 		if err := p.TestConnection(ctx); err != nil {
 			return fmt.Errorf("connection failed: %w", err)
 		}
-		user, _ := p.GetCurrentUser(ctx)
-		fmt.Printf("%s Authenticated as %s\n\n", green("✓"), bold(user))
-	} else {
-		fmt.Printf("%s No token - public repos only\n\n", yellow("!"))
+		user, userErr = p.GetCurrentUser(ctx)
+		userKnown = true
+		fmt.Fprintf(connectOut, "%s Authenticated as %s\n\n", green("✓"), bold(user))
+	}
+
+	// Personal repos are those in the authenticated user's own namespace
+	var personalNamespace string
+	if mirrorExclPersonal {
+		if token == "" {
+			return fmt.Errorf("--exclude-personal requires a token, to look up the authenticated user")
+		}
+		if !userKnown {
+			user, userErr = p.GetCurrentUser(ctx)
+		}
+		if userErr != nil {
+			return checkTokenExpired(providerType, fmt.Errorf("--exclude-personal: %w", userErr))
+		}
+		personalNamespace = user
 	}
 
 	// Configure mirror options
 	opts := mirror.Options{
-		BaseDir:       mirrorDir,
-		Parallel:      mirrorParallel,
-		SkipArchived:  true,
-		Verbose:       mirrorVerbose,
-		MaxAgeMonths:  mirrorMaxAge,
-		SkipPreflight: mirrorSkipPreflight,
-		SSH:           mirrorSSH,
+		BaseDir:        mirrorDir,
+		Parallel:       parallel,
+		SkipArchived:   cfg.Mirror.SkipArchived,
+		Verbose:        mirrorVerbose,
+		MaxAgeMonths:   mirrorMaxAge,
+		StrictAge:      mirrorStrictAge,
+		SkipPreflight:  mirrorSkipPreflight || mirrorDryRun,
+		SSH:            mirrorSSH,
+		StripPrefix:    mirrorStripPrefix,
+		MirrorReleases: mirrorReleases,
+		FailFast:       mirrorFailFast,
+
+		PreferSSHForPrivate: mirrorSSHPrivate,
+
+		RefreshDefaultBranch: mirrorRefreshHead,
+		UpdateRemotes:        mirrorUpdateRemotes,
+		FollowRedirects:      mirrorRedirects,
+
+		GitConfig: gitConfig,
+		SSHConfig: mirrorSSHConfig,
+
+		Progress: status,
+
+		Flatten:     mirrorFlatten,
+		OnCollision: mirrorOnCollision,
+
+		RecurseSubmodules: mirrorSubmodules,
+		SubmoduleJobs:     mirrorSubmoduleJobs,
+		FetchJobs:         mirrorFetchJobs,
+
+		Filter:           mirrorFilter,
+		FilterSubmodules: filterSubmodules,
+
+		Depth: mirrorDepth,
+
+		TrackBranches: mirrorTrackBranches,
+
+		PruneTags: mirrorPruneTags,
+
+		ResumePartial: mirrorResume,
+
+		CloneParallel:  cloneParallel,
+		UpdateParallel: updateParallel,
+
+		MarkArchived: mirrorMarkArchived,
+
+		Bare: mirrorBare,
+
+		IncludeMembers: mirrorMembers,
+
+		Deadline: deadline,
+
+		RefreshStaleOnly: mirrorStaleOnly,
+
+		DryRun: mirrorDryRun,
+
+		Grep:             mirrorGrep,
+		NameRegex:        nameRegex,
+		NameRegexExclude: nameExclude,
+
+		PathPrefix:   mirrorPathPrefix,
+		ExcludeOwner: personalNamespace,
+
+		Languages: mirror.ParseLanguages(mirrorLanguage),
+
+		MaxSize:   maxSize,
+		ProbeSize: mirrorProbeSize,
+
+		ExcludeSizeZero: mirrorExcludeEmpty,
+
+		DirMode: dirMode,
 	}
 
 	// Determine base directory
@@ -246,8 +770,13 @@ func runMirror(cmd *cobra.Command, args []string) error {
 			homeDir = "." // Fallback to current directory
 		}
 
-		// For multiple groups, use a common parent directory
-		if len(groups) > 1 {
+		// Targets all under one top-level group go where mirroring that group puts them
+		if len(targets) > 0 {
+			groups = targetGroups(targets)
+		}
+
+		// For multiple groups or search results, use a common parent directory
+		if len(groups) != 1 {
 			// Use provider-specific directory: $HOME/gitlab-repos or $HOME/github-repos
 			opts.BaseDir = filepath.Join(homeDir, fmt.Sprintf("%s-repos", providerType))
 		} else {
@@ -256,24 +785,432 @@ func runMirror(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Each snapshot run mirrors into its own timestamped directory under the base directory
+	snapshotRoot := opts.BaseDir
+	if mirrorSnapshot {
+		if mirrorLinkPrevious {
+			snapshots, err := mirror.ListSnapshots(snapshotRoot)
+			if err != nil {
+				return err
+			}
+			if len(snapshots) > 0 {
+				opts.LinkDest = snapshots[len(snapshots)-1]
+			}
+		}
+		opts.BaseDir = mirror.SnapshotDir(snapshotRoot, time.Now())
+	}
+
+	// Incremental runs and checkpoints share the state file in the base directory
+	// (the snapshot root for --snapshot). It is only required to be readable for --since-last-run.
+	state, err := mirror.LoadState(snapshotRoot)
+	if err != nil && mirrorSinceLastRun {
+		return err
+	}
+	if mirrorSinceLastRun {
+		since := state.Since()
+		if !since.IsZero() {
+			opts.UpdatedSince = since
+			fmt.Fprintf(status, "%s Only updating repos changed since %s\n", cyan("→"), since.Local().Format("2006-01-02 15:04:05"))
+		}
+		if cp := state.Checkpoint; cp != nil && len(cp.Synced) > 0 {
+			opts.SyncedSince = cp.SyncedSince()
+			fmt.Fprintf(status, "%s Resuming: %d repo(s) already synced by the unfinished run of %s\n", cyan("→"),
+				len(cp.Synced), cp.RunStarted.Local().Format("2006-01-02 15:04:05"))
+		} else if since.IsZero() {
+			fmt.Fprintf(status, "%s No previous run recorded in %s, mirroring everything\n", yellow("!"), snapshotRoot)
+		}
+		fmt.Fprintln(status)
+	}
+
+	// A marker file narrows the run to repos updated since the last successful one
+	var marker time.Time
+	if mirrorMarkerFile != "" {
+		marker, err = mirror.ReadMarker(mirrorMarkerFile)
+		if err != nil {
+			return err
+		}
+		if marker.IsZero() {
+			fmt.Fprintf(status, "%s No marker in %s yet, mirroring everything\n\n", yellow("!"), mirrorMarkerFile)
+		} else {
+			opts.ChangedAfter = marker
+			fmt.Fprintf(status, "%s Only mirroring repos updated after %s\n\n", cyan("→"), marker.Local().Format("2006-01-02 15:04:05"))
+		}
+	}
+
+	// Periodic checkpoints let --since-last-run resume a run that crashed or was killed
+	if mirrorCheckpoint > 0 && !mirrorDryRun {
+		opts.CheckpointInterval = mirrorCheckpoint
+		opts.Checkpoint = func(results []mirror.Result) {
+			checkpoint := state
+			checkpoint.Checkpoint = mirror.NewCheckpoint(runStarted, results)
+			if err := writeRunState(snapshotRoot, checkpoint, dirMode); err != nil {
+				fmt.Fprintf(status, "  %s checkpoint failed: %v\n", yellow("!"), err)
+			}
+		}
+	}
+
+	// Member repos are personal accounts - require an explicit yes
+	if mirrorMembers {
+		if mirrorSearch != "" || mirrorTargetsFile != "" {
+			return fmt.Errorf("--include-members-repos cannot be combined with --search or --targets-file")
+		}
+		fmt.Fprintf(status, "%s --include-members-repos lists every member of %s and mirrors the repos they own\n",
+			yellow("!"), strings.Join(groups, ", "))
+		fmt.Fprintf(status, "  into %s. This can be many repos and includes personal projects.\n",
+			filepath.Join(opts.BaseDir, "member", "<user>"))
+		if !mirrorYes && !confirm("Continue?") {
+			return fmt.Errorf("aborted (use --yes to skip this prompt in scripts)")
+		}
+		fmt.Fprintln(status)
+	}
+
+	// Git output log, appended so earlier runs are kept; readable by the owner only
+	if mirrorLogFile != "" {
+		logFile, err := os.OpenFile(mirrorLogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		defer logFile.Close()
+		fmt.Fprintf(logFile, "=== ztigit %s mirror run %s ===\n", version, time.Now().Format(time.RFC3339))
+		opts.Log = logFile
+	}
+
+	// Audit log: structured and append-only; readable by the owner only
+	if mirrorAuditLog != "" {
+		auditFile, err := os.OpenFile(mirrorAuditLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		defer auditFile.Close()
+		opts.Audit = auditFile
+	}
+
 	// Create mirror and run
 	m := mirror.New(p, opts)
 
-	fmt.Printf("%s Mirroring %d group(s) to %s\n\n", cyan("→"), len(groups), bold(opts.BaseDir))
+	if mirrorCheckPaths {
+		return checkTokenExpired(providerType, runMirrorCheckPaths(ctx, m, groups, targets))
+	}
+	if mirrorCountOnly {
+		return checkTokenExpired(providerType, runMirrorCountOnly(ctx, m, groups, targets, os.Stdout))
+	}
+
+	if mirrorDryRun {
+		fmt.Println("[DRY-RUN] Nothing will be cloned, updated, or written; planned actions are listed below")
+		fmt.Println()
+	}
 
-	results, err := m.MirrorGroups(ctx, groups)
+	var results []mirror.Result
+	if mirrorTargetsFile != "" {
+		fmt.Fprintf(connectOut, "%s Mirroring %d repo(s) from %s to %s\n\n", cyan("→"), len(targets), mirrorTargetsFile, bold(opts.BaseDir))
+		results, err = m.MirrorTargets(ctx, targets)
+	} else if mirrorSearch != "" {
+		fmt.Fprintf(connectOut, "%s Mirroring search results to %s\n\n", cyan("→"), bold(opts.BaseDir))
+		results, err = m.MirrorSearch(ctx, mirrorSearch)
+	} else {
+		fmt.Fprintf(connectOut, "%s Mirroring %d group(s) to %s\n\n", cyan("→"), len(groups), bold(opts.BaseDir))
+		results, err = m.MirrorGroups(ctx, groups)
+	}
+	// A listing that fails part way still reports, and records, the repos synced before it
+	listErr := checkTokenExpired(providerType, err)
+	if listErr != nil && len(results) == 0 {
+		return listErr
+	}
+
+	mirror.WriteResults(os.Stdout, results, mirrorSummaryFormat, time.Since(runStarted))
+	if mirrorOutput == "github-actions" {
+		mirror.PrintAnnotations(os.Stdout, results)
+	}
+	// Lockfiles, reports, state, and markers describe real runs only
+	if mirrorDryRun {
+		return listErr
+	}
+	if mirrorKeepSnapshots > 0 {
+		if err := pruneSnapshots(snapshotRoot, results); err != nil {
+			return err
+		}
+	}
+	if mirrorLockfile != "" {
+		if err := mirror.WriteLockfile(mirrorLockfile, results); err != nil {
+			return err
+		}
+		fmt.Printf("\n%s Commits written to %s\n", green("✓"), mirrorLockfile)
+	}
+	if mirrorSummaryFile != "" {
+		if err := mirror.WriteRunSummary(mirrorSummaryFile, runStarted, os.Args[1:], results, mirrorSummaryRepos); err != nil {
+			return err
+		}
+	}
+	if mirrorFailuresFile != "" {
+		if err := mirror.WriteFailures(mirrorFailuresFile, results); err != nil {
+			return err
+		}
+		if s := mirror.Summarize(results); s.Failed+s.Aborted+s.TimedOut > 0 {
+			fmt.Printf("\n%s Repos to retry written to %s (use --targets-file)\n", yellow("!"), mirrorFailuresFile)
+		}
+	}
+	if err := saveRunState(snapshotRoot, state, runStarted, results, listErr != nil, dirMode); err != nil {
+		return err
+	}
+	if mirrorMarkerFile != "" {
+		if err := advanceMarker(marker, results, listErr != nil); err != nil {
+			return err
+		}
+	}
+	if listErr != nil {
+		return listErr
+	}
+
+	if s := mirror.Summarize(results); s.TimedOut > 0 {
+		return fmt.Errorf("%w: %d repo(s) timed out", mirror.ErrMaxRuntime, s.TimedOut)
+	}
+	for _, r := range results {
+		if provider.IsUnauthorized(r.Error) {
+			return checkTokenExpired(providerType, r.Error)
+		}
+	}
+
+	if mirrorFailFast {
+		for _, r := range results {
+			if r.Action == "failed" {
+				return fmt.Errorf("mirror aborted: %s failed", r.Repository.FullPath)
+			}
+		}
+	}
+	return nil
+}
+
+// saveRunState records a successful run for --since-last-run. Runs with failures, repos
+// left unstarted, or clones --refresh-stale-only left alone keep the previous run's time
+// and record a checkpoint of the repos they did sync, so the next incremental run retries
+// only the rest. Runs narrowed by filters (partialRun), and runs whose listing failed
+// part way (incomplete), cover only part of the groups and only record a checkpoint.
+func saveRunState(baseDir string, prev mirror.State, started time.Time, results []mirror.Result, incomplete bool, dirMode os.FileMode) error {
+	s := mirror.Summarize(results)
+	if partialRun() || incomplete || s.Failed > 0 || s.Aborted > 0 || s.TimedOut > 0 || s.Fresh > 0 {
+		prev.Checkpoint = mirror.NewCheckpoint(started, results)
+		return writeRunState(baseDir, prev, dirMode)
+	}
+	return writeRunState(baseDir, mirror.State{
+		LastRunStarted:   started,
+		LastRunCompleted: time.Now(),
+	}, dirMode)
+}
+
+// partialRun reports whether filters narrow the run to part of the groups' repos, so
+// repos it did not sync were left out, not found up to date
+func partialRun() bool {
+	return mirrorGrep != "" || mirrorPathPrefix != "" || mirrorExclPersonal || mirrorNameRegex != "" || mirrorNameExclude != "" ||
+		mirrorLanguage != "" || mirrorTargetsFile != "" || mirrorSearch != "" || mirrorMaxSize != "" || mirrorExcludeEmpty
+}
+
+// advanceMarker moves the --marker-file timestamp to the latest update of the repos
+// synced. A run with failures, or repos it did not get to (including the rest of a
+// listing that failed, incomplete), leaves the marker alone so the next run retries them.
+// So does a filtered run (partialRun): the repos it left out would never be picked up.
+func advanceMarker(prev time.Time, results []mirror.Result, incomplete bool) error {
+	if partialRun() {
+		fmt.Printf("%s Marker not advanced: filters left out part of the repos\n", yellow("!"))
+		return nil
+	}
+	if s := mirror.Summarize(results); incomplete || s.Failed > 0 || s.Aborted > 0 || s.TimedOut > 0 || s.Fresh > 0 {
+		fmt.Printf("%s Marker not advanced: not every repo was mirrored\n", yellow("!"))
+		return nil
+	}
+	next := mirror.NextMarker(prev, results)
+	if next.IsZero() || next.Equal(prev) {
+		return nil
+	}
+	return mirror.WriteMarker(mirrorMarkerFile, next)
+}
+
+// writeRunState writes the state file, creating the base directory (with --dir-mode) if
+// nothing was cloned yet
+func writeRunState(baseDir string, state mirror.State, dirMode os.FileMode) error {
+	if err := mirror.MkdirAll(baseDir, dirMode); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return mirror.SaveState(baseDir, state)
+}
+
+// listMirrorRepos lists the repos a mirror run starts from: --targets-file entries,
+// --search results, or the repos of the groups
+func listMirrorRepos(ctx context.Context, m *mirror.Mirror, groups, targets []string) ([]provider.Repository, error) {
+	switch {
+	case mirrorTargetsFile != "":
+		repos, failed := m.TargetRepos(ctx, targets)
+		if len(failed) > 0 {
+			return nil, fmt.Errorf("%s: %w", failed[0].Repository.FullPath, failed[0].Error)
+		}
+		return repos, nil
+	case mirrorSearch != "":
+		return m.SearchRepos(ctx, mirrorSearch)
+	}
+	return m.ListRepos(ctx, groups)
+}
+
+// targetGroups returns the distinct top-level groups of --targets-file entries
+func targetGroups(targets []string) []string {
+	var groups []string
+	for _, target := range targets {
+		group, _, _ := strings.Cut(target, "/")
+		if !slices.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// runMirrorCheckPaths lists repos and reports destinations that exceed path limits
+func runMirrorCheckPaths(ctx context.Context, m *mirror.Mirror, groups, targets []string) error {
+	repos, err := listMirrorRepos(ctx, m, groups, targets)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s Checking local paths...\n\n", cyan("→"))
+	issues, checked := m.CheckPaths(repos)
+	if errs := mirror.PrintPathIssues(issues, checked); errs > 0 {
+		return fmt.Errorf("%d repo path(s) would fail", errs)
+	}
+	return nil
+}
+
+// pruneSnapshots removes the oldest snapshots beyond --keep-snapshots. Nothing is
+// removed after a run with failures, so a broken snapshot never replaces a good one.
+func pruneSnapshots(root string, results []mirror.Result) error {
+	if mirror.Summarize(results).Failed > 0 {
+		fmt.Printf("\n%s Not pruning snapshots: this run had failures\n", yellow("!"))
+		return nil
+	}
+
+	removed, err := mirror.PruneSnapshots(root, mirrorKeepSnapshots)
+	if err != nil {
+		return err
+	}
+	for _, dir := range removed {
+		fmt.Printf("%s Removed old snapshot %s\n", green("✓"), dir)
+	}
+	return nil
+}
+
+// errCheckListed stops a --check-only listing once it has seen a repo
+var errCheckListed = errors.New("listed")
+
+// runMirrorCheckOnly confirms the token works and each group can be listed, without
+// reading more than one repo of it where the provider allows (see probeGroup)
+func runMirrorCheckOnly(ctx context.Context, p provider.Provider, token string, groups []string) error {
+	if token == "" {
+		fmt.Fprintf(os.Stderr, "%s No token - checking public access only\n", yellow("!"))
+	} else {
+		if err := p.TestConnection(ctx); err != nil {
+			return fmt.Errorf("connection failed: %w", err)
+		}
+		user, err := p.GetCurrentUser(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s Authenticated as %s\n", green("✓"), bold(user))
+	}
+
+	for _, group := range groups {
+		listed, err := probeGroup(ctx, p, group)
+		if err != nil {
+			return fmt.Errorf("cannot list %s: %w", group, err)
+		}
+		if listed {
+			fmt.Printf("%s %s is accessible\n", green("✓"), bold(group))
+		} else {
+			fmt.Printf("%s %s is accessible but has no repos visible to this token\n", yellow("!"), bold(group))
+		}
+	}
+	return nil
+}
+
+// probeGroup reports whether group has any repo visible to the token. Providers that
+// implement provider.GroupProber fetch a single repo; others stop after the first page.
+func probeGroup(ctx context.Context, p provider.Provider, group string) (bool, error) {
+	if prober, ok := p.(provider.GroupProber); ok {
+		return prober.ProbeGroup(ctx, group)
+	}
+	listed := false
+	err := p.ListGroupProjectsStream(ctx, group, func(provider.Repository) error {
+		listed = true
+		return errCheckListed
+	})
+	if errors.Is(err, errCheckListed) {
+		err = nil
+	}
+	return listed, err
+}
+
+// runMirrorCountOnly lists repos, applies the mirror filters, and prints only the count to out
+func runMirrorCountOnly(ctx context.Context, m *mirror.Mirror, groups, targets []string, out io.Writer) error {
+	repos, err := listMirrorRepos(ctx, m, groups, targets)
 	if err != nil {
 		return err
 	}
 
-	mirror.PrintResults(results)
+	fmt.Fprintln(out, m.CountRepos(ctx, repos))
 	return nil
 }
 
+// parseGitConfigFlags builds the run's git config from --protocol-v2 and --git-config
+func parseGitConfigFlags(protocolV2 bool, entries []string) ([]mirror.GitConfig, error) {
+	var gitConfig []mirror.GitConfig
+	if protocolV2 {
+		// First, so an explicit --git-config protocol.version still wins
+		gitConfig = append(gitConfig, mirror.GitConfig{Key: "protocol.version", Value: "2"})
+	}
+	for _, entry := range entries {
+		gc, err := mirror.ParseGitConfig(entry)
+		if err != nil {
+			return nil, err
+		}
+		gitConfig = append(gitConfig, gc)
+	}
+	return gitConfig, nil
+}
+
+// parseParallel parses --parallel: a number of repos, or "auto" for separate clone and
+// update limits sized from the CPU count (see mirror.AutoParallel). Clone and update
+// limits are 0 for a number, meaning both share it. With "auto" the overall limit is
+// their sum, so repos waiting for a full update slot never hold every dispatch slot
+// and starve clones (or the other way round).
+func parseParallel(value string) (parallel, clone, update int, err error) {
+	if value == "auto" {
+		clone, update = mirror.AutoParallel(runtime.NumCPU())
+		return clone + update, clone, update, nil
+	}
+	parallel, err = strconv.Atoi(value)
+	if err != nil || parallel < 1 {
+		return 0, 0, 0, fmt.Errorf("invalid --parallel: %q (must be a number of at least 1, or 'auto')", value)
+	}
+	return parallel, 0, 0, nil
+}
+
+// applyMirrorConfigDefaults fills unset mirror flags from the config file
+func applyMirrorConfigDefaults(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	if !flags.Changed("parallel") && cfg.Mirror.Parallel > 0 {
+		mirrorParallel = strconv.Itoa(cfg.Mirror.Parallel)
+	}
+	if !flags.Changed("max-age") && cfg.Mirror.MaxAgeMonths >= 0 {
+		mirrorMaxAge = cfg.Mirror.MaxAgeMonths
+	}
+	if !flags.Changed("ssh") {
+		mirrorSSH = cfg.Mirror.UseSSH()
+	}
+	if !flags.Changed("verbose") {
+		mirrorVerbose = cfg.Mirror.Verbose
+	}
+}
+
 // parsedURL holds parsed git hosting URL components
 type parsedURL struct {
 	baseURL  string
 	orgName  string
+	path     string // Group or project path, without .git or web UI suffixes
 	provider provider.ProviderType
 }
 
@@ -302,9 +1239,22 @@ func parseGitURL(rawURL string) (*parsedURL, error) {
 	baseURL := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
 	providerType := provider.DetectProvider(baseURL)
 
+	// Drop a .git suffix and web UI pages such as /-/tree/main (GitLab), /tree/main (GitHub),
+	// or /src/main (Bitbucket)
+	path = strings.TrimSuffix(path, ".git")
+	if i := strings.Index(path, "/-/"); i >= 0 {
+		path = path[:i]
+	}
+	if providerType == provider.ProviderGitHub || providerType == provider.ProviderBitbucket {
+		if segments := strings.SplitN(path, "/", 3); len(segments) == 3 {
+			path = segments[0] + "/" + segments[1]
+		}
+	}
+
 	return &parsedURL{
 		baseURL:  baseURL,
 		orgName:  orgName,
+		path:     path,
 		provider: providerType,
 	}, nil
 }
@@ -313,30 +1263,58 @@ func parseGitURL(rawURL string) (*parsedURL, error) {
 var protectCmd = &cobra.Command{
 	Use:   "protect",
 	Short: "Protect environments",
-	Long:  `Protect deployment environments matching a pattern.`,
-	RunE:  runProtect,
+	Long: `Protect deployment environments matching a pattern, or of a deployment tier.
+
+Use --project for one project, or --group to protect the matching environments
+of every project in a group or GitHub org.`,
+	Example: `  # Require approval from a user and a team on every repo's prod environment
+  ztigit protect --group acme --pattern 'prod$' --reviewers alice,acme/platform -p github --dry-run`,
+	RunE: runProtect,
 }
 
 var (
 	protectProject   string
+	protectGroup     string
 	protectPattern   string
 	protectURL       string
 	protectProvider  string
 	protectDryRun    bool
 	protectAccessLvl int
 	protectApprovals int
+	protectWaitTimer int
+	protectBranches  []string
+	protectReviewers []string
+	protectParallel  int
+	protectNames     []string
+	protectTier      string
 )
 
 func init() {
 	protectCmd.Flags().StringVarP(&protectProject, "project", "P", "", "Project path (e.g., group/project)")
 	protectCmd.Flags().StringVar(&protectPattern, "pattern", "", "Environment name pattern (e.g., 'dev', 'prod', 'all')")
+	protectCmd.Flags().StringSliceVar(&protectNames, "names", nil, "Exact environment names to protect, comma-separated (instead of --pattern)")
+	protectCmd.Flags().StringVar(&protectTier, "tier", "", "Only environments of this deployment tier: production, staging, testing, development, or other (combines with --pattern/--names)")
 	protectCmd.Flags().StringVarP(&protectURL, "url", "u", "", "Git hosting URL")
 	protectCmd.Flags().StringVarP(&protectProvider, "provider", "p", "", "Provider type: gitlab or github")
 	protectCmd.Flags().BoolVar(&protectDryRun, "dry-run", false, "Show what would be protected without making changes")
+	protectCmd.Flags().BoolVar(&noTestConnection, "no-test-connection", false, noTestConnectionUsage)
 	protectCmd.Flags().IntVar(&protectAccessLvl, "access-level", 30, "Access level required (30=developer, 40=maintainer, 60=admin)")
 	protectCmd.Flags().IntVar(&protectApprovals, "approvals", 1, "Required approvals")
-	protectCmd.MarkFlagRequired("project")
-	protectCmd.MarkFlagRequired("pattern")
+	protectCmd.Flags().IntVar(&protectWaitTimer, "wait-timer", 0, "GitHub: minutes to wait before deployments proceed (0-43200)")
+	protectCmd.Flags().StringSliceVar(&protectBranches, "deploy-branches", nil, "GitHub: branches allowed to deploy, comma-separated patterns or 'protected'")
+	protectCmd.Flags().StringSliceVar(&protectReviewers, "reviewers", nil, "GitHub: users or org/team slugs that must approve deployments, comma-separated (up to 6)")
+	protectCmd.Flags().StringVarP(&protectGroup, "group", "g", "", "Protect matching environments in every project of this group/org (including subgroups)")
+	protectCmd.Flags().IntVar(&protectParallel, "parallel", 1, "Environments of a project to protect at once")
+	protectCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "parallel-env-protect" {
+			name = "parallel"
+		}
+		return pflag.NormalizedName(name)
+	})
+	protectCmd.MarkFlagsOneRequired("project", "group")
+	protectCmd.MarkFlagsMutuallyExclusive("project", "group")
+	protectCmd.MarkFlagsOneRequired("pattern", "names", "tier")
+	protectCmd.MarkFlagsMutuallyExclusive("pattern", "names")
 	rootCmd.AddCommand(protectCmd)
 }
 
@@ -354,37 +1332,28 @@ func runProtect(cmd *cobra.Command, args []string) error {
 		providerType = provider.DetectProvider(protectURL)
 	}
 
-	// Get token and URL
-	token := cfg.GetToken(string(providerType))
+	// Get URL and token
 	baseURL := protectURL
 	if baseURL == "" {
 		baseURL = cfg.GetBaseURL(string(providerType))
 	}
+	token := cfg.GetToken(string(providerType), baseURL)
 
 	if token == "" {
 		return fmt.Errorf("no token configured for %s", providerType)
 	}
 
-	// Create provider
-	var p provider.Provider
-	var err error
-
-	switch providerType {
-	case provider.ProviderGitLab:
-		p, err = provider.NewGitLabProvider(token, baseURL)
-	case provider.ProviderGitHub:
-		p, err = provider.NewGitHubProvider(token, baseURL)
-	default:
-		return fmt.Errorf("unknown provider: %s", providerType)
+	// Exact names are matched as an escaped, anchored alternation
+	if len(protectNames) > 0 {
+		protectPattern = protect.NamesPattern(protectNames)
 	}
-
-	if err != nil {
-		return err
+	// --tier alone selects every environment in the tier
+	if protectPattern == "" {
+		protectPattern = "all"
 	}
 
-	// Test connection
-	if err := p.TestConnection(ctx); err != nil {
-		return err
+	if len(protectReviewers) > protect.MaxReviewers {
+		return fmt.Errorf("--reviewers accepts at most %d users or teams", protect.MaxReviewers)
 	}
 
 	// Configure protect options
@@ -392,6 +1361,47 @@ func runProtect(cmd *cobra.Command, args []string) error {
 		AccessLevel:       protectAccessLvl,
 		RequiredApprovals: protectApprovals,
 		DryRun:            protectDryRun,
+		Parallel:          protectParallel,
+		Tier:              protectTier,
+		WaitTimer:         protectWaitTimer,
+		DeployBranches:    protectBranches,
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	// Create provider
+	p, err := newProvider(providerType, token, baseURL)
+	if err != nil {
+		return err
+	}
+	caps := p.Capabilities()
+	if !caps.Environments {
+		return errNotSupported("environment protection", providerType)
+	}
+	// Wait timers, deployment branches, and reviewers are GitHub-style deployment rules
+	if !caps.DeploymentRules && (protectWaitTimer != 0 || len(protectBranches) > 0 || len(protectReviewers) > 0) {
+		return errNotSupported("--wait-timer, --deploy-branches, and --reviewers", providerType)
+	}
+	resolver, canResolve := p.(provider.ReviewerResolver)
+	if len(protectReviewers) > 0 && !canResolve {
+		return errNotSupported("--reviewers", providerType)
+	}
+
+	// Test connection
+	if !noTestConnection {
+		if err := p.TestConnection(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Look reviewers up once; the same IDs are applied to every environment
+	if len(protectReviewers) > 0 {
+		reviewers, err := resolver.ResolveReviewers(ctx, protectReviewers)
+		if err != nil {
+			return checkTokenExpired(providerType, err)
+		}
+		opts.Reviewers = reviewers
 	}
 
 	// Create protector and run
@@ -402,9 +1412,26 @@ func runProtect(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
+	if protectGroup != "" {
+		selection := protect.Selection(protectPattern, protectTier)
+		fmt.Printf("Protecting environments %s in projects of %s...\n\n", selection, protectGroup)
+		projects, err := pr.ProtectGroupEnvironments(ctx, protectGroup, protectPattern)
+		if err != nil {
+			if len(projects) > 0 {
+				protect.PrintGroupResults(projects, selection, protectDryRun)
+			}
+			return checkTokenExpired(providerType, err)
+		}
+		protect.PrintGroupResults(projects, selection, protectDryRun)
+		return nil
+	}
+
 	results, err := pr.ProtectEnvironments(ctx, protectProject, protectPattern)
 	if err != nil {
-		return err
+		if len(results) > 0 {
+			protect.PrintResults(results, protectDryRun)
+		}
+		return checkTokenExpired(providerType, err)
 	}
 
 	protect.PrintResults(results, protectDryRun)
@@ -413,84 +1440,225 @@ func runProtect(cmd *cobra.Command, args []string) error {
 
 // Environments command
 var envsCmd = &cobra.Command{
-	Use:   "environments",
-	Short: "List environments for a project",
-	Long:  `List all deployment environments and their protection status.`,
-	RunE:  runEnvironments,
+	Use:   "environments [url]",
+	Short: "List environments for a project or group",
+	Long: `List all deployment environments and their protection status.
+
+With --group, every project in the group (including subgroups) is listed,
+grouped by project, to audit protection across a whole group tree.
+
+Instead of --project or --group, pass the project or group URL; the provider,
+server, and whether it is a project or a group are derived from it.`,
+	Example: `  ztigit environments https://gitlab.com/company/platform/deploy-tools
+  ztigit environments https://github.com/zsoftly --unprotected-only`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runEnvironments,
 }
 
 var (
-	envsProject  string
-	envsURL      string
-	envsProvider string
+	envsProject         string
+	envsGroup           string
+	envsURL             string
+	envsProvider        string
+	envsUnprotectedOnly bool
+	envsProtectedOnly   bool
+	envsPattern         string
+	envsFast            bool
 )
 
 func init() {
 	envsCmd.Flags().StringVarP(&envsProject, "project", "P", "", "Project path (e.g., group/project)")
+	envsCmd.Flags().StringVarP(&envsGroup, "group", "g", "", "List environments of every project in this group/org (including subgroups)")
 	envsCmd.Flags().StringVarP(&envsURL, "url", "u", "", "Git hosting URL")
 	envsCmd.Flags().StringVarP(&envsProvider, "provider", "p", "", "Provider type: gitlab or github")
-	envsCmd.MarkFlagRequired("project")
+	envsCmd.Flags().BoolVar(&envsUnprotectedOnly, "unprotected-only", false, "Only show environments that are not protected (alias: --only-unprotected)")
+	envsCmd.Flags().BoolVar(&envsProtectedOnly, "only-protected", false, "Only show environments that are protected")
+	envsCmd.Flags().StringVar(&envsPattern, "pattern", "", "Only show environments matching this pattern (e.g., 'prod'); with --group --unprotected-only, exit non-zero if any project has one unprotected")
+	envsCmd.Flags().BoolVar(&noTestConnection, "no-test-connection", false, noTestConnectionUsage)
+	envsCmd.Flags().BoolVar(&envsFast, "fast", false, "With --group --unprotected-only, only list projects that have unprotected environments (stops at the first one per project)")
+	envsCmd.MarkFlagsMutuallyExclusive("project", "group")
+	envsCmd.MarkFlagsMutuallyExclusive("unprotected-only", "only-protected")
+	envsCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "only-unprotected" {
+			name = "unprotected-only"
+		}
+		return pflag.NormalizedName(name)
+	})
 	rootCmd.AddCommand(envsCmd)
 }
 
 func runEnvironments(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	// A URL names the server, provider, and project or group in one argument
+	var target *parsedURL
+	if len(args) > 0 {
+		if envsProject != "" || envsGroup != "" {
+			return fmt.Errorf("a URL cannot be combined with --project or --group")
+		}
+		parsed, err := parseGitURL(args[0])
+		if err != nil {
+			return err
+		}
+		target = parsed
+		if envsURL == "" {
+			envsURL = parsed.baseURL
+		}
+		if envsProvider == "" {
+			envsProvider = string(parsed.provider)
+		}
+	} else if envsProject == "" && envsGroup == "" {
+		return fmt.Errorf("a project or group URL, --project, or --group is required")
+	}
+
+	if envsFast && (envsProject != "" || (envsGroup == "" && target == nil) || !envsUnprotectedOnly) {
+		return fmt.Errorf("--fast requires --group and --unprotected-only")
+	}
+	if envsFast && envsPattern != "" {
+		return fmt.Errorf("--fast cannot be combined with --pattern")
+	}
+
 	// Determine provider
 	providerType := provider.ProviderType(envsProvider)
 	if envsProvider == "" {
 		providerType = provider.DetectProvider(envsURL)
 	}
 
-	// Get token and URL
-	token := cfg.GetToken(string(providerType))
+	// Get URL and token
 	baseURL := envsURL
 	if baseURL == "" {
 		baseURL = cfg.GetBaseURL(string(providerType))
 	}
+	token := cfg.GetToken(string(providerType), baseURL)
 
 	if token == "" {
 		return fmt.Errorf("no token configured for %s", providerType)
 	}
 
 	// Create provider
-	var p provider.Provider
-	var err error
-
-	switch providerType {
-	case provider.ProviderGitLab:
-		p, err = provider.NewGitLabProvider(token, baseURL)
-	case provider.ProviderGitHub:
-		p, err = provider.NewGitHubProvider(token, baseURL)
-	default:
-		return fmt.Errorf("unknown provider: %s", providerType)
-	}
-
+	p, err := newProvider(providerType, token, baseURL)
 	if err != nil {
 		return err
 	}
+	if !p.Capabilities().Environments {
+		return errNotSupported("environments", providerType)
+	}
 
 	// Test connection
-	if err := p.TestConnection(ctx); err != nil {
-		return err
+	if !noTestConnection {
+		if err := p.TestConnection(ctx); err != nil {
+			return err
+		}
+	}
+
+	if target != nil {
+		envsProject, envsGroup, err = environmentsTarget(ctx, p, target.path)
+		if err != nil {
+			return checkTokenExpired(providerType, err)
+		}
+		if envsFast && envsGroup == "" {
+			return fmt.Errorf("--fast requires a group, but %s is a project", args[0])
+		}
 	}
 
-	// List environments
 	pr := protect.New(p, protect.DefaultOptions())
+
+	// Fast audit: only which projects have unprotected environments
+	if envsFast {
+		fmt.Printf("Scanning projects in %s for unprotected environments...\n\n", envsGroup)
+		scans, err := pr.ScanGroupUnprotected(ctx, envsGroup)
+		if err != nil {
+			return checkTokenExpired(providerType, err)
+		}
+		protect.PrintScanResults(scans)
+		// A project that could not be scanned is not known to be protected
+		var failed int
+		for _, scan := range scans {
+			if scan.Error != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d project(s) in %s could not be checked for unprotected environments", failed, envsGroup)
+		}
+		return nil
+	}
+
+	// Group audit: environments of every project, grouped by project
+	if envsGroup != "" {
+		fmt.Printf("Listing environments of projects in %s...\n\n", envsGroup)
+		projects, err := pr.ListGroupEnvironments(ctx, envsGroup)
+		if err != nil {
+			return checkTokenExpired(providerType, err)
+		}
+		for i := range projects {
+			projects[i].Environments = protect.MatchingEnvironments(projects[i].Environments, envsPattern)
+		}
+
+		// Policy check: projects with an unprotected environment matching the pattern
+		if envsUnprotectedOnly && envsPattern != "" {
+			gaps := protect.ComplianceGaps(projects)
+			protect.PrintComplianceGaps(gaps, len(projects), envsPattern)
+			if len(gaps) > 0 {
+				return fmt.Errorf("%d project(s) in %s have unprotected or unverified environments matching %q", len(gaps), envsGroup, envsPattern)
+			}
+			return nil
+		}
+
+		for i := range projects {
+			projects[i].Environments = filterEnvironmentStatus(projects[i].Environments)
+		}
+		protect.PrintGroupEnvironments(projects)
+		return nil
+	}
+
+	// List environments
 	envs, err := pr.ListEnvironments(ctx, envsProject)
 	if err != nil {
-		return err
+		return checkTokenExpired(providerType, err)
 	}
+	envs = filterEnvironmentStatus(protect.MatchingEnvironments(envs, envsPattern))
 
 	protect.PrintEnvironments(envs)
 	return nil
 }
 
+// environmentsTarget decides whether a URL path names a project or a group. One segment
+// is a group (or GitHub org/user), and without subgroups (GitHub) two segments are owner/repo.
+// GitLab paths can be a project or a nested subgroup, so the path is looked up as a project first.
+func environmentsTarget(ctx context.Context, p provider.Provider, path string) (project, group string, err error) {
+	switch {
+	case !strings.Contains(path, "/"):
+		return "", path, nil
+	case !p.Capabilities().Subgroups:
+		return path, "", nil
+	}
+
+	if _, err := p.GetProject(ctx, path); err != nil {
+		if provider.IsUnauthorized(err) {
+			return "", "", err
+		}
+		return "", path, nil
+	}
+	return path, "", nil
+}
+
+// filterEnvironmentStatus applies --unprotected-only or --only-protected
+func filterEnvironmentStatus(envs []provider.Environment) []provider.Environment {
+	switch {
+	case envsUnprotectedOnly:
+		return protect.UnprotectedOnly(envs)
+	case envsProtectedOnly:
+		return protect.ProtectedOnly(envs)
+	}
+	return envs
+}
+
 // Auth command
 var authCmd = &cobra.Command{
 	Use:   "auth",
 	Short: "Manage authentication",
-	Long:  `Configure authentication tokens for GitLab and GitHub.`,
+	Long:  `Configure authentication tokens for GitLab, GitHub, and Bitbucket.`,
 }
 
 var authLoginCmd = &cobra.Command{
@@ -509,45 +1677,245 @@ Examples:
   echo $GITHUB_TOKEN | ztigit auth login -p github
 
   # Interactive (paste token, press Enter)
-  ztigit auth login -p gitlab`,
+  ztigit auth login -p gitlab
+
+  # Reuse the token of an existing gh or glab CLI login
+  ztigit auth login --from-gh
+  ztigit auth login --from-glab -u https://gitlab.example.com`,
 	RunE: runAuthLogin,
 }
 
 var (
 	authLoginProvider string
 	authLoginURL      string
+	authLoginFromGH   bool
+	authLoginFromGLab bool
 )
 
 func init() {
-	authLoginCmd.Flags().StringVarP(&authLoginProvider, "provider", "p", "", "Provider type: gitlab or github")
+	authLoginCmd.Flags().StringVarP(&authLoginProvider, "provider", "p", "", "Provider type: gitlab, github, or bitbucket")
 	authLoginCmd.Flags().StringVarP(&authLoginURL, "url", "u", "", "Base URL for the provider")
-	authLoginCmd.MarkFlagRequired("provider")
+	authLoginCmd.Flags().BoolVar(&authLoginFromGH, "from-gh", false, "Import the token of the GitHub CLI (gh) login for this host")
+	authLoginCmd.Flags().BoolVar(&authLoginFromGLab, "from-glab", false, "Import the token of the GitLab CLI (glab) login for this host")
+	authLoginCmd.MarkFlagsOneRequired("provider", "from-gh", "from-glab")
+	authLoginCmd.MarkFlagsMutuallyExclusive("from-gh", "from-glab")
+
+	authListCmd.Flags().StringVarP(&authListOutput, "output", "o", "text", "Output format: text or json")
+
+	authVerifyCmd.Flags().StringVar(&authVerifyFor, "for", "", "Operation to check the token for: mirror or protect")
+	authVerifyCmd.Flags().StringVarP(&authVerifyProvider, "provider", "p", "", "Provider type: gitlab, github, or bitbucket (default: every provider with a token)")
+	authVerifyCmd.MarkFlagRequired("for")
 
 	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authListCmd)
+	authCmd.AddCommand(authVerifyCmd)
 	rootCmd.AddCommand(authCmd)
 }
 
+var authListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured providers and token sources",
+	Long: `List each provider with its base URL, whether a token is configured,
+and where the token comes from (keychain, env, config, or none).
+
+Examples:
+  ztigit auth list
+  ztigit auth list -o json`,
+	RunE: runAuthList,
+}
+
+var authListOutput string
+
+// authListEntry describes one provider's authentication state
+type authListEntry struct {
+	Provider        string `json:"provider"`
+	BaseURL         string `json:"base_url"`
+	TokenConfigured bool   `json:"token_configured"`
+	Source          string `json:"source"`
+}
+
+func runAuthList(cmd *cobra.Command, args []string) error {
+	if authListOutput != "text" && authListOutput != "json" {
+		return fmt.Errorf("invalid output format: %q (must be 'text' or 'json')", authListOutput)
+	}
+
+	var entries []authListEntry
+	for _, pt := range provider.Registered() {
+		baseURL := cfg.GetBaseURL(string(pt))
+		source := cfg.TokenSource(string(pt), baseURL)
+		entries = append(entries, authListEntry{
+			Provider:        string(pt),
+			BaseURL:         baseURL,
+			TokenConfigured: source != config.TokenSourceNone,
+			Source:          source,
+		})
+	}
+
+	if authListOutput == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	fmt.Printf("%-10s %-35s %-12s %s\n", "PROVIDER", "URL", "TOKEN", "SOURCE")
+	for _, e := range entries {
+		token := "(not set)"
+		if e.TokenConfigured {
+			token = "configured"
+		}
+		fmt.Printf("%-10s %-35s %-12s %s\n", e.Provider, e.BaseURL, token, e.Source)
+	}
+	return nil
+}
+
+var authVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that a stored token has the scopes an operation needs",
+	Long: `Check the scopes of the stored token against what an operation needs:
+read access for mirror, write access for protect. Missing scopes are listed,
+and the command exits non-zero if any are missing. Scopes the operation does
+not need are pointed out, without failing, so tokens can be kept least-privilege.
+
+GitHub fine-grained tokens and GitLab OAuth tokens do not report their scopes;
+they are reported as unverifiable rather than failing.
+
+Examples:
+  ztigit auth verify --for mirror
+  ztigit auth verify --for protect -p gitlab`,
+	RunE: runAuthVerify,
+}
+
+var (
+	authVerifyFor      string
+	authVerifyProvider string
+)
+
+func runAuthVerify(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if authVerifyFor != provider.OperationMirror && authVerifyFor != provider.OperationProtect {
+		return fmt.Errorf("invalid --for %q (must be 'mirror' or 'protect')", authVerifyFor)
+	}
+
+	providers := provider.Registered()
+	if authVerifyProvider != "" {
+		pt := provider.ProviderType(authVerifyProvider)
+		if err := validateProviderType(pt); err != nil {
+			return err
+		}
+		providers = []provider.ProviderType{pt}
+	}
+
+	checked, insufficient := 0, 0
+	for _, pt := range providers {
+		baseURL := cfg.GetBaseURL(string(pt))
+		token := cfg.GetToken(string(pt), baseURL)
+		if token == "" {
+			if authVerifyProvider != "" {
+				return fmt.Errorf("no token configured for %s", pt)
+			}
+			continue
+		}
+		checked++
+
+		p, err := newProvider(pt, token, baseURL)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s (%s)\n", pt, baseURL)
+		scopes, ok, err := p.TokenScopes(ctx)
+		if err != nil {
+			return checkTokenExpired(pt, err)
+		}
+		if !ok {
+			fmt.Printf("  %s Scopes cannot be verified for this kind of token\n\n", yellow("!"))
+			continue
+		}
+
+		required := provider.RequiredScopes(pt, authVerifyFor)
+		missing := provider.MissingScopes(scopes, required)
+		for _, scope := range required {
+			if slices.Contains(missing, scope) {
+				fmt.Printf("  %s %s (missing)\n", red("✗"), scope)
+			} else {
+				fmt.Printf("  %s %s\n", green("✓"), scope)
+			}
+		}
+		// Least-privilege advisory only; extra scopes never fail the check
+		if excess := provider.ExcessScopes(scopes, required); len(excess) > 0 {
+			fmt.Printf("  %s Broader than %s needs: %s\n", yellow("!"), authVerifyFor, strings.Join(excess, ", "))
+		}
+		fmt.Println()
+		if len(missing) > 0 {
+			insufficient++
+		}
+	}
+
+	if checked == 0 {
+		return fmt.Errorf("no tokens configured (run 'ztigit auth login' first)")
+	}
+	if insufficient > 0 {
+		return fmt.Errorf("%d token(s) are missing scopes required for %s", insufficient, authVerifyFor)
+	}
+	return nil
+}
+
 func runAuthLogin(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	// --from-gh/--from-glab imply the provider
+	fromCLI := ""
+	switch {
+	case authLoginFromGH:
+		fromCLI = config.CLIGitHub
+		if authLoginProvider == "" {
+			authLoginProvider = string(provider.ProviderGitHub)
+		}
+	case authLoginFromGLab:
+		fromCLI = config.CLIGitLab
+		if authLoginProvider == "" {
+			authLoginProvider = string(provider.ProviderGitLab)
+		}
+	}
+
 	// Validate provider
 	providerType := provider.ProviderType(authLoginProvider)
-	if providerType != provider.ProviderGitLab && providerType != provider.ProviderGitHub {
-		return fmt.Errorf("invalid provider: %s (must be 'gitlab' or 'github')", authLoginProvider)
+	if providerType != provider.ProviderGitLab && providerType != provider.ProviderGitHub && providerType != provider.ProviderBitbucket {
+		return fmt.Errorf("invalid provider: %s (must be 'gitlab', 'github', or 'bitbucket')", authLoginProvider)
 	}
 
 	// Determine base URL
 	baseURL := authLoginURL
 	if baseURL == "" {
-		if providerType == provider.ProviderGitLab {
+		switch providerType {
+		case provider.ProviderGitLab:
 			baseURL = "https://gitlab.com"
-		} else {
+		case provider.ProviderBitbucket:
+			baseURL = "https://bitbucket.org"
+		default:
 			baseURL = "https://github.com"
 		}
 	}
 
-	// Get token from environment variable or stdin (never from command line flag)
-	token := getTokenFromEnvOrStdin(providerType)
+	if (fromCLI == config.CLIGitHub && providerType != provider.ProviderGitHub) ||
+		(fromCLI == config.CLIGitLab && providerType != provider.ProviderGitLab) {
+		return fmt.Errorf("--from-%s cannot be used with --provider %s", fromCLI, providerType)
+	}
+
+	// Get token from the other CLI, or from environment variable or stdin (never from command line flag)
+	var token string
+	if fromCLI != "" {
+		host := urlHost(baseURL)
+		var err error
+		token, err = config.ImportCLIToken(fromCLI, host)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Using %s credentials for %s\n", fromCLI, host)
+	} else {
+		token = getTokenFromEnvOrStdin(providerType)
+	}
 	if token == "" {
 		return fmt.Errorf("no token provided. Set %s_TOKEN environment variable or pipe token via stdin",
 			strings.ToUpper(string(providerType)))
@@ -559,18 +1927,9 @@ func runAuthLogin(cmd *cobra.Command, args []string) error {
 	}
 
 	// Test the token
-	var p provider.Provider
-	var err error
-
-	switch providerType {
-	case provider.ProviderGitLab:
-		p, err = provider.NewGitLabProvider(token, baseURL)
-	case provider.ProviderGitHub:
-		p, err = provider.NewGitHubProvider(token, baseURL)
-	}
-
+	p, err := newProvider(providerType, token, baseURL)
 	if err != nil {
-		return fmt.Errorf("failed to create provider: %w", err)
+		return err
 	}
 
 	fmt.Printf("Testing connection to %s...\n", baseURL)
@@ -581,6 +1940,10 @@ func runAuthLogin(cmd *cobra.Command, args []string) error {
 	user, _ := p.GetCurrentUser(ctx)
 	fmt.Printf("Authenticated as: %s\n", user)
 
+	// A token saved before keys included the host belongs to the instance configured
+	// until now; move it to that instance's key before the base URL changes
+	cfg.MigrateLegacyToken(string(providerType))
+
 	// Save to config
 	switch providerType {
 	case provider.ProviderGitLab:
@@ -589,6 +1952,9 @@ func runAuthLogin(cmd *cobra.Command, args []string) error {
 	case provider.ProviderGitHub:
 		cfg.GitHub.Token = token
 		cfg.GitHub.BaseURL = baseURL
+	case provider.ProviderBitbucket:
+		cfg.Bitbucket.Token = token
+		cfg.Bitbucket.BaseURL = baseURL
 	}
 
 	if err := config.Save(cfg); err != nil {
@@ -604,6 +1970,14 @@ func runAuthLogin(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// urlHost returns the host of a base URL such as https://gitlab.example.com
+func urlHost(baseURL string) string {
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return baseURL
+}
+
 // validateURLSecurity checks that HTTPS is used when token is present
 func validateURLSecurity(baseURL, token string) error {
 	if token != "" && strings.HasPrefix(strings.ToLower(baseURL), "http://") {
@@ -614,27 +1988,64 @@ func validateURLSecurity(baseURL, token string) error {
 
 // validateProviderType ensures the provider type is safe for use in filesystem paths
 func validateProviderType(pt provider.ProviderType) error {
-	// Provider type must be one of the known types
-	switch pt {
-	case provider.ProviderGitLab, provider.ProviderGitHub:
-		return nil
-	default:
-		return fmt.Errorf("invalid provider type: %q (must be 'gitlab' or 'github')", pt)
+	// Provider type must be registered; registered names are restricted to safe characters
+	if !provider.IsRegistered(pt) {
+		return fmt.Errorf("invalid provider type: %q (must be one of %s)", pt, provider.RegisteredList())
+	}
+	return nil
+}
+
+// newProvider creates a provider client of the given type
+func newProvider(providerType provider.ProviderType, token, baseURL string) (provider.Provider, error) {
+	p, err := provider.New(providerType, token, baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
 	}
+	return p, nil
+}
+
+// connection is a provider for testConnections; name identifies it in failures
+type connection struct {
+	name string
+	p    provider.Provider
+}
+
+// testConnections runs TestConnection on several providers at once, so a command that
+// talks to more than one waits for the slowest instead of the sum. Every failure is
+// reported in one error, naming the providers that failed.
+func testConnections(ctx context.Context, conns []connection) error {
+	errs := make([]error, len(conns))
+	var wg sync.WaitGroup
+	for i, c := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = c.p.TestConnection(ctx)
+		}()
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("  %s: %v", conns[i].name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("connection failed for %d of %d providers:\n%s", len(failed), len(conns), strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// errNotSupported reports a feature missing from a provider's capabilities
+func errNotSupported(feature string, providerType provider.ProviderType) error {
+	return fmt.Errorf("%s: not supported by %s", feature, providerType)
 }
 
 // getTokenFromEnvOrStdin reads token from environment variable or stdin
 func getTokenFromEnvOrStdin(providerType provider.ProviderType) string {
 	// Try environment variable first
-	var envVars []string
-	switch providerType {
-	case provider.ProviderGitLab:
-		envVars = []string{"GITLAB_TOKEN", "ZTIGIT_GITLAB_TOKEN"}
-	case provider.ProviderGitHub:
-		envVars = []string{"GITHUB_TOKEN", "ZTIGIT_GITHUB_TOKEN"}
-	}
-
-	for _, env := range envVars {
+	for _, env := range config.TokenEnvVars(string(providerType)) {
 		if token := os.Getenv(env); token != "" {
 			return token
 		}
@@ -660,6 +2071,23 @@ func getTokenFromEnvOrStdin(providerType provider.ProviderType) string {
 	return ""
 }
 
+// confirm asks a yes/no question on the terminal. Returns false when stdin
+// is not a terminal so scripts never block on a prompt.
+func confirm(question string) bool {
+	stat, _ := os.Stdin.Stat()
+	if (stat.Mode() & os.ModeCharDevice) == 0 {
+		return false
+	}
+
+	fmt.Printf("%s [y/N]: ", question)
+	scanner := bufio.NewScanner(os.Stdin)
+	if !scanner.Scan() {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	return answer == "y" || answer == "yes"
+}
+
 // Config command
 var configCmd = &cobra.Command{
 	Use:   "config",
@@ -668,11 +2096,105 @@ var configCmd = &cobra.Command{
 	RunE:  runConfig,
 }
 
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Show where configuration and tokens are stored",
+	Long:  `Print the config file and directory, the keyring service and backend, and whether the keyring is available.`,
+	Args:  cobra.NoArgs,
+	RunE:  runConfigPath,
+}
+
+var (
+	configOutput     string
+	configPathOutput string
+)
+
 func init() {
+	configCmd.Flags().StringVarP(&configOutput, "output", "o", "text", "Output format: text or json")
+	configPathCmd.Flags().StringVarP(&configPathOutput, "output", "o", "text", "Output format: text or json")
+	configCmd.AddCommand(configPathCmd)
 	rootCmd.AddCommand(configCmd)
 }
 
+// configPathDoc is the machine-readable form of `config path`
+type configPathDoc struct {
+	ConfigFile       string `json:"config_file"`
+	ConfigDir        string `json:"config_dir"`
+	KeyringService   string `json:"keyring_service"`
+	KeyringBackend   string `json:"keyring_backend"`
+	KeyringAvailable bool   `json:"keyring_available"`
+}
+
+func runConfigPath(cmd *cobra.Command, args []string) error {
+	if configPathOutput != "text" && configPathOutput != "json" {
+		return fmt.Errorf("invalid output format: %q (must be 'text' or 'json')", configPathOutput)
+	}
+
+	doc := configPathDoc{
+		ConfigFile:       config.GetConfigFile(),
+		ConfigDir:        config.GetConfigDir(),
+		KeyringService:   config.KeyringService,
+		KeyringBackend:   config.KeyringBackend(),
+		KeyringAvailable: config.ProbeKeyring(),
+	}
+
+	if configPathOutput == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	}
+
+	fmt.Printf("Config file:     %s\n", doc.ConfigFile)
+	fmt.Printf("Config dir:      %s\n", doc.ConfigDir)
+	fmt.Printf("Keyring service: %s\n", doc.KeyringService)
+	fmt.Printf("Keyring backend: %s\n", doc.KeyringBackend)
+	if doc.KeyringAvailable {
+		fmt.Printf("Keyring:         %s\n", green("available"))
+	} else {
+		fmt.Printf("Keyring:         %s (tokens are stored in the config file)\n", yellow("unavailable"))
+	}
+	return nil
+}
+
+// configProviderOutput is the machine-readable form of a provider's settings
+type configProviderOutput struct {
+	BaseURL         string `json:"base_url"`
+	TokenConfigured bool   `json:"token_configured"`
+}
+
+// configMirrorOutput is the machine-readable form of the mirror settings
+type configMirrorOutput struct {
+	BaseDir      string `json:"base_dir"`
+	Parallel     int    `json:"parallel"`
+	SkipArchived bool   `json:"skip_archived"`
+	MaxAgeMonths int    `json:"max_age_months"`
+	SSH          bool   `json:"ssh"`
+	Protocol     string `json:"protocol,omitempty"`
+	Verbose      bool   `json:"verbose"`
+}
+
+// configOutputDoc is the effective configuration emitted by `config --output json`.
+// Tokens are never included, only whether one is configured.
+type configOutputDoc struct {
+	ConfigFile       string               `json:"config_file"`
+	KeyringAvailable bool                 `json:"keyring_available"`
+	DefaultProvider  string               `json:"default_provider"`
+	GitLab           configProviderOutput `json:"gitlab"`
+	GitHub           configProviderOutput `json:"github"`
+	Bitbucket        configProviderOutput `json:"bitbucket"`
+	Mirror           configMirrorOutput   `json:"mirror"`
+	Debug            bool                 `json:"debug"`
+}
+
 func runConfig(cmd *cobra.Command, args []string) error {
+	switch configOutput {
+	case "text":
+	case "json":
+		return printConfigJSON()
+	default:
+		return fmt.Errorf("invalid output format: %q (must be 'text' or 'json')", configOutput)
+	}
+
 	fmt.Printf("Configuration file: %s\n\n", config.GetConfigFile())
 
 	fmt.Println("GitLab:")
@@ -692,11 +2214,62 @@ func runConfig(cmd *cobra.Command, args []string) error {
 		fmt.Println("  Token: (not set)")
 	}
 
+	fmt.Println()
+	fmt.Println("Bitbucket:")
+	fmt.Printf("  URL:   %s\n", cfg.Bitbucket.BaseURL)
+	if cfg.Bitbucket.Token != "" {
+		fmt.Printf("  Token: %s\n", green("***configured***"))
+	} else {
+		fmt.Println("  Token: (not set)")
+	}
+
 	fmt.Println()
 	fmt.Println("Mirror:")
 	fmt.Printf("  Base directory: %s\n", cfg.Mirror.BaseDir)
 	fmt.Printf("  Parallel:       %d\n", cfg.Mirror.Parallel)
 	fmt.Printf("  Skip archived:  %t\n", cfg.Mirror.SkipArchived)
+	fmt.Printf("  Max age:        %d months\n", cfg.Mirror.MaxAgeMonths)
+	fmt.Printf("  SSH:            %t\n", cfg.Mirror.UseSSH())
+	if cfg.Mirror.Protocol != "" {
+		fmt.Printf("  Protocol:       %s\n", cfg.Mirror.Protocol)
+	}
+	fmt.Printf("  Verbose:        %t\n", cfg.Mirror.Verbose)
 
 	return nil
 }
+
+// printConfigJSON writes the effective configuration as JSON to stdout
+func printConfigJSON() error {
+	doc := configOutputDoc{
+		ConfigFile:      config.GetConfigFile(),
+		DefaultProvider: cfg.DefaultProvider,
+		GitLab: configProviderOutput{
+			BaseURL:         cfg.GetBaseURL("gitlab"),
+			TokenConfigured: cfg.GetToken("gitlab", cfg.GetBaseURL("gitlab")) != "",
+		},
+		GitHub: configProviderOutput{
+			BaseURL:         cfg.GetBaseURL("github"),
+			TokenConfigured: cfg.GetToken("github", cfg.GetBaseURL("github")) != "",
+		},
+		Bitbucket: configProviderOutput{
+			BaseURL:         cfg.GetBaseURL("bitbucket"),
+			TokenConfigured: cfg.GetToken("bitbucket", cfg.GetBaseURL("bitbucket")) != "",
+		},
+		Mirror: configMirrorOutput{
+			BaseDir:      cfg.Mirror.BaseDir,
+			Parallel:     cfg.Mirror.Parallel,
+			SkipArchived: cfg.Mirror.SkipArchived,
+			MaxAgeMonths: cfg.Mirror.MaxAgeMonths,
+			SSH:          cfg.Mirror.UseSSH(),
+			Protocol:     cfg.Mirror.Protocol,
+			Verbose:      cfg.Mirror.Verbose,
+		},
+		Debug: cfg.Debug,
+	}
+	// Probe after token lookups so a failed keychain access is reflected
+	doc.KeyringAvailable = config.IsKeyringAvailable()
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
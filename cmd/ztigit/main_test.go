@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// checkProvider lists the repos of groups for --check-only. Provider methods the
+// check does not call are left to the nil embedded interface.
+type checkProvider struct {
+	provider.Provider
+
+	repos    map[string][]provider.Repository // Repos by group
+	listErrs map[string]error                 // Listing errors by group
+	connErr  error                            // TestConnection result
+
+	connected bool // TestConnection was called
+}
+
+func (c *checkProvider) TestConnection(ctx context.Context) error {
+	c.connected = true
+	return c.connErr
+}
+
+func (c *checkProvider) GetCurrentUser(ctx context.Context) (string, error) {
+	return "alice", nil
+}
+
+func (c *checkProvider) ListGroupProjectsStream(ctx context.Context, groupPath string, fn func(provider.Repository) error) error {
+	if err := c.listErrs[groupPath]; err != nil {
+		return err
+	}
+	for _, repo := range c.repos[groupPath] {
+		if err := fn(repo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRunMirrorCheckOnly(t *testing.T) {
+	repos := map[string][]provider.Repository{
+		"devops": {{FullPath: "devops/api"}, {FullPath: "devops/web"}},
+		"empty":  nil,
+	}
+	listErrs := map[string]error{"secret": provider.ErrNotFound}
+
+	tests := []struct {
+		name          string
+		token         string
+		groups        []string
+		connErr       error
+		wantErr       string // Substring of the error; empty = success
+		wantConnected bool
+	}{
+		{name: "accessible group", token: "t", groups: []string{"devops"}, wantConnected: true},
+		{name: "empty group is not an error", token: "t", groups: []string{"empty"}, wantConnected: true},
+		{name: "inaccessible group", token: "t", groups: []string{"devops", "secret"}, wantErr: "cannot list secret", wantConnected: true},
+		{name: "no token checks public access only", groups: []string{"devops"}},
+		{name: "no token, inaccessible group", groups: []string{"secret"}, wantErr: "cannot list secret"},
+		{name: "connection failure", token: "t", groups: []string{"devops"}, connErr: errors.New("401 Unauthorized"), wantErr: "connection failed", wantConnected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &checkProvider{repos: repos, listErrs: listErrs, connErr: tt.connErr}
+			err := runMirrorCheckOnly(context.Background(), p, tt.token, tt.groups)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("runMirrorCheckOnly() error = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("runMirrorCheckOnly() error = %v, want %q", err, tt.wantErr)
+			}
+			if p.connected != tt.wantConnected {
+				t.Errorf("TestConnection called = %v, want %v", p.connected, tt.wantConnected)
+			}
+		})
+	}
+}
+
+func TestParseParallel(t *testing.T) {
+	parallel, clone, update, err := parseParallel("auto")
+	if err != nil {
+		t.Fatalf("parseParallel(auto) error = %v", err)
+	}
+	// Each kind must be able to fill its own slots while the other's are all taken
+	if parallel != clone+update {
+		t.Errorf("parseParallel(auto) = %d, want clone + update = %d", parallel, clone+update)
+	}
+
+	tests := []struct {
+		value    string
+		parallel int
+		wantErr  bool
+	}{
+		{value: "4", parallel: 4},
+		{value: "1", parallel: 1},
+		{value: "0", wantErr: true},
+		{value: "many", wantErr: true},
+	}
+	for _, tt := range tests {
+		parallel, clone, update, err := parseParallel(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseParallel(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if parallel != tt.parallel || clone != 0 || update != 0 {
+			t.Errorf("parseParallel(%q) = %d, %d, %d; want %d, 0, 0", tt.value, parallel, clone, update, tt.parallel)
+		}
+	}
+}
+
+func TestParseGitConfigFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		protocolV2 bool
+		entries    []string
+		want       string // key=value entries, in order
+		wantErr    bool
+	}{
+		{name: "none"},
+		{name: "protocol v2", protocolV2: true, want: "protocol.version=2"},
+		{name: "git config", entries: []string{"http.postBuffer=524288000"}, want: "http.postBuffer=524288000"},
+		// An explicit protocol.version comes later, so git applies it over --protocol-v2
+		{
+			name:       "explicit protocol version wins",
+			protocolV2: true,
+			entries:    []string{"protocol.version=1"},
+			want:       "protocol.version=2 protocol.version=1",
+		},
+		{name: "invalid entry", protocolV2: true, entries: []string{"novalue"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGitConfigFlags(tt.protocolV2, tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseGitConfigFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var entries []string
+			for _, gc := range got {
+				entries = append(entries, gc.Key+"="+gc.Value)
+			}
+			if strings.Join(entries, " ") != tt.want {
+				t.Errorf("parseGitConfigFlags() = %v, want %q", entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestTestConnections(t *testing.T) {
+	// A fresh fake per connection, since they are tested at once
+	ok := func() provider.Provider { return &checkProvider{} }
+	down := func() provider.Provider { return &checkProvider{connErr: errors.New("dial tcp: connection refused")} }
+	denied := func() provider.Provider { return &checkProvider{connErr: errors.New("401 Unauthorized")} }
+
+	tests := []struct {
+		name    string
+		conns   []connection
+		wantErr string // Full error; empty = success
+	}{
+		{name: "all connected", conns: []connection{{"source", ok()}, {"destination", ok()}}},
+		{
+			name:    "one failed",
+			conns:   []connection{{"source", ok()}, {"destination", down()}},
+			wantErr: "connection failed for 1 of 2 providers:\n  destination: dial tcp: connection refused",
+		},
+		// Every failure is listed, in the order the connections were given
+		{
+			name:    "both failed",
+			conns:   []connection{{"source", denied()}, {"destination", down()}},
+			wantErr: "connection failed for 2 of 2 providers:\n  source: 401 Unauthorized\n  destination: dial tcp: connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testConnections(context.Background(), tt.conns)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("testConnections() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("testConnections() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zsoftly/ztigit/internal/mirror"
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// destTokenEnv holds the destination token when source and destination are the same
+// provider type on different hosts, since the config keeps one token per provider
+const destTokenEnv = "ZTIGIT_DEST_TOKEN"
+
+// Replicate command
+var replicateCmd = &cobra.Command{
+	Use:   "replicate <url-or-org>",
+	Short: "Replicate a group/org to another host",
+	Long: `Replicate every repository of a group or organization to an org or group on
+another host, e.g. as an off-site backup.
+
+Each repo is kept as a bare mirror under --dir, then its branches and tags are
+pushed to a repo of the same name in --dest-org, which is created (with the
+source's description and visibility) if it does not exist. Subgroup paths are
+joined with "-" (acme/platform/api becomes <dest-org>/platform-api).
+
+The destination token comes from ` + destTokenEnv + `, or the stored token of
+the destination provider.
+
+Examples:
+  ztigit replicate https://gitlab.com/company --dest-provider github --dest-org company-backup
+  ZTIGIT_DEST_TOKEN=... ztigit replicate https://github.com/zsoftly --dest-url https://ghe.example.com --dest-org zsoftly`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReplicate,
+}
+
+var (
+	replicateProvider     string
+	replicateDestProvider string
+	replicateDestURL      string
+	replicateDestOrg      string
+	replicateDir          string
+	replicateParallel     int
+	replicateSSH          bool
+	replicateVerbose      bool
+	replicateGrep         string
+)
+
+func init() {
+	replicateCmd.Flags().StringVarP(&replicateProvider, "provider", "p", "", "Source provider type: gitlab, github, or bitbucket (auto-detected from URL)")
+	replicateCmd.Flags().StringVar(&replicateDestProvider, "dest-provider", "", "Destination provider type: gitlab, github, or bitbucket (auto-detected from --dest-url)")
+	replicateCmd.Flags().StringVar(&replicateDestURL, "dest-url", "", "Destination base URL (default: the destination provider's configured URL)")
+	replicateCmd.Flags().StringVar(&replicateDestOrg, "dest-org", "", "Destination org or group to create and push repos in")
+	replicateCmd.Flags().StringVarP(&replicateDir, "dir", "d", "", "Directory for the bare mirrors pushed from (default: $HOME/<org>-replica)")
+	replicateCmd.Flags().IntVar(&replicateParallel, "parallel", 4, "Number of parallel repos")
+	replicateCmd.Flags().BoolVar(&replicateSSH, "ssh", false, "Use SSH URLs instead of HTTPS for git operations")
+	replicateCmd.Flags().BoolVar(&noTestConnection, "no-test-connection", false, noTestConnectionUsage)
+	replicateCmd.Flags().BoolVarP(&replicateVerbose, "verbose", "v", false, "Verbose output")
+	replicateCmd.Flags().StringVar(&replicateGrep, "grep", "", "Only replicate repos whose name, path, or description contains this text (case-insensitive)")
+	replicateCmd.MarkFlagRequired("dest-org")
+	replicateCmd.MarkFlagsOneRequired("dest-provider", "dest-url")
+	rootCmd.AddCommand(replicateCmd)
+}
+
+func runReplicate(cmd *cobra.Command, args []string) error {
+	if err := mirror.CheckGitInstalled(); err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	// Source: a URL or an org name with --provider
+	target := args[0]
+	var group, baseURL string
+	var providerType provider.ProviderType
+	if strings.HasPrefix(target, "https://") || strings.HasPrefix(target, "http://") {
+		parsed, err := parseGitURL(target)
+		if err != nil {
+			return err
+		}
+		group, baseURL, providerType = parsed.orgName, parsed.baseURL, parsed.provider
+	} else {
+		if replicateProvider == "" {
+			return fmt.Errorf("provider required when not using URL. Use --provider github or --provider gitlab")
+		}
+		group = target
+		providerType = provider.ProviderType(replicateProvider)
+		baseURL = cfg.GetBaseURL(string(providerType))
+	}
+	if replicateProvider != "" {
+		providerType = provider.ProviderType(replicateProvider)
+	}
+	if err := validateProviderType(providerType); err != nil {
+		return err
+	}
+
+	// Destination
+	destType := provider.ProviderType(replicateDestProvider)
+	if replicateDestProvider == "" {
+		destType = provider.DetectProvider(replicateDestURL)
+	}
+	if err := validateProviderType(destType); err != nil {
+		return err
+	}
+	destURL := replicateDestURL
+	if destURL == "" {
+		destURL = cfg.GetBaseURL(string(destType))
+	}
+	destURL = strings.TrimSuffix(destURL, "/")
+	if strings.EqualFold(destURL, baseURL) && strings.EqualFold(replicateDestOrg, group) {
+		return fmt.Errorf("destination %s/%s is the source", destURL, replicateDestOrg)
+	}
+
+	destToken := os.Getenv(destTokenEnv)
+	if destToken == "" {
+		destToken = cfg.GetToken(string(destType), destURL)
+	}
+	if destToken == "" {
+		return fmt.Errorf("no token for the destination (set %s or run 'ztigit auth login -p %s')", destTokenEnv, destType)
+	}
+	token := cfg.GetToken(string(providerType), baseURL)
+	if err := validateURLSecurity(baseURL, token); err != nil {
+		return err
+	}
+	if err := validateURLSecurity(destURL, destToken); err != nil {
+		return err
+	}
+
+	p, err := newProvider(providerType, token, baseURL)
+	if err != nil {
+		return err
+	}
+	dest, err := newProvider(destType, destToken, destURL)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s Connecting to %s\n", cyan("→"), bold(baseURL))
+	if token == "" {
+		fmt.Printf("%s No token - public repos only\n", yellow("!"))
+	}
+	fmt.Printf("%s Connecting to %s\n", cyan("→"), bold(destURL))
+	if !noTestConnection {
+		conns := []connection{{name: "destination " + destURL, p: dest}}
+		if token != "" {
+			conns = append([]connection{{name: "source " + baseURL, p: p}}, conns...)
+		}
+		if err := testConnections(ctx, conns); err != nil {
+			return err
+		}
+	}
+	fmt.Println()
+
+	opts := mirror.Options{
+		BaseDir:      replicateDir,
+		Parallel:     replicateParallel,
+		SkipArchived: cfg.Mirror.SkipArchived,
+		Verbose:      replicateVerbose,
+		SSH:          replicateSSH,
+		Grep:         replicateGrep,
+
+		Bare:      true,
+		Replicate: &mirror.Replica{Provider: dest, Namespace: replicateDestOrg, Token: destToken},
+	}
+	if opts.BaseDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil || homeDir == "" {
+			homeDir = "." // Fallback to current directory
+		}
+		opts.BaseDir = filepath.Join(homeDir, group+"-replica")
+	}
+
+	m := mirror.New(p, opts)
+	fmt.Printf("%s Replicating %s to %s/%s (mirrors in %s)\n\n", cyan("→"), bold(group), destURL, bold(replicateDestOrg), opts.BaseDir)
+	results, err := m.MirrorGroups(ctx, []string{group})
+	if err != nil && len(results) == 0 {
+		return checkTokenExpired(providerType, err)
+	}
+
+	mirror.PrintResults(results)
+	if err != nil {
+		return checkTokenExpired(providerType, err)
+	}
+
+	if s := mirror.Summarize(results); s.Failed > 0 {
+		return fmt.Errorf("%d repo(s) failed to replicate", s.Failed)
+	}
+	return nil
+}
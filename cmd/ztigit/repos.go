@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zsoftly/ztigit/internal/mirror"
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// Repos command
+var reposCmd = &cobra.Command{
+	Use:   "repos",
+	Short: "Inspect repositories",
+	Long:  `List and summarize the repositories of a group or organization without cloning.`,
+}
+
+var reposListCmd = &cobra.Command{
+	Use:   "list <url-or-org>",
+	Short: "List repositories of a group/org",
+	Long: `List the repositories of a group or organization with their size and last activity.
+
+Examples:
+  ztigit repos list https://github.com/zsoftly
+  ztigit repos list devops -p gitlab --grep payments
+  ztigit repos list https://github.com/zsoftly --language Go
+  ztigit repos list https://gitlab.com/company --format tree
+  ztigit repos list https://gitlab.com/company --with-env-count
+  ztigit repos list https://gitlab.com/company --format urls --ssh
+  ztigit repos list https://gitlab.com/company --include-size-breakdown --top 20`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReposList,
+}
+
+var (
+	reposProvider      string
+	reposGrep          string
+	reposLanguage      string
+	reposSizeBreakdown bool
+	reposTop           int
+	reposFormat        string
+	reposEnvCount      bool
+	reposSSH           bool
+)
+
+func init() {
+	reposListCmd.Flags().StringVarP(&reposProvider, "provider", "p", "", "Provider type: gitlab, github, or bitbucket (auto-detected from URL)")
+	reposListCmd.Flags().StringVar(&reposGrep, "grep", "", "Only list repos whose name, path, or description contains this text (case-insensitive)")
+	reposListCmd.Flags().StringVar(&reposLanguage, "language", "", "Only list repos whose primary language is one of these (comma-separated, e.g., \"Go,Python\")")
+	reposListCmd.Flags().BoolVar(&reposSizeBreakdown, "include-size-breakdown", false, "Show the largest repos, a size histogram, and the total size")
+	reposListCmd.Flags().IntVar(&reposTop, "top", 10, "Number of largest repos to show with --include-size-breakdown")
+	reposListCmd.Flags().BoolVar(&reposEnvCount, "with-env-count", false, "Show how many deployment environments each repo has (one API call per repo)")
+	reposListCmd.Flags().StringVar(&reposFormat, "format", "list", "Output format: list, tree to show the group hierarchy with sizes per group, urls for one clone URL per line, or env for NAME=url lines")
+	reposListCmd.Flags().BoolVar(&reposSSH, "ssh", false, "With --format urls or env, print SSH clone URLs instead of HTTPS")
+	reposCmd.AddCommand(reposListCmd)
+	rootCmd.AddCommand(reposCmd)
+}
+
+func runReposList(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if reposTop < 1 {
+		return fmt.Errorf("--top must be at least 1")
+	}
+	switch reposFormat {
+	case "list", "tree", "urls", "env":
+	default:
+		return fmt.Errorf("invalid format: %q (must be 'list', 'tree', 'urls', or 'env')", reposFormat)
+	}
+	if reposEnvCount && reposFormat != "list" {
+		return fmt.Errorf("--with-env-count requires --format list")
+	}
+	urlFormat := reposFormat == "urls" || reposFormat == "env"
+	if reposSSH && !urlFormat {
+		return fmt.Errorf("--ssh requires --format urls or env")
+	}
+	if reposSizeBreakdown && urlFormat {
+		return fmt.Errorf("--include-size-breakdown cannot be combined with --format %s", reposFormat)
+	}
+
+	// URL output is meant for other tools: progress goes to stderr
+	var progress io.Writer = os.Stdout
+	if urlFormat {
+		progress = os.Stderr
+	}
+
+	// Determine group and provider from a URL or org name
+	target := args[0]
+	var group, baseURL string
+	var providerType provider.ProviderType
+
+	if strings.HasPrefix(target, "https://") || strings.HasPrefix(target, "http://") {
+		parsed, err := parseGitURL(target)
+		if err != nil {
+			return err
+		}
+		group, baseURL, providerType = parsed.orgName, parsed.baseURL, parsed.provider
+	} else {
+		if reposProvider == "" {
+			return fmt.Errorf("provider required when not using URL. Use --provider github or --provider gitlab")
+		}
+		group = target
+		providerType = provider.ProviderType(reposProvider)
+		baseURL = cfg.GetBaseURL(string(providerType))
+	}
+	if reposProvider != "" {
+		providerType = provider.ProviderType(reposProvider)
+	}
+
+	if err := validateProviderType(providerType); err != nil {
+		return err
+	}
+
+	// Token is optional for public repos
+	token := cfg.GetToken(string(providerType), baseURL)
+	if err := validateURLSecurity(baseURL, token); err != nil {
+		return err
+	}
+
+	// Create provider
+	p, err := newProvider(providerType, token, baseURL)
+	if err != nil {
+		return err
+	}
+
+	m := mirror.New(p, mirror.Options{Parallel: 4, Languages: mirror.ParseLanguages(reposLanguage), Progress: progress})
+	repos, err := m.ListRepos(ctx, []string{group})
+	if err != nil {
+		return checkTokenExpired(providerType, err)
+	}
+	repos = m.FilterLanguages(ctx, mirror.FilterGrep(repos, reposGrep))
+
+	switch reposFormat {
+	case "tree":
+		mirror.PrintRepoTree(mirror.BuildRepoTree(repos))
+	case "urls":
+		mirror.WriteRepoURLs(os.Stdout, repos, reposSSH)
+	case "env":
+		mirror.WriteRepoEnv(os.Stdout, repos, reposSSH)
+	default:
+		var envCounts map[string]int
+		if reposEnvCount {
+			envCounts = m.EnvironmentCounts(ctx, repos)
+		}
+		mirror.PrintRepoList(repos, envCounts)
+	}
+	if reposSizeBreakdown {
+		mirror.PrintSizeBreakdown(mirror.BreakDownSizes(repos, reposTop), len(repos))
+	}
+	return nil
+}
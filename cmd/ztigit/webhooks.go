@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zsoftly/ztigit/internal/provider"
+	"github.com/zsoftly/ztigit/internal/webhooks"
+)
+
+// webhookSecretEnv is the environment variable set-secret reads the new secret from
+const webhookSecretEnv = "ZTIGIT_WEBHOOK_SECRET"
+
+// Webhooks command
+var webhooksCmd = &cobra.Command{
+	Use:   "webhooks",
+	Short: "List project webhooks and rotate their secrets",
+	Long: `List the webhooks of a project, or of every project in a group or GitHub org
+(including subgroups), with their status and events.
+
+Use "webhooks set-secret" to rotate the secret of matching webhooks.`,
+	Example: `  ztigit webhooks -P devops/deploy-tools -p gitlab
+  ztigit webhooks -g zsoftly -p github --match 'ci\.example\.com'`,
+	Args: cobra.NoArgs,
+	RunE: runWebhooks,
+}
+
+var webhooksSetSecretCmd = &cobra.Command{
+	Use:   "set-secret",
+	Short: "Rotate the secret of matching webhooks",
+	Long: `Set a new secret on the webhooks of a project, or of every project in a group
+or GitHub org (including subgroups), whose URL matches --match. --all selects
+every webhook instead.
+
+The secret is never passed on the command line: it is read from the
+ZTIGIT_WEBHOOK_SECRET environment variable, from stdin when piped, or from a prompt.`,
+	Example: `  # Preview, then rotate the secret of every CI webhook in a group
+  ztigit webhooks set-secret -g devops -p gitlab --match 'ci\.example\.com' --dry-run
+  ZTIGIT_WEBHOOK_SECRET="$NEW_SECRET" ztigit webhooks set-secret -g devops -p gitlab --match 'ci\.example\.com'`,
+	Args: cobra.NoArgs,
+	RunE: runWebhooksSetSecret,
+}
+
+var (
+	webhooksProject  string
+	webhooksGroup    string
+	webhooksURL      string
+	webhooksProvider string
+	webhooksMatch    string
+	webhooksAll      bool
+	webhooksDryRun   bool
+)
+
+func init() {
+	flags := webhooksCmd.PersistentFlags()
+	flags.StringVarP(&webhooksProject, "project", "P", "", "Project path (e.g., group/project)")
+	flags.StringVarP(&webhooksGroup, "group", "g", "", "Every project of this group/org (including subgroups)")
+	flags.StringVarP(&webhooksURL, "url", "u", "", "Git hosting URL")
+	flags.StringVarP(&webhooksProvider, "provider", "p", "", "Provider type: gitlab or github")
+	flags.StringVar(&webhooksMatch, "match", "", "Only webhooks whose URL matches this Go regular expression")
+	flags.BoolVar(&noTestConnection, "no-test-connection", false, noTestConnectionUsage)
+	webhooksCmd.MarkFlagsOneRequired("project", "group")
+	webhooksCmd.MarkFlagsMutuallyExclusive("project", "group")
+
+	webhooksSetSecretCmd.Flags().BoolVar(&webhooksAll, "all", false, "Rotate the secret of every webhook (instead of --match)")
+	webhooksSetSecretCmd.Flags().BoolVar(&webhooksDryRun, "dry-run", false, "Show which webhooks would be updated without making changes")
+
+	webhooksCmd.AddCommand(webhooksSetSecretCmd)
+	rootCmd.AddCommand(webhooksCmd)
+}
+
+// newWebhookManager creates the provider for a webhooks command, checks that it manages
+// webhooks, and tests the connection
+func newWebhookManager(ctx context.Context, dryRun bool) (*webhooks.Manager, provider.ProviderType, error) {
+	if webhooksProvider == "" && webhooksURL == "" {
+		return nil, "", fmt.Errorf("at least one of --provider or --url must be specified")
+	}
+	providerType := provider.ProviderType(webhooksProvider)
+	if webhooksProvider == "" {
+		providerType = provider.DetectProvider(webhooksURL)
+	}
+
+	baseURL := webhooksURL
+	if baseURL == "" {
+		baseURL = cfg.GetBaseURL(string(providerType))
+	}
+	token := cfg.GetToken(string(providerType), baseURL)
+	if token == "" {
+		return nil, providerType, fmt.Errorf("no token configured for %s", providerType)
+	}
+
+	opts := webhooks.Options{DryRun: dryRun}
+	if webhooksMatch != "" {
+		re, err := regexp.Compile(webhooksMatch)
+		if err != nil {
+			return nil, providerType, fmt.Errorf("invalid --match: %w", err)
+		}
+		opts.Match = re
+	}
+
+	p, err := newProvider(providerType, token, baseURL)
+	if err != nil {
+		return nil, providerType, err
+	}
+	hooks, ok := p.(provider.WebhookManager)
+	if !ok {
+		return nil, providerType, errNotSupported("webhooks", providerType)
+	}
+
+	if !noTestConnection {
+		if err := p.TestConnection(ctx); err != nil {
+			return nil, providerType, err
+		}
+	}
+
+	return webhooks.New(p, hooks, opts), providerType, nil
+}
+
+func runWebhooks(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	m, providerType, err := newWebhookManager(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	if webhooksGroup != "" {
+		fmt.Printf("Listing webhooks of projects in %s...\n\n", webhooksGroup)
+		projects, err := m.ListGroupWebhooks(ctx, webhooksGroup)
+		if err != nil {
+			return checkTokenExpired(providerType, err)
+		}
+		webhooks.PrintGroupWebhooks(projects)
+		return nil
+	}
+
+	hooks, err := m.ListWebhooks(ctx, webhooksProject)
+	if err != nil {
+		return checkTokenExpired(providerType, err)
+	}
+	webhooks.PrintWebhooks(hooks)
+	return nil
+}
+
+func runWebhooksSetSecret(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// --match is inherited from webhooks, so cobra flag groups cannot cover it here
+	if (webhooksMatch == "") == !webhooksAll {
+		return fmt.Errorf("exactly one of --match or --all is required")
+	}
+
+	// Read the secret first, so a missing one fails before any API call; a dry run sends none
+	var secret string
+	if !webhooksDryRun {
+		secret = getWebhookSecret()
+		if secret == "" {
+			return fmt.Errorf("no secret provided. Set %s or pipe the secret via stdin", webhookSecretEnv)
+		}
+	}
+
+	m, providerType, err := newWebhookManager(ctx, webhooksDryRun)
+	if err != nil {
+		return err
+	}
+
+	if webhooksDryRun {
+		fmt.Println("[DRY-RUN] The following webhooks would get the new secret:")
+		fmt.Println()
+	}
+
+	if webhooksGroup != "" {
+		fmt.Printf("Rotating webhook secrets in projects of %s...\n\n", webhooksGroup)
+		projects, err := m.SetGroupSecret(ctx, webhooksGroup, secret)
+		if len(projects) > 0 {
+			webhooks.PrintGroupResults(projects, webhooksDryRun)
+		}
+		return checkTokenExpired(providerType, err)
+	}
+
+	results, err := m.SetSecret(ctx, webhooksProject, secret)
+	if len(results) > 0 {
+		webhooks.PrintResults(results, webhooksDryRun)
+	}
+	return checkTokenExpired(providerType, err)
+}
+
+// getWebhookSecret reads the new webhook secret from the environment, piped stdin, or a
+// prompt, like auth login reads tokens
+func getWebhookSecret() string {
+	if secret := os.Getenv(webhookSecretEnv); secret != "" {
+		return secret
+	}
+
+	stat, _ := os.Stdin.Stat()
+	if (stat.Mode() & os.ModeCharDevice) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		if scanner.Scan() {
+			return strings.TrimSpace(scanner.Text())
+		}
+		return ""
+	}
+
+	fmt.Print("Enter new webhook secret: ")
+	scanner := bufio.NewScanner(os.Stdin)
+	if scanner.Scan() {
+		return strings.TrimSpace(scanner.Text())
+	}
+	return ""
+}
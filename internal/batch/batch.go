@@ -0,0 +1,98 @@
+// Package batch holds the scaffolding shared by operations that change every project
+// of a group in turn (protect, webhooks): per-project results, counting, printing,
+// and pacing of write requests
+package batch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ProjectResults holds the results of one project in a group run
+type ProjectResults[R any] struct {
+	Project string
+	Results []R
+	Error   error // The project's items could not be listed
+}
+
+// Count counts results by the action each one reports
+func Count[R any](results []R, action func(R) string) map[string]int {
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[action(r)]++
+	}
+	return counts
+}
+
+// Prefix returns the prefix of result lines: "[DRY-RUN] " for a dry run, else ""
+func Prefix(dryRun bool) string {
+	if dryRun {
+		return "[DRY-RUN] "
+	}
+	return ""
+}
+
+// PrintProjects prints the results of a group run per project with printLine, and
+// projects whose items could not be listed with their error. Projects without results
+// are only counted. It returns the results of all projects, for the caller's summary.
+func PrintProjects[R any](projects []ProjectResults[R], dryRun bool, printLine func(r R, prefix string)) (all []R, without, listFailed int) {
+	prefix := Prefix(dryRun)
+	for _, p := range projects {
+		if p.Error != nil {
+			listFailed++
+			fmt.Printf("%s\n  %s[FAIL] %v\n\n", p.Project, prefix, p.Error)
+			continue
+		}
+		if len(p.Results) == 0 {
+			without++
+			continue
+		}
+
+		fmt.Println(p.Project)
+		for _, r := range p.Results {
+			printLine(r, "  "+prefix)
+		}
+		fmt.Println()
+		all = append(all, p.Results...)
+	}
+	return all, without, listFailed
+}
+
+// Limiter spaces out write requests to stay clear of API rate limits. It is shared by
+// all workers of a run, so the request rate stays the same however many run at once.
+type Limiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time // Earliest time the next request may start
+}
+
+// NewLimiter returns a Limiter that lets one request through per interval
+func NewLimiter(interval time.Duration) *Limiter {
+	return &Limiter{interval: interval}
+}
+
+// Wait blocks until the caller's turn to send a request, or until ctx is done, and
+// returns ctx.Err(). The first request goes through at once.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait := time.Until(at); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+	}
+	return ctx.Err()
+}
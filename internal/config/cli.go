@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// CLI tools whose stored credentials can be imported with ImportCLIToken
+const (
+	CLIGitHub = "gh"
+	CLIGitLab = "glab"
+)
+
+// ImportCLIToken returns the token the gh or glab CLI has stored for host. The CLI
+// itself is asked first, so tokens it keeps in the system keyring are found; if it is
+// not installed or too old to print its token, its config file is read instead.
+func ImportCLIToken(tool, host string) (string, error) {
+	var args []string
+	var configFile, tokenKey string
+	switch tool {
+	case CLIGitHub:
+		args = []string{"auth", "token", "--hostname", host}
+		configFile = filepath.Join(ghConfigDir(), "hosts.yml")
+		tokenKey = host + "::oauth_token"
+	case CLIGitLab:
+		args = []string{"config", "get", "token", "--host", host}
+		configFile = filepath.Join(glabConfigDir(), "config.yml")
+		tokenKey = "hosts::" + host + "::token"
+	default:
+		return "", fmt.Errorf("unknown CLI: %s (use 'gh' or 'glab')", tool)
+	}
+
+	if out, err := exec.Command(tool, args...).Output(); err == nil {
+		if token := strings.TrimSpace(string(out)); token != "" {
+			return token, nil
+		}
+	}
+
+	// Host names contain dots, so use a different key delimiter
+	v := viper.NewWithOptions(viper.KeyDelimiter("::"))
+	v.SetConfigFile(configFile)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("no %s credentials found for %s (run '%s auth login' first)", tool, host, tool)
+		}
+		return "", fmt.Errorf("failed to read %s: %w", configFile, err)
+	}
+	token := v.GetString(strings.ToLower(tokenKey))
+	if token == "" {
+		return "", fmt.Errorf("no %s credentials found for %s (run '%s auth login' first)", tool, host, tool)
+	}
+	return token, nil
+}
+
+// ghConfigDir returns the gh CLI config directory, following gh's own lookup order
+func ghConfigDir() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh")
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("AppData"); dir != "" {
+			return filepath.Join(dir, "GitHub CLI")
+		}
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "gh")
+}
+
+// glabConfigDir returns the glab CLI config directory, following glab's own lookup order
+func glabConfigDir() string {
+	if dir := os.Getenv("GLAB_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "glab-cli")
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "glab-cli")
+}
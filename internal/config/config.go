@@ -2,12 +2,19 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/zalando/go-keyring"
+
+	"github.com/zsoftly/ztigit/internal/retry"
 )
 
 const (
@@ -26,6 +33,9 @@ type Config struct {
 	// GitHub configuration
 	GitHub GitHubConfig `mapstructure:"github"`
 
+	// Bitbucket Cloud configuration
+	Bitbucket BitbucketConfig `mapstructure:"bitbucket"`
+
 	// Mirror configuration
 	Mirror MirrorConfig `mapstructure:"mirror"`
 
@@ -45,6 +55,13 @@ type GitHubConfig struct {
 	BaseURL string `mapstructure:"base_url"`
 }
 
+// BitbucketConfig holds Bitbucket Cloud-specific configuration. The token is an
+// access token, or "username:app-password" for an app password.
+type BitbucketConfig struct {
+	Token   string `mapstructure:"token"`
+	BaseURL string `mapstructure:"base_url"`
+}
+
 // MirrorConfig holds mirror operation configuration
 type MirrorConfig struct {
 	// Base directory for cloned repositories
@@ -55,6 +72,75 @@ type MirrorConfig struct {
 
 	// Skip archived repositories
 	SkipArchived bool `mapstructure:"skip_archived"`
+
+	// Skip repos not updated in this many months (0 = no limit)
+	MaxAgeMonths int `mapstructure:"max_age_months"`
+
+	// Use SSH URLs instead of HTTPS for git operations
+	SSH bool `mapstructure:"ssh"`
+
+	// Preferred git protocol: "https" or "ssh" (empty = https with ssh fallback)
+	Protocol string `mapstructure:"protocol"`
+
+	// Verbose git output
+	Verbose bool `mapstructure:"verbose"`
+}
+
+// validate checks the values of settings that only accept a fixed set, so a typo in the
+// config file is reported instead of silently falling back to the default
+func (m *MirrorConfig) validate() error {
+	m.Protocol = strings.ToLower(strings.TrimSpace(m.Protocol))
+	switch m.Protocol {
+	case "", "https", "ssh":
+		return nil
+	}
+	return fmt.Errorf("invalid mirror.protocol %q: must be https or ssh", m.Protocol)
+}
+
+// UseSSH reports whether mirror git operations should prefer SSH
+func (m MirrorConfig) UseSSH() bool {
+	return m.SSH || strings.EqualFold(m.Protocol, "ssh")
+}
+
+// Token sources reported by TokenSource
+const (
+	TokenSourceKeychain = "keychain"
+	TokenSourceEnv      = "env"
+	TokenSourceConfig   = "config"
+	TokenSourceNone     = "none"
+)
+
+// tokenEnvVars lists the environment variables checked for each provider's token
+var tokenEnvVars = map[string][]string{
+	"gitlab":    {"GITLAB_TOKEN", "ZTIGIT_GITLAB_TOKEN"},
+	"github":    {"GITHUB_TOKEN", "ZTIGIT_GITHUB_TOKEN"},
+	"bitbucket": {"BITBUCKET_TOKEN", "ZTIGIT_BITBUCKET_TOKEN"},
+}
+
+// envName returns a provider name as used in environment variables (my-host -> MY_HOST)
+func envName(provider string) string {
+	return strings.ToUpper(strings.ReplaceAll(provider, "-", "_"))
+}
+
+// TokenEnvVars returns the environment variables checked for a provider's token.
+// Providers without a config section (registered by other packages) use
+// <NAME>_TOKEN and ZTIGIT_<NAME>_TOKEN.
+func TokenEnvVars(provider string) []string {
+	if vars, ok := tokenEnvVars[provider]; ok {
+		return vars
+	}
+	name := envName(provider)
+	return []string{name + "_TOKEN", "ZTIGIT_" + name + "_TOKEN"}
+}
+
+// envToken returns the first token set in a provider's environment variables
+func envToken(provider string) string {
+	for _, env := range TokenEnvVars(provider) {
+		if token := os.Getenv(env); token != "" {
+			return token
+		}
+	}
+	return ""
 }
 
 // DefaultConfig returns the default configuration
@@ -72,10 +158,14 @@ func DefaultConfig() *Config {
 		GitHub: GitHubConfig{
 			BaseURL: "https://github.com",
 		},
+		Bitbucket: BitbucketConfig{
+			BaseURL: "https://bitbucket.org",
+		},
 		Mirror: MirrorConfig{
 			BaseDir:      filepath.Join(homeDir, "git-repos"),
 			Parallel:     4,
 			SkipArchived: true,
+			MaxAgeMonths: 12,
 		},
 		Debug: false,
 	}
@@ -103,10 +193,12 @@ func Load() (*Config, error) {
 	viper.AutomaticEnv()
 
 	// Map environment variables
-	viper.BindEnv("gitlab.token", "GITLAB_TOKEN", "ZTIGIT_GITLAB_TOKEN")
+	viper.BindEnv(append([]string{"gitlab.token"}, tokenEnvVars["gitlab"]...)...)
 	viper.BindEnv("gitlab.base_url", "GITLAB_URL", "ZTIGIT_GITLAB_URL")
-	viper.BindEnv("github.token", "GITHUB_TOKEN", "ZTIGIT_GITHUB_TOKEN")
+	viper.BindEnv(append([]string{"github.token"}, tokenEnvVars["github"]...)...)
 	viper.BindEnv("github.base_url", "GITHUB_URL", "ZTIGIT_GITHUB_URL")
+	viper.BindEnv(append([]string{"bitbucket.token"}, tokenEnvVars["bitbucket"]...)...)
+	viper.BindEnv("bitbucket.base_url", "BITBUCKET_URL", "ZTIGIT_BITBUCKET_URL")
 
 	// Try to read config file (not required, ignore errors)
 	_ = viper.ReadInConfig()
@@ -115,10 +207,28 @@ func Load() (*Config, error) {
 	if err := viper.Unmarshal(cfg); err != nil {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
+	if err := cfg.Mirror.validate(); err != nil {
+		return nil, err
+	}
+
+	// Config file values are not shell-expanded
+	cfg.Mirror.BaseDir = ExpandPath(cfg.Mirror.BaseDir)
 
 	return cfg, nil
 }
 
+// ExpandPath expands $VAR and ${VAR} references and a leading ~ in a path,
+// so paths from config files and quoted flags behave as they would in a shell
+func ExpandPath(path string) string {
+	path = os.ExpandEnv(path)
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		if homeDir, err := os.UserHomeDir(); err == nil && homeDir != "" {
+			path = filepath.Join(homeDir, path[1:])
+		}
+	}
+	return path
+}
+
 // GetConfigDir returns the configuration directory path
 func GetConfigDir() string {
 	homeDir, err := os.UserHomeDir()
@@ -146,12 +256,17 @@ func Save(cfg *Config) error {
 	// Try to store tokens in keychain (secure storage)
 	keyringWorked := false
 	if cfg.GitLab.Token != "" {
-		if err := SetTokenSecure("gitlab", cfg.GitLab.Token); err == nil && keyringAvailable {
+		if err := SetTokenSecure("gitlab", cfg.GitLab.BaseURL, cfg.GitLab.Token); err == nil && keyringAvailable {
 			keyringWorked = true
 		}
 	}
 	if cfg.GitHub.Token != "" {
-		if err := SetTokenSecure("github", cfg.GitHub.Token); err == nil && keyringAvailable {
+		if err := SetTokenSecure("github", cfg.GitHub.BaseURL, cfg.GitHub.Token); err == nil && keyringAvailable {
+			keyringWorked = true
+		}
+	}
+	if cfg.Bitbucket.Token != "" {
+		if err := SetTokenSecure("bitbucket", cfg.Bitbucket.BaseURL, cfg.Bitbucket.Token); err == nil && keyringAvailable {
 			keyringWorked = true
 		}
 	}
@@ -160,19 +275,26 @@ func Save(cfg *Config) error {
 	viper.Set("default_provider", cfg.DefaultProvider)
 	viper.Set("gitlab.base_url", cfg.GitLab.BaseURL)
 	viper.Set("github.base_url", cfg.GitHub.BaseURL)
+	viper.Set("bitbucket.base_url", cfg.Bitbucket.BaseURL)
 	viper.Set("mirror.base_dir", cfg.Mirror.BaseDir)
 	viper.Set("mirror.parallel", cfg.Mirror.Parallel)
 	viper.Set("mirror.skip_archived", cfg.Mirror.SkipArchived)
+	viper.Set("mirror.max_age_months", cfg.Mirror.MaxAgeMonths)
+	viper.Set("mirror.ssh", cfg.Mirror.SSH)
+	viper.Set("mirror.protocol", cfg.Mirror.Protocol)
+	viper.Set("mirror.verbose", cfg.Mirror.Verbose)
 	viper.Set("debug", cfg.Debug)
 
 	// Only store tokens in config file if keychain is not available
 	if !keyringWorked {
 		viper.Set("gitlab.token", cfg.GitLab.Token)
 		viper.Set("github.token", cfg.GitHub.Token)
+		viper.Set("bitbucket.token", cfg.Bitbucket.Token)
 	} else {
 		// Clear tokens from config file if using keychain
 		viper.Set("gitlab.token", "")
 		viper.Set("github.token", "")
+		viper.Set("bitbucket.token", "")
 	}
 
 	// Write config file
@@ -189,11 +311,11 @@ func Save(cfg *Config) error {
 	return nil
 }
 
-// GetToken returns the token for the specified provider
+// GetToken returns the token for the specified provider instance at baseURL
 // Checks keychain first, then falls back to config file/env vars
-func (c *Config) GetToken(provider string) string {
+func (c *Config) GetToken(provider, baseURL string) string {
 	// Try keychain first (most secure)
-	if token := GetTokenSecure(provider); token != "" {
+	if token := c.keyringToken(provider, baseURL); token != "" {
 		return token
 	}
 
@@ -203,52 +325,183 @@ func (c *Config) GetToken(provider string) string {
 		return c.GitLab.Token
 	case "github":
 		return c.GitHub.Token
+	case "bitbucket":
+		return c.Bitbucket.Token
 	default:
-		return ""
+		return envToken(provider)
+	}
+}
+
+// TokenSource reports where the effective token for a provider instance comes from,
+// following the same priority as GetToken
+func (c *Config) TokenSource(provider, baseURL string) string {
+	if c.keyringToken(provider, baseURL) != "" {
+		return TokenSourceKeychain
+	}
+
+	if envToken(provider) != "" {
+		return TokenSourceEnv
+	}
+
+	switch provider {
+	case "gitlab":
+		if c.GitLab.Token != "" {
+			return TokenSourceConfig
+		}
+	case "github":
+		if c.GitHub.Token != "" {
+			return TokenSourceConfig
+		}
+	case "bitbucket":
+		if c.Bitbucket.Token != "" {
+			return TokenSourceConfig
+		}
 	}
+	return TokenSourceNone
 }
 
-// GetBaseURL returns the base URL for the specified provider
+// GetBaseURL returns the base URL for the specified provider. Providers without a
+// config section read ZTIGIT_<NAME>_URL.
 func (c *Config) GetBaseURL(provider string) string {
 	switch provider {
 	case "gitlab":
 		return c.GitLab.BaseURL
 	case "github":
 		return c.GitHub.BaseURL
+	case "bitbucket":
+		return c.Bitbucket.BaseURL
 	default:
-		return ""
+		return os.Getenv("ZTIGIT_" + envName(provider) + "_URL")
 	}
 }
 
 // keyringAvailable checks if the system keyring is available
 var keyringAvailable = true
 
-// SetTokenSecure stores a token securely in the system keychain
-// Falls back to config file if keychain is unavailable
-func SetTokenSecure(provider, token string) error {
+// Keychain access can fail transiently on macOS when another process holds the
+// keychain or it is briefly locked; those errors are retried before giving up
+var (
+	keyringRetries    = 3
+	keyringRetryDelay = 200 * time.Millisecond
+)
+
+// transientKeyringErrors are error fragments from lock contention rather than a missing keyring
+var transientKeyringErrors = []string{
+	"user interaction is not allowed", // errSecInteractionNotAllowed (-25308)
+	"-25308",
+	"keychain is locked",
+}
+
+// isTransientKeyringError reports whether a keyring error is likely to succeed on retry
+func isTransientKeyringError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range transientKeyringErrors {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// withKeyringRetry runs a keyring operation, retrying transient errors with backoff
+func withKeyringRetry(op func() error) error {
+	policy := retry.Policy{
+		MaxAttempts: keyringRetries + 1,
+		BaseDelay:   keyringRetryDelay,
+		Retryable:   isTransientKeyringError,
+	}
+	return retry.Do(context.Background(), policy, func(context.Context) error { return op() })
+}
+
+// keyringToken looks up the keychain token of the provider instance at baseURL. Tokens
+// saved before keys included the host belong to the provider's configured instance, and
+// are moved to that instance's key the first time it is looked up.
+func (c *Config) keyringToken(provider, baseURL string) string {
+	key := tokenKey(provider, baseURL)
+	if token := getKeyring(key); token != "" {
+		return token
+	}
+	if key != tokenKey(provider, c.GetBaseURL(provider)) {
+		return ""
+	}
+	return c.MigrateLegacyToken(provider)
+}
+
+// MigrateLegacyToken moves a token saved before keys included the host to the key of
+// the provider's configured instance, and returns it ("" if there is none). Call it
+// before changing the configured base URL: afterwards nothing would look the old
+// token up.
+func (c *Config) MigrateLegacyToken(provider string) string {
+	baseURL := c.GetBaseURL(provider)
+	legacy := legacyTokenKey(provider)
+	key := tokenKey(provider, baseURL)
+	// A token already under the instance's key is newer than the legacy one
+	if key == legacy || getKeyring(key) != "" {
+		return ""
+	}
+	token := getKeyring(legacy)
+	if token != "" {
+		if err := SetTokenSecure(provider, baseURL, token); err == nil && keyringAvailable {
+			_ = deleteKeyring(legacy)
+		}
+	}
+	return token
+}
+
+// tokenKey returns the keychain key of a provider's token for the instance at baseURL,
+// e.g. gitlab-token@gitlab.example.com, so several instances of one provider can each
+// have a token. Without a host in baseURL, the per-provider key is used.
+func tokenKey(provider, baseURL string) string {
+	u, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil || u.Host == "" {
+		return legacyTokenKey(provider)
+	}
+	return fmt.Sprintf("%s-token@%s", provider, strings.ToLower(u.Host))
+}
+
+// legacyTokenKey returns the per-provider keychain key tokens were stored under before
+// keys included the host
+func legacyTokenKey(provider string) string {
+	return fmt.Sprintf("%s-token", provider)
+}
+
+// SetTokenSecure stores a token for the provider instance at baseURL securely in the
+// system keychain. Falls back to config file if keychain is unavailable
+func SetTokenSecure(provider, baseURL, token string) error {
 	if !keyringAvailable {
 		return nil // Will use config file fallback
 	}
 
-	key := fmt.Sprintf("%s-token", provider)
-	err := keyring.Set(KeyringService, key, token)
+	key := tokenKey(provider, baseURL)
+	err := withKeyringRetry(func() error {
+		return keyring.Set(KeyringService, key, token)
+	})
 	if err != nil {
-		// Keyring not available (e.g., headless server), mark as unavailable
+		// Keyring not available (e.g., headless server) or still locked after retries
 		keyringAvailable = false
 		return nil // Fall back to config file storage
 	}
 	return nil
 }
 
-// GetTokenSecure retrieves a token from the system keychain
-// Returns empty string if not found or keychain unavailable
-func GetTokenSecure(provider string) string {
+// GetTokenSecure retrieves the token for the provider instance at baseURL from the
+// system keychain. Returns empty string if not found or keychain unavailable
+func GetTokenSecure(provider, baseURL string) string {
+	return getKeyring(tokenKey(provider, baseURL))
+}
+
+// getKeyring reads a keychain entry, returning "" if not found or keychain unavailable
+func getKeyring(key string) string {
 	if !keyringAvailable {
 		return ""
 	}
 
-	key := fmt.Sprintf("%s-token", provider)
-	token, err := keyring.Get(KeyringService, key)
+	var token string
+	err := withKeyringRetry(func() error {
+		var getErr error
+		token, getErr = keyring.Get(KeyringService, key)
+		return getErr
+	})
 	if err != nil {
 		// Keyring not available or token not found
 		if err == keyring.ErrNotFound {
@@ -261,13 +514,18 @@ func GetTokenSecure(provider string) string {
 	return token
 }
 
-// DeleteTokenSecure removes a token from the system keychain
-func DeleteTokenSecure(provider string) error {
+// DeleteTokenSecure removes the token for the provider instance at baseURL from the
+// system keychain
+func DeleteTokenSecure(provider, baseURL string) error {
+	return deleteKeyring(tokenKey(provider, baseURL))
+}
+
+// deleteKeyring removes a keychain entry; a missing entry is not an error
+func deleteKeyring(key string) error {
 	if !keyringAvailable {
 		return nil
 	}
 
-	key := fmt.Sprintf("%s-token", provider)
 	err := keyring.Delete(KeyringService, key)
 	if err != nil && err != keyring.ErrNotFound {
 		return err
@@ -279,3 +537,22 @@ func DeleteTokenSecure(provider string) error {
 func IsKeyringAvailable() bool {
 	return keyringAvailable
 }
+
+// ProbeKeyring checks whether the system keyring can be reached right now.
+// A lookup that finds nothing still proves the keyring answered.
+func ProbeKeyring() bool {
+	_, err := keyring.Get(KeyringService, "probe")
+	return err == nil || err == keyring.ErrNotFound
+}
+
+// KeyringBackend returns the name of the system keyring used on this platform
+func KeyringBackend() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "macOS Keychain"
+	case "windows":
+		return "Windows Credential Manager"
+	default:
+		return "Secret Service (D-Bus)"
+	}
+}
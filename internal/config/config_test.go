@@ -0,0 +1,228 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zalando/go-keyring"
+)
+
+// useMockKeyring stores tokens in memory for the test and marks the keyring available.
+// go-keyring cannot switch back to the system keyring, so cleanup leaves an empty mock
+// in place; tokens set by one test never reach another.
+func useMockKeyring(t *testing.T) {
+	available := keyringAvailable
+	keyring.MockInit()
+	keyringAvailable = true
+	t.Cleanup(func() {
+		keyring.MockInit()
+		keyringAvailable = available
+	})
+}
+
+func TestGetToken_MultipleInstances(t *testing.T) {
+	useMockKeyring(t)
+
+	cfg := DefaultConfig()
+	cfg.GitLab.BaseURL = "https://gitlab.example.com"
+	if err := SetTokenSecure("gitlab", "https://gitlab.com", "cloud-token"); err != nil {
+		t.Fatalf("SetTokenSecure error = %v", err)
+	}
+	if err := SetTokenSecure("gitlab", "https://gitlab.example.com", "self-hosted-token"); err != nil {
+		t.Fatalf("SetTokenSecure error = %v", err)
+	}
+
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"https://gitlab.com", "cloud-token"},
+		{"https://gitlab.example.com", "self-hosted-token"},
+		{"https://GitLab.Example.com/", "self-hosted-token"},
+		{"https://gitlab.other.com", ""},
+	}
+	for _, tt := range tests {
+		if got := cfg.GetToken("gitlab", tt.baseURL); got != tt.want {
+			t.Errorf("GetToken(gitlab, %s) = %q, want %q", tt.baseURL, got, tt.want)
+		}
+	}
+	if got := cfg.TokenSource("gitlab", "https://gitlab.com"); got != TokenSourceKeychain {
+		t.Errorf("TokenSource() = %q, want %q", got, TokenSourceKeychain)
+	}
+}
+
+func TestGetToken_MigratesLegacyKey(t *testing.T) {
+	useMockKeyring(t)
+
+	cfg := DefaultConfig()
+	cfg.GitLab.BaseURL = "https://gitlab.example.com"
+	if err := keyring.Set(KeyringService, "gitlab-token", "old-token"); err != nil {
+		t.Fatalf("keyring.Set error = %v", err)
+	}
+
+	// The old key belongs to the configured instance only
+	if got := cfg.GetToken("gitlab", "https://gitlab.com"); got != "" {
+		t.Errorf("GetToken(other instance) = %q, want none", got)
+	}
+	if got := cfg.GetToken("gitlab", "https://gitlab.example.com"); got != "old-token" {
+		t.Errorf("GetToken(configured instance) = %q, want old-token", got)
+	}
+
+	if _, err := keyring.Get(KeyringService, "gitlab-token"); err != keyring.ErrNotFound {
+		t.Errorf("old key still present after migration (err = %v)", err)
+	}
+	if got := GetTokenSecure("gitlab", "https://gitlab.example.com"); got != "old-token" {
+		t.Errorf("GetTokenSecure() after migration = %q, want old-token", got)
+	}
+
+	// Logging in to another instance before any lookup: the old token is moved to the
+	// previously configured instance before the base URL changes, not stranded
+	cfg = DefaultConfig()
+	cfg.GitLab.BaseURL = "https://gitlab.com"
+	if err := keyring.Set(KeyringService, "gitlab-token", "cloud-token"); err != nil {
+		t.Fatalf("keyring.Set error = %v", err)
+	}
+	cfg.MigrateLegacyToken("gitlab")
+	cfg.GitLab.BaseURL = "https://gitlab.example.com"
+	if got := cfg.GetToken("gitlab", "https://gitlab.com"); got != "cloud-token" {
+		t.Errorf("GetToken(previous instance) after login elsewhere = %q, want cloud-token", got)
+	}
+	if got := cfg.GetToken("gitlab", "https://gitlab.example.com"); got != "old-token" {
+		t.Errorf("GetToken(new instance) = %q, want its own token", got)
+	}
+
+	// A legacy token never replaces one already saved under the instance's key
+	if err := keyring.Set(KeyringService, "gitlab-token", "stale-token"); err != nil {
+		t.Fatalf("keyring.Set error = %v", err)
+	}
+	if got := cfg.MigrateLegacyToken("gitlab"); got != "" {
+		t.Errorf("MigrateLegacyToken() = %q with a token already saved, want none", got)
+	}
+	if got := GetTokenSecure("gitlab", "https://gitlab.example.com"); got != "old-token" {
+		t.Errorf("GetTokenSecure() = %q, want old-token kept", got)
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := `# tokens
+ZTIGIT_ENVFILE_TEST_TOKEN=ztigit-token
+export ENVFILE_TEST_URL="https://git.example.com"
+PATH=/tmp/evil
+GIT_SSH_COMMAND=ssh -o ProxyCommand=evil
+LD_PRELOAD=/tmp/evil.so
+GIT_TEST_URL=https://evil.example.com
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"ZTIGIT_ENVFILE_TEST_TOKEN", "ENVFILE_TEST_URL", "LD_PRELOAD", "GIT_SSH_COMMAND", "GIT_TEST_URL"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key) // Restored by t.Setenv's cleanup
+	}
+
+	ignored, err := LoadEnvFile(path)
+	if err != nil {
+		t.Fatalf("LoadEnvFile() error = %v", err)
+	}
+	if want := []string{"PATH", "GIT_SSH_COMMAND", "LD_PRELOAD", "GIT_TEST_URL"}; !slices.Equal(ignored, want) {
+		t.Errorf("LoadEnvFile() ignored %v, want %v", ignored, want)
+	}
+	if got := os.Getenv("ZTIGIT_ENVFILE_TEST_TOKEN"); got != "ztigit-token" {
+		t.Errorf("ZTIGIT_ENVFILE_TEST_TOKEN = %q, want ztigit-token", got)
+	}
+	if got := os.Getenv("ENVFILE_TEST_URL"); got != "https://git.example.com" {
+		t.Errorf("ENVFILE_TEST_URL = %q, want the unquoted URL", got)
+	}
+	for _, key := range []string{"LD_PRELOAD", "GIT_SSH_COMMAND", "GIT_TEST_URL"} {
+		if value, ok := os.LookupEnv(key); ok {
+			t.Errorf("%s = %q, want it left unset", key, value)
+		}
+	}
+}
+
+func TestIsTransientKeyringError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("User interaction is not allowed. (-25308)"), true},
+		{errors.New("OSStatus -25308"), true},
+		{errors.New("The keychain is locked"), true},
+		// A duplicate item is not contention: retrying the same Set would fail the same way
+		{errors.New("The specified item already exists in the keychain. (-25299)"), false},
+		{errors.New("The specified item could not be found in the keychain"), false},
+		{errors.New("exec: \"dbus-launch\": executable file not found in $PATH"), false},
+		{keyring.ErrNotFound, false},
+	}
+
+	for _, tt := range tests {
+		if got := isTransientKeyringError(tt.err); got != tt.want {
+			t.Errorf("isTransientKeyringError(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWithKeyringRetry(t *testing.T) {
+	delay := keyringRetryDelay
+	keyringRetryDelay = time.Millisecond
+	t.Cleanup(func() { keyringRetryDelay = delay })
+
+	tests := []struct {
+		name         string
+		err          error
+		wantAttempts int
+	}{
+		{"transient error is retried", errors.New("keychain is locked"), keyringRetries + 1},
+		{"duplicate item is not retried", errors.New("item already exists (-25299)"), 1},
+		{"missing keyring is not retried", errors.New("no such interface"), 1},
+		{"success", nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := withKeyringRetry(func() error {
+				attempts++
+				return tt.err
+			})
+			if err != tt.err {
+				t.Errorf("withKeyringRetry() error = %v, want %v", err, tt.err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestMirrorConfigValidate(t *testing.T) {
+	tests := []struct {
+		protocol string
+		want     string // Normalized value; empty with wantErr
+		wantErr  bool
+	}{
+		{"", "", false},
+		{"https", "https", false},
+		{"SSH", "ssh", false},
+		{" ssh ", "ssh", false},
+		{"git", "", true},
+		{"htps", "", true},
+	}
+
+	for _, tt := range tests {
+		m := MirrorConfig{Protocol: tt.protocol}
+		err := m.validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validate(protocol %q) error = %v, wantErr %v", tt.protocol, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && m.Protocol != tt.want {
+			t.Errorf("validate(protocol %q) left %q, want %q", tt.protocol, m.Protocol, tt.want)
+		}
+	}
+}
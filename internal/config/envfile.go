@@ -0,0 +1,92 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// LoadEnvFile reads KEY=VALUE pairs from a .env file into the process environment
+// so they are picked up by the environment bindings in Load.
+// Variables already set in the environment take precedence over the file.
+// Only ztigit's own variables are loaded (see EnvFileVar); the keys of any others
+// are returned, in file order, so the caller can warn about them.
+func LoadEnvFile(path string) (ignored []string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open env file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip blank lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Allow shell-style "export KEY=VALUE"
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return ignored, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNum)
+		}
+		if !EnvFileVar(key) {
+			ignored = append(ignored, key)
+			continue
+		}
+
+		if _, exists := os.LookupEnv(key); exists {
+			continue
+		}
+		if err := os.Setenv(key, parseEnvValue(value)); err != nil {
+			return ignored, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return ignored, fmt.Errorf("failed to read env file: %w", err)
+	}
+	return ignored, nil
+}
+
+// EnvFileVar reports whether an env file may set key: ZTIGIT_* variables, and provider
+// tokens and URLs (<PROVIDER>_TOKEN, <PROVIDER>_URL). Anything else, such as PATH,
+// LD_PRELOAD, or GIT_SSH_COMMAND, would change every git command ztigit runs.
+func EnvFileVar(key string) bool {
+	if strings.HasPrefix(key, "ZTIGIT_") {
+		return envVarName.MatchString(key)
+	}
+	if strings.HasPrefix(key, "GIT_") {
+		return false
+	}
+	return providerEnvVar.MatchString(key)
+}
+
+var (
+	envVarName     = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+	providerEnvVar = regexp.MustCompile(`^[A-Z][A-Z0-9_]*_(TOKEN|URL)$`)
+)
+
+// parseEnvValue strips surrounding quotes, or a trailing comment from unquoted values
+func parseEnvValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '"' || first == '\'') && first == last {
+			return value[1 : len(value)-1]
+		}
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
@@ -0,0 +1,57 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// applyStrictAge replaces LastUpdated with the date of the last commit on the default
+// branch, so MaxAgeMonths means the same thing on every provider. GitHub reports the
+// last push to any branch and GitLab the last activity of any kind (issues, merge
+// requests); both are at or after the default branch's last commit, so repos already
+// older than the cutoff are stale either way and are not looked up.
+// Repos whose commit date cannot be fetched keep the provider's date.
+func (m *Mirror) applyStrictAge(ctx context.Context, repos []provider.Repository) []provider.Repository {
+	if !m.options.StrictAge || m.options.MaxAgeMonths <= 0 {
+		return repos
+	}
+	cutoffDate := time.Now().AddDate(0, -m.options.MaxAgeMonths, 0)
+
+	updated := make([]provider.Repository, len(repos))
+	copy(updated, repos)
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, m.options.Parallel)
+	for i := range updated {
+		repo := &updated[i]
+		if repo.DefaultBranch == "" || repo.LastUpdated.Before(cutoffDate) {
+			continue
+		}
+		if m.options.SkipArchived && repo.Archived {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			date, err := m.provider.BranchCommitDate(ctx, repo.FullPath, repo.DefaultBranch)
+			if err != nil {
+				if m.options.Verbose {
+					fmt.Fprintf(m.progress(), "  %s %s: using provider activity date: %v\n", yellow("!"), repo.FullPath, err)
+				}
+				return
+			}
+			repo.LastUpdated = date
+		}()
+	}
+	wg.Wait()
+
+	return updated
+}
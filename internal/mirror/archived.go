@@ -0,0 +1,32 @@
+package mirror
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// archivedMarker is the file MarkArchived writes into clones of repos archived upstream
+const archivedMarker = "ARCHIVED"
+
+// markArchived writes the ARCHIVED marker into an existing clone of a repo that was
+// archived upstream. The first detection date is kept on later runs. Working tree
+// clones exclude the marker from git so it does not show up as an untracked file.
+func (m *Mirror) markArchived(repoDir string) error {
+	marker := filepath.Join(repoDir, archivedMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return nil
+	}
+
+	if !m.options.Bare {
+		if err := excludeFromGit(repoDir, "/"+archivedMarker); err != nil {
+			return err
+		}
+	}
+	content := fmt.Sprintf("Archived upstream; ztigit no longer updates this clone.\nDetected: %s\n", time.Now().Format(time.RFC3339))
+	if err := os.WriteFile(marker, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", marker, err)
+	}
+	return nil
+}
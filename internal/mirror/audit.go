@@ -0,0 +1,104 @@
+package mirror
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditRecord is one line of the audit log
+type auditRecord struct {
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	DurationMs int64     `json:"duration_ms"`
+	Repo       string    `json:"repo,omitempty"` // Directory relative to BaseDir, or the remote for ls-remote
+	Args       []string  `json:"args"`           // Command line, starting with "git", credentials redacted
+	ExitCode   int       `json:"exit_code"`      // -1 if git did not run or was killed
+	Error      string    `json:"error,omitempty"`
+}
+
+// auditLog writes one JSON record per git command. Unlike gitLog it holds no command
+// output, only what ran, where, when, and how it ended.
+type auditLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// record appends a finished command to the audit log; a nil log records nothing
+func (l *auditLog) record(baseDir string, cmd *exec.Cmd, start time.Time, err error) {
+	if l == nil {
+		return
+	}
+
+	end := time.Now()
+	rec := auditRecord{
+		Start:      start,
+		End:        end,
+		DurationMs: end.Sub(start).Milliseconds(),
+		Repo:       auditRepo(baseDir, cmd.Args),
+		Args:       make([]string, len(cmd.Args)),
+	}
+	for i, arg := range cmd.Args {
+		rec.Args[i] = redactCredentials(arg)
+	}
+	if err != nil {
+		rec.ExitCode = -1
+		rec.Error = redactCredentials(err.Error())
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			rec.ExitCode = exitErr.ExitCode()
+		}
+	}
+
+	line, jsonErr := json.Marshal(rec)
+	if jsonErr != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(append(line, '\n'))
+}
+
+// auditRepo returns the repo a git command acts on: the -C directory, the clone
+// destination, or the remote of ls-remote. Directories under baseDir are made relative.
+func auditRepo(baseDir string, args []string) string {
+	var target string
+	for i := 1; i < len(args); i++ {
+		switch {
+		case args[i] == "-C" && i+1 < len(args):
+			target = args[i+1]
+		case args[i] == "clone" || args[i] == "ls-remote":
+			if last := args[len(args)-1]; last != args[i] {
+				target = last
+			}
+		default:
+			continue
+		}
+		break
+	}
+	if target == "" {
+		return ""
+	}
+
+	if filepath.IsAbs(target) && baseDir != "" {
+		if rel, err := filepath.Rel(baseDir, target); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return redactCredentials(target)
+}
+
+// credentialPattern matches the user info of URLs such as https://oauth2:<token>@host/
+var credentialPattern = regexp.MustCompile(`://[^/@\s]+@`)
+
+// redactCredentials masks the user info of any URL in s
+func redactCredentials(s string) string {
+	return credentialPattern.ReplaceAllString(s, "://REDACTED@")
+}
@@ -0,0 +1,101 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// bareSuffix is appended to the local path of bare mirrors, following the git convention
+const bareSuffix = ".git"
+
+// isBareRepo checks if a directory is a bare repository
+func isBareRepo(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, "objects"))
+	if err != nil {
+		return false
+	}
+	return info.IsDir()
+}
+
+// remoteUpdateArgs returns the git arguments that update the bare mirror at dir
+func (m *Mirror) remoteUpdateArgs(dir string) []string {
+	args := []string{"-C", dir}
+	if m.options.FetchJobs > 0 {
+		args = append(args, "-c", "fetch.parallel="+strconv.Itoa(m.options.FetchJobs))
+	}
+	return append(args, "remote", "update", "--prune")
+}
+
+// updateBareRepo fetches all refs into a bare mirror, pruning refs deleted on the server.
+// The mirror refspec covers refs/tags too, so deleted tags are always pruned (PruneTags is implied).
+// `git remote update` has no --jobs option, so FetchJobs is passed as fetch.parallel.
+func (m *Mirror) updateBareRepo(ctx context.Context, dir string) error {
+	cmd := m.gitCommand(ctx, m.remoteUpdateArgs(dir)...)
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+
+	if m.options.Verbose {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+
+	if err := m.run(cmd); err != nil {
+		return fmt.Errorf("git remote update failed: %w", err)
+	}
+	return nil
+}
+
+// setBareHead points HEAD of a bare mirror at the default branch.
+// A mirror has no refs/remotes/origin, so `git remote set-head` does not apply;
+// HEAD itself is what clients and git-daemon report as the default branch.
+// defaultBranch is the provider-reported branch; if empty, the server's HEAD is used.
+func (m *Mirror) setBareHead(ctx context.Context, dir, defaultBranch string) error {
+	branch := defaultBranch
+	if branch == "" {
+		var err error
+		branch, err = m.remoteHeadBranch(ctx, dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	ref := "refs/heads/" + branch
+	verifyCmd := m.gitCommand(ctx, "-C", dir, "rev-parse", "--verify", "--quiet", ref)
+	if err := m.run(verifyCmd); err != nil {
+		return fmt.Errorf("default branch %s not found in mirror", branch)
+	}
+
+	cmd := m.gitCommand(ctx, "-C", dir, "symbolic-ref", "HEAD", ref)
+	if err := m.run(cmd); err != nil {
+		return fmt.Errorf("failed to set HEAD to %s: %w", branch, err)
+	}
+	return nil
+}
+
+// remoteHeadBranch asks origin which branch its HEAD points at
+func (m *Mirror) remoteHeadBranch(ctx context.Context, dir string) (string, error) {
+	cmd := m.gitCommand(ctx, "-C", dir, "ls-remote", "--symref", "origin", "HEAD")
+	output, err := m.output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to get default branch: %w", err)
+	}
+
+	// Output starts with "ref: refs/heads/main\tHEAD"
+	for _, line := range strings.Split(string(output), "\n") {
+		target, ok := strings.CutPrefix(line, "ref: refs/heads/")
+		if !ok {
+			continue
+		}
+		if branch, _, ok := strings.Cut(target, "\t"); ok && branch != "" {
+			return branch, nil
+		}
+	}
+	return "", fmt.Errorf("failed to get default branch: origin HEAD is not a branch")
+}
@@ -0,0 +1,60 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// trackBranches keeps a local branch for every origin branch matching TrackBranches.
+// Missing branches are created with upstream tracking; existing ones are fast-forwarded
+// to origin. The checked-out branch is left to the normal pull, and branches with local
+// commits that diverge from origin are left alone. Returns the number of branches tracked.
+func (m *Mirror) trackBranches(ctx context.Context, dir string) (int, error) {
+	refsCmd := m.gitCommand(ctx, "-C", dir, "for-each-ref", "--format=%(refname:lstrip=3)", "refs/remotes/origin/")
+	refsOutput, err := m.output(refsCmd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list remote branches: %w", err)
+	}
+
+	headCmd := m.gitCommand(ctx, "-C", dir, "symbolic-ref", "--quiet", "--short", "HEAD")
+	headOutput, _ := m.output(headCmd) // Detached HEAD: no current branch
+	current := strings.TrimSpace(string(headOutput))
+
+	tracked := 0
+	for _, branch := range strings.Fields(string(refsOutput)) {
+		if branch == "HEAD" {
+			continue
+		}
+		if matched, _ := path.Match(m.options.TrackBranches, branch); !matched {
+			continue
+		}
+		tracked++
+		if branch == current {
+			continue
+		}
+
+		remoteRef := "refs/remotes/origin/" + branch
+		localRef := "refs/heads/" + branch
+		if err := m.run(m.gitCommand(ctx, "-C", dir, "show-ref", "--verify", "--quiet", localRef)); err != nil {
+			if err := m.run(m.gitCommand(ctx, "-C", dir, "branch", "--track", branch, "origin/"+branch)); err != nil {
+				return tracked, fmt.Errorf("failed to create branch %s: %w", branch, err)
+			}
+			continue
+		}
+
+		// Only fast-forward; a branch with local commits is someone's work
+		if err := m.run(m.gitCommand(ctx, "-C", dir, "merge-base", "--is-ancestor", localRef, remoteRef)); err != nil {
+			if m.options.Verbose {
+				fmt.Printf("    %s branch %s has diverged from origin, not updated\n", yellow("!"), branch)
+			}
+			continue
+		}
+		if err := m.run(m.gitCommand(ctx, "-C", dir, "update-ref", localRef, remoteRef)); err != nil {
+			return tracked, fmt.Errorf("failed to update branch %s: %w", branch, err)
+		}
+	}
+
+	return tracked, nil
+}
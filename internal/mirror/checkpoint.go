@@ -0,0 +1,41 @@
+package mirror
+
+import (
+	"sync"
+	"time"
+)
+
+// startCheckpoints calls Options.Checkpoint every CheckpointInterval with a copy of
+// the results collected so far, read under mu. The returned stop function waits for
+// a checkpoint in progress, so none is written after it returns.
+func (m *Mirror) startCheckpoints(mu *sync.Mutex, results *[]Result) (stop func()) {
+	if m.options.CheckpointInterval <= 0 || m.options.Checkpoint == nil {
+		return func() {}
+	}
+
+	ticker := time.NewTicker(m.options.CheckpointInterval)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				mu.Lock()
+				snapshot := make([]Result, len(*results))
+				copy(snapshot, *results)
+				mu.Unlock()
+				m.options.Checkpoint(snapshot)
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+		wg.Wait()
+	}
+}
@@ -0,0 +1,141 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// atomicTempFile matches the temporary files of writeFileAtomic (atomicTempSuffix),
+// capturing the name of the file being written
+var atomicTempFile = regexp.MustCompile(`^(.+)\.[0-9]+\.tmp$`)
+
+// Leftover is a broken clone or a stray temporary file found by FindLeftovers
+type Leftover struct {
+	Path   string
+	Reason string
+	IsDir  bool
+}
+
+// FindLeftovers walks baseDir for what interrupted runs leave behind: directories that
+// look like clones but that git does not accept as a repository, and the temporary
+// files of unfinished state writes and release downloads. Clones are not descended
+// into, except for their release assets.
+func FindLeftovers(ctx context.Context, baseDir string) ([]Leftover, error) {
+	if _, err := os.Stat(baseDir); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", baseDir, err)
+	}
+
+	var leftovers []Leftover
+	err := filepath.WalkDir(baseDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !d.IsDir() {
+			if reason := strayTempFile(path); reason != "" {
+				leftovers = append(leftovers, Leftover{Path: path, Reason: reason})
+			}
+			return nil
+		}
+
+		gitDir := cloneGitDir(path)
+		if gitDir == "" {
+			return nil
+		}
+		if err := exec.CommandContext(ctx, "git", "--git-dir="+gitDir, "rev-parse", "--git-dir").Run(); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			leftovers = append(leftovers, Leftover{Path: path, Reason: "broken clone: not a valid git repository", IsDir: true})
+			return filepath.SkipDir
+		}
+
+		// Release downloads killed mid-transfer leave .part files inside the clone
+		parts, _ := filepath.Glob(filepath.Join(path, releasesDirName, "*", ".*.part"))
+		for _, part := range parts {
+			leftovers = append(leftovers, Leftover{Path: part, Reason: "unfinished release download"})
+		}
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return leftovers, fmt.Errorf("failed to scan %s: %w", baseDir, err)
+	}
+	return leftovers, nil
+}
+
+// cloneGitDir returns the git directory of a directory that looks like a clone:
+// <dir>/.git for a working tree, or dir itself for a bare mirror (<name>.git with a
+// HEAD file or objects directory). Returns "" for other directories.
+func cloneGitDir(dir string) string {
+	if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+		return filepath.Join(dir, ".git")
+	}
+	if !strings.HasSuffix(dir, bareSuffix) {
+		return ""
+	}
+	for _, name := range []string{"HEAD", "objects"} {
+		if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+// strayTempFile describes a temporary file left by an unfinished atomic write (the
+// state file, a --marker-file, --report-failures-file, or --summary-file), or returns
+// "" for any other file
+func strayTempFile(path string) string {
+	match := atomicTempFile.FindStringSubmatch(filepath.Base(path))
+	if match == nil {
+		return ""
+	}
+	if match[1] == stateFile {
+		return "unfinished state file write"
+	}
+	return "unfinished write of " + match[1]
+}
+
+// RemoveLeftovers deletes the leftovers, continuing past failures.
+// Returns the number removed and the first error.
+func RemoveLeftovers(leftovers []Leftover) (int, error) {
+	removed := 0
+	var firstErr error
+	for _, l := range leftovers {
+		if err := os.RemoveAll(l.Path); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to remove %s: %w", l.Path, err)
+			}
+			continue
+		}
+		removed++
+	}
+	return removed, firstErr
+}
+
+// PrintLeftovers prints one line per leftover and a total
+func PrintLeftovers(leftovers []Leftover, baseDir string) {
+	if len(leftovers) == 0 {
+		fmt.Printf("%s No broken clones or stray temporary files in %s\n", green("✓"), baseDir)
+		return
+	}
+	for _, l := range leftovers {
+		path := l.Path
+		if rel, err := filepath.Rel(baseDir, l.Path); err == nil {
+			path = rel
+		}
+		if l.IsDir {
+			path += string(filepath.Separator)
+		}
+		fmt.Printf("  %s %s %s\n", yellow("!"), path, faint("("+l.Reason+")"))
+	}
+	fmt.Println()
+	fmt.Printf("Found: %d\n", len(leftovers))
+}
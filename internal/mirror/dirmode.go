@@ -0,0 +1,71 @@
+package mirror
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// defaultDirMode is used for created directories when Options.DirMode is not set
+const defaultDirMode os.FileMode = 0755
+
+// ParseDirMode parses an octal directory mode such as "0750". The owner must keep
+// full access, since ztigit writes into the directories it creates.
+func ParseDirMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid directory mode %q (use octal permissions, e.g. 0750)", s)
+	}
+	if mode&0700 != 0700 {
+		return 0, fmt.Errorf("invalid directory mode %q: the owner needs read, write, and execute (7xx)", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// mkdirAll creates dir and any missing parents with DirMode (see MkdirAll)
+func (m *Mirror) mkdirAll(dir string) error {
+	return MkdirAll(dir, m.options.DirMode)
+}
+
+// MkdirAll creates dir and any missing parents. With a mode set, every directory it
+// creates is chmod'ed afterwards so the mode is exact regardless of the umask; 0 uses
+// the default 0755 minus the umask.
+func MkdirAll(dir string, mode os.FileMode) error {
+	if mode == 0 {
+		return os.MkdirAll(dir, defaultDirMode)
+	}
+
+	// Remember which directories are missing, deepest first
+	var missing []string
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append(missing, d)
+		if parent := filepath.Dir(d); parent == d {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(dir, mode); err != nil {
+		return err
+	}
+	for _, d := range missing {
+		if err := os.Chmod(d, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyDirMode sets DirMode on a directory created by git (e.g. a new clone)
+func (m *Mirror) applyDirMode(dir string) error {
+	if m.options.DirMode == 0 {
+		return nil
+	}
+	if err := os.Chmod(dir, m.options.DirMode); err != nil {
+		return fmt.Errorf("failed to set directory mode: %w", err)
+	}
+	return nil
+}
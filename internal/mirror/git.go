@@ -0,0 +1,210 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitConfig is a git config entry applied to every git command for this run only
+type GitConfig struct {
+	Key   string
+	Value string
+}
+
+// ParseGitConfig parses a "key=value" git config entry (e.g., "credential.helper=store")
+func ParseGitConfig(s string) (GitConfig, error) {
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || !strings.Contains(key, ".") {
+		return GitConfig{}, fmt.Errorf("invalid git config %q (expected section.key=value)", s)
+	}
+	return GitConfig{Key: key, Value: value}, nil
+}
+
+// gitCommand builds a git command with the run's ephemeral config applied
+func (m *Mirror) gitCommand(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	if len(m.options.GitConfig) > 0 {
+		cmd.Env = withGitConfig(cmd.Environ(), m.options.GitConfig)
+	}
+	if m.options.SSHConfig != "" {
+		cmd.Env = withSSHConfig(cmd.Environ(), m.options.SSHConfig)
+	}
+	return cmd
+}
+
+// withSSHConfig points GIT_SSH_COMMAND at an SSH config file. An SSH command already
+// set in the environment (e.g. ssh -i <key>) is kept and gets -F appended.
+func withSSHConfig(env []string, file string) []string {
+	sshCommand := "ssh"
+	result := make([]string, 0, len(env)+1)
+	for _, kv := range env {
+		if value, ok := strings.CutPrefix(kv, "GIT_SSH_COMMAND="); ok {
+			if value != "" {
+				sshCommand = value
+			}
+			continue
+		}
+		result = append(result, kv)
+	}
+	// GIT_SSH_COMMAND is run by a shell, so quote the path
+	quoted := "'" + strings.ReplaceAll(file, "'", `'\''`) + "'"
+	return append(result, "GIT_SSH_COMMAND="+sshCommand+" -F "+quoted)
+}
+
+// run runs a git command, recording its output in the log and the command in the
+// audit log if either is set. Output also goes wherever the caller pointed cmd.Stdout/cmd.Stderr.
+func (m *Mirror) run(cmd *exec.Cmd) error {
+	start := time.Now()
+	if m.log == nil {
+		err := cmd.Run()
+		m.audit.record(m.options.BaseDir, cmd, start, err)
+		return err
+	}
+
+	buf := &syncBuffer{}
+	cmd.Stdout = teeWriter(cmd.Stdout, buf)
+	cmd.Stderr = teeWriter(cmd.Stderr, buf)
+
+	err := cmd.Run()
+	m.log.record(cmd, start, buf.Bytes(), err)
+	m.audit.record(m.options.BaseDir, cmd, start, err)
+	return err
+}
+
+// output runs a git command and returns its stdout, recording stdout and stderr in the log
+// and the command in the audit log if either is set
+func (m *Mirror) output(cmd *exec.Cmd) ([]byte, error) {
+	start := time.Now()
+	if m.log == nil {
+		out, err := cmd.Output()
+		m.audit.record(m.options.BaseDir, cmd, start, err)
+		return out, err
+	}
+
+	stderr := &syncBuffer{}
+	cmd.Stderr = stderr
+
+	out, err := cmd.Output()
+	m.log.record(cmd, start, append(out, stderr.Bytes()...), err)
+	m.audit.record(m.options.BaseDir, cmd, start, err)
+	return out, err
+}
+
+// gitLog writes git command output from parallel repos to a single log,
+// one complete entry per command so output is never interleaved
+type gitLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// record appends a command, its result, and its output to the log. Credentials in
+// URLs are masked, as in the audit log, since clone URLs and git's messages may carry them.
+func (l *gitLog) record(cmd *exec.Cmd, start time.Time, output []byte, err error) {
+	status := "ok"
+	if err != nil {
+		status = redactCredentials(err.Error())
+	}
+	args := make([]string, len(cmd.Args))
+	for i, arg := range cmd.Args {
+		args[i] = redactCredentials(arg)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fmt.Fprintf(l.w, "[%s] %s (%s, %s)\n", start.Format(time.RFC3339), strings.Join(args, " "), status,
+		time.Since(start).Round(time.Millisecond))
+	if len(output) > 0 {
+		text := redactCredentials(string(output))
+		io.WriteString(l.w, text)
+		if !strings.HasSuffix(text, "\n") {
+			fmt.Fprintln(l.w)
+		}
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes from a command's stdout and stderr
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
+
+// teeWriter adds w2 to an optional writer
+func teeWriter(w io.Writer, w2 io.Writer) io.Writer {
+	if w == nil {
+		return w2
+	}
+	return io.MultiWriter(w, w2)
+}
+
+// headCommit returns the commit SHA that HEAD resolves to
+func (m *Mirror) headCommit(ctx context.Context, dir string) (string, error) {
+	output, err := m.output(m.gitCommand(ctx, "-C", dir, "rev-parse", "--verify", "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// isEmptyClone reports whether a clone has no refs at all, as after cloning a repo
+// nothing has been pushed to yet
+func (m *Mirror) isEmptyClone(ctx context.Context, dir string) (bool, error) {
+	output, err := m.output(m.gitCommand(ctx, "-C", dir, "for-each-ref", "--count=1"))
+	if err != nil {
+		return false, fmt.Errorf("failed to list refs: %w", err)
+	}
+	return len(output) == 0, nil
+}
+
+// isShallow reports whether a clone has truncated history (git clone --depth)
+func (m *Mirror) isShallow(ctx context.Context, dir string) (bool, error) {
+	output, err := m.output(m.gitCommand(ctx, "-C", dir, "rev-parse", "--is-shallow-repository"))
+	if err != nil {
+		return false, fmt.Errorf("failed to check for a shallow clone: %w", err)
+	}
+	return strings.TrimSpace(string(output)) == "true", nil
+}
+
+// withGitConfig adds config entries to an environment using GIT_CONFIG_COUNT,
+// GIT_CONFIG_KEY_<n> and GIT_CONFIG_VALUE_<n> (git 2.31+), so nothing is written
+// to the user's git config. Entries already in the environment are preserved.
+func withGitConfig(env []string, entries []GitConfig) []string {
+	offset := 0
+	result := make([]string, 0, len(env)+2*len(entries)+1)
+	for _, kv := range env {
+		if value, ok := strings.CutPrefix(kv, "GIT_CONFIG_COUNT="); ok {
+			offset, _ = strconv.Atoi(value)
+			continue
+		}
+		result = append(result, kv)
+	}
+
+	for i, entry := range entries {
+		n := offset + i
+		result = append(result,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", n, entry.Key),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", n, entry.Value),
+		)
+	}
+	return append(result, fmt.Sprintf("GIT_CONFIG_COUNT=%d", offset+len(entries)))
+}
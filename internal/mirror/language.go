@@ -0,0 +1,90 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// ParseLanguages splits a comma-separated language list such as "Go,Python"
+func ParseLanguages(s string) []string {
+	var languages []string
+	for _, lang := range strings.Split(s, ",") {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			languages = append(languages, lang)
+		}
+	}
+	return languages
+}
+
+// FilterLanguages returns the repos whose primary language is one of Options.Languages,
+// looking up languages the listing did not include
+func (m *Mirror) FilterLanguages(ctx context.Context, repos []provider.Repository) []provider.Repository {
+	if len(m.options.Languages) == 0 {
+		return repos
+	}
+	var matched []provider.Repository
+	for _, repo := range m.applyLanguages(ctx, repos) {
+		if matchesLanguage(repo, m.options.Languages) {
+			matched = append(matched, repo)
+		}
+	}
+	return matched
+}
+
+// applyLanguages fills in Language for repos listed without one (GitLab), but only
+// when a language filter is set and only for repos the other filters would keep.
+// Repos whose language cannot be fetched stay unknown and are filtered out.
+func (m *Mirror) applyLanguages(ctx context.Context, repos []provider.Repository) []provider.Repository {
+	if len(m.options.Languages) == 0 {
+		return repos
+	}
+
+	updated := make([]provider.Repository, len(repos))
+	copy(updated, repos)
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, m.options.Parallel)
+	for i := range updated {
+		repo := &updated[i]
+		if repo.Language != "" || !m.matchesNameFilters(*repo) {
+			continue
+		}
+		if m.options.SkipArchived && repo.Archived {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			lang, err := m.provider.PrimaryLanguage(ctx, repo.FullPath)
+			if err != nil {
+				if m.options.Verbose {
+					fmt.Fprintf(m.progress(), "  %s %s: language unknown: %v\n", yellow("!"), repo.FullPath, err)
+				}
+				return
+			}
+			repo.Language = lang
+		}()
+	}
+	wg.Wait()
+
+	return updated
+}
+
+// matchesLanguage reports whether a repo's primary language is one of languages,
+// ignoring case. Repos with no known language never match.
+func matchesLanguage(repo provider.Repository, languages []string) bool {
+	for _, lang := range languages {
+		if repo.Language != "" && strings.EqualFold(repo.Language, lang) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,323 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// Collision policies for repos that resolve to the same local path
+const (
+	CollisionSuffix = "suffix" // Append -2, -3, ... to later repos
+	CollisionSkip   = "skip"   // Skip every repo involved
+	CollisionFail   = "fail"   // Mark every repo involved as failed
+)
+
+// plannedRepo is a repo with its resolved local path, or a result decided before cloning
+type plannedRepo struct {
+	repo      provider.Repository
+	relPath   string
+	collision bool
+	movedFrom string  // Previous local path, if relocated (FollowRedirects)
+	result    *Result // Non-nil if the repo must not be cloned
+}
+
+// localPath validates a repo path and returns its location relative to BaseDir
+func (m *Mirror) localPath(repo provider.Repository) (string, error) {
+	// Validate the path before using it
+	if err := validatePath(repo.FullPath); err != nil {
+		return "", fmt.Errorf("invalid path %q: %w", repo.FullPath, err)
+	}
+
+	var relPath string
+	if m.options.Flatten {
+		// Flat layout: BaseDir/<repo-name>
+		relPath = path.Base(repo.FullPath)
+	} else if m.members[repo.FullPath] != "" {
+		// Member-owned repos: BaseDir/member/<user>/<repo>
+		relPath = memberDir + "/" + repo.FullPath
+	} else {
+		// Clone into BaseDir/<full-path> to preserve hierarchy (minus any stripped prefix)
+		var err error
+		relPath, err = stripPathPrefix(repo.FullPath, m.options.StripPrefix)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Bare mirrors: BaseDir/<path>.git
+	if m.options.Bare {
+		relPath += bareSuffix
+	}
+	return relPath, nil
+}
+
+// stripPathPrefix removes leading path segments from a repository path.
+// The prefix must match whole segments, and at least one segment must remain.
+func stripPathPrefix(fullPath, prefix string) (string, error) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return fullPath, nil
+	}
+
+	rest, ok := strings.CutPrefix(fullPath, prefix+"/")
+	if !ok || rest == "" {
+		return "", fmt.Errorf("path %q does not start with prefix %q", fullPath, prefix)
+	}
+	return rest, nil
+}
+
+// planLayout resolves the local path of every repo and applies the collision policy.
+// Repos whose paths collide are ordered by FullPath, and keep the suffixed clone they
+// already have (see assignSuffixes).
+func (m *Mirror) planLayout(repos []provider.Repository) []plannedRepo {
+	plan := make([]plannedRepo, len(repos))
+	groups := make(map[string][]int) // collision key -> indexes into plan
+
+	for i, repo := range repos {
+		plan[i].repo = repo
+		relPath, err := m.localPath(repo)
+		if err != nil {
+			plan[i].result = &Result{
+				Repository: repo,
+				Action:     "failed",
+				Error:      err,
+			}
+			continue
+		}
+		plan[i].relPath = relPath
+		key := collisionKey(relPath)
+		groups[key] = append(groups[key], i)
+	}
+
+	taken := make(map[string]bool, len(groups))
+	for key := range groups {
+		taken[key] = true
+	}
+
+	// Iterate keys in order so suffix assignment is deterministic
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	dirs := make(map[string][]os.DirEntry) // Directory listings for assignSuffixes
+	for _, key := range keys {
+		indexes := groups[key]
+		if len(indexes) < 2 {
+			// A repo that no longer collides keeps the suffixed clone it has
+			if m.options.OnCollision != CollisionSkip && m.options.OnCollision != CollisionFail {
+				m.assignSuffixes(plan, indexes, taken, dirs)
+			}
+			continue
+		}
+		sort.Slice(indexes, func(a, b int) bool {
+			return plan[indexes[a]].repo.FullPath < plan[indexes[b]].repo.FullPath
+		})
+
+		var names []string
+		for _, i := range indexes {
+			names = append(names, plan[i].repo.FullPath)
+		}
+
+		for _, i := range indexes {
+			plan[i].collision = true
+			switch m.options.OnCollision {
+			case CollisionSkip:
+				plan[i].result = &Result{
+					Repository: plan[i].repo,
+					Action:     "collision",
+					Error:      fmt.Errorf("skipped: local path %q shared by %s", plan[i].relPath, strings.Join(names, ", ")),
+					Collision:  true,
+				}
+			case CollisionFail:
+				plan[i].result = &Result{
+					Repository: plan[i].repo,
+					Action:     "failed",
+					Error:      fmt.Errorf("local path %q shared by %s", plan[i].relPath, strings.Join(names, ", ")),
+					Collision:  true,
+				}
+			}
+		}
+		if m.options.OnCollision != CollisionSkip && m.options.OnCollision != CollisionFail {
+			m.assignSuffixes(plan, indexes, taken, dirs)
+		}
+	}
+
+	return plan
+}
+
+// collisionKey normalizes a local path for collision detection.
+// Windows and macOS filesystems are case-insensitive by default.
+func collisionKey(relPath string) string {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return strings.ToLower(relPath)
+	}
+	return relPath
+}
+
+// assignSuffixes gives every repo of a group sharing one local path its own path. A repo
+// whose existing clone (at the shared path or a suffixed one) has it as origin keeps
+// that clone, so suffixes never pass to another repo when repos are added or removed
+// upstream. The other repos, in FullPath order, get the shared path if nothing is
+// there yet, else the next free suffix; a path holding another repo's clone is never
+// handed out. taken holds the collision keys of every planned path; dirs caches
+// directory listings across calls.
+//
+// A single repo is only looked at if suffixed paths exist, i.e. it collided before.
+func (m *Mirror) assignSuffixes(plan []plannedRepo, indexes []int, taken map[string]bool, dirs map[string][]os.DirEntry) {
+	shared := plan[indexes[0]].relPath
+	existing := m.suffixedClones(shared, len(indexes) == 1, dirs)
+	if existing == nil {
+		return
+	}
+
+	// Sorted, so a repo with several clones keeps the same one every run
+	paths := make([]string, 0, len(existing))
+	for relPath := range existing {
+		paths = append(paths, relPath)
+	}
+	sort.Strings(paths)
+
+	used := make(map[string]bool)
+	assigned := make(map[int]bool)
+	for _, i := range indexes {
+		for _, relPath := range paths {
+			if !used[collisionKey(relPath)] && sameRemote(existing[relPath], plan[i].repo) {
+				used[collisionKey(relPath)] = true
+				assigned[i] = true
+				plan[i].relPath = relPath
+				break
+			}
+		}
+	}
+
+	var holder string // Repo at the shared path, for messages
+	for _, i := range indexes {
+		if plan[i].relPath == shared && assigned[i] {
+			holder = plan[i].repo.FullPath
+		}
+	}
+
+	for _, i := range indexes {
+		if !assigned[i] {
+			candidate := shared
+			for suffix := 2; ; suffix++ {
+				key := collisionKey(candidate)
+				_, onDisk := existing[candidate]
+				if !used[key] && !onDisk && (candidate == shared || !taken[key]) {
+					break
+				}
+				candidate = withSuffix(shared, suffix, m.options.Bare)
+			}
+			used[collisionKey(candidate)] = true
+			taken[collisionKey(candidate)] = true
+			plan[i].relPath = candidate
+			if candidate == shared {
+				holder = plan[i].repo.FullPath
+			}
+		}
+	}
+
+	for _, i := range indexes {
+		if plan[i].relPath != shared {
+			other := holder
+			if other == "" {
+				other = "a local clone"
+			}
+			fmt.Printf("  %s %s collides with %s, using %s\n", yellow("!"), plan[i].repo.FullPath, other, plan[i].relPath)
+		}
+	}
+}
+
+// withSuffix appends -<n> to a local path, keeping the .git suffix of bare mirrors
+// last (repo-2.git)
+func withSuffix(relPath string, n int, bare bool) string {
+	if bare {
+		return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(relPath, bareSuffix), n, bareSuffix)
+	}
+	return fmt.Sprintf("%s-%d", relPath, n)
+}
+
+// suffixedClones returns what already exists at a shared local path and its suffixed
+// variants (relPath-2, ...), mapped to each clone's origin URL ("" if it is not a
+// clone or has no origin). With onlySuffixed, nil is returned if there is no suffixed
+// variant, without looking at any clone.
+func (m *Mirror) suffixedClones(shared string, onlySuffixed bool, dirs map[string][]os.DirEntry) map[string]string {
+	dir, name := path.Split(shared)
+	stem := name
+	if m.options.Bare {
+		stem = strings.TrimSuffix(name, bareSuffix)
+	}
+	suffixed := regexp.MustCompile("^" + regexp.QuoteMeta(collisionKey(stem)) + `-[0-9]+` + regexp.QuoteMeta(collisionKey(strings.TrimPrefix(name, stem))) + "$")
+
+	entries, ok := dirs[dir]
+	if !ok {
+		entries, _ = os.ReadDir(filepath.Join(m.options.BaseDir, filepath.FromSlash(dir)))
+		dirs[dir] = entries
+	}
+
+	var matches []string
+	hasSuffixed := false
+	for _, entry := range entries {
+		key := collisionKey(entry.Name())
+		if suffixed.MatchString(key) {
+			hasSuffixed = true
+		} else if key != collisionKey(name) {
+			continue
+		}
+		matches = append(matches, dir+entry.Name())
+	}
+	if onlySuffixed && !hasSuffixed {
+		return nil
+	}
+
+	existing := make(map[string]string, len(matches))
+	for _, relPath := range matches {
+		existing[relPath] = ""
+
+		absPath := filepath.Join(m.options.BaseDir, filepath.FromSlash(relPath))
+		isRepo := isGitRepo(absPath)
+		if m.options.Bare {
+			isRepo = isBareRepo(absPath)
+		}
+		if !isRepo {
+			continue
+		}
+		output, err := m.output(m.gitCommand(context.Background(), "-C", absPath, "remote", "get-url", "origin"))
+		if err == nil {
+			existing[relPath] = strings.TrimSpace(string(output))
+		}
+	}
+	return existing
+}
+
+// sameRemote reports whether origin is one of repo's clone URLs, ignoring the protocol
+// (a clone may have used the fallback method) and case
+func sameRemote(origin string, repo provider.Repository) bool {
+	if origin == "" {
+		return false
+	}
+	if origin == repo.CloneURL || origin == repo.SSHUrl {
+		return true
+	}
+	host, originPath, ok := parseRemote(origin)
+	if !ok {
+		return false
+	}
+	for _, u := range []string{repo.CloneURL, repo.SSHUrl} {
+		if h, p, ok := parseRemote(u); ok && h == host && strings.EqualFold(p, originPath) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// FilterGrep returns the repos whose name, full path, or description contains term (case-insensitive)
+func FilterGrep(repos []provider.Repository, term string) []provider.Repository {
+	if term == "" {
+		return repos
+	}
+	var matched []provider.Repository
+	for _, repo := range repos {
+		if matchesGrep(repo, term) {
+			matched = append(matched, repo)
+		}
+	}
+	return matched
+}
+
+// CountRepos returns how many repos would be mirrored after the archived, age, name, and language filters
+func (m *Mirror) CountRepos(ctx context.Context, repos []provider.Repository) int {
+	active, _ := m.filterRepos(m.applyStrictAge(ctx, m.applySizes(ctx, m.applyLanguages(ctx, repos))))
+	return len(active)
+}
+
+// PrintRepoList prints one line per repo with its size and last activity. With envCounts
+// (see EnvironmentCounts), each line also shows the repo's environment count: "-" if the
+// provider has no environments and "?" if the lookup failed.
+func PrintRepoList(repos []provider.Repository, envCounts map[string]int) {
+	envTotal := 0
+	for _, repo := range repos {
+		updated := "-"
+		if !repo.LastUpdated.IsZero() {
+			updated = repo.LastUpdated.Format("2006-01-02")
+		}
+		envs := ""
+		if envCounts != nil {
+			count, ok := envCounts[repo.FullPath]
+			switch {
+			case !ok:
+				envs = fmt.Sprintf("  %3s envs", "-")
+			case count < 0:
+				envs = fmt.Sprintf("  %3s envs", "?")
+			default:
+				envs = fmt.Sprintf("  %3d envs", count)
+				envTotal += count
+			}
+		}
+		note := ""
+		if repo.Archived {
+			note = " " + faint("(archived)")
+		}
+		fmt.Printf("  %-50s %10s  %s%s%s\n", repo.FullPath, formatSize(repo.Size), updated, envs, note)
+	}
+
+	fmt.Println()
+	if len(envCounts) > 0 {
+		fmt.Printf("Total: %d repos, %d environments\n", len(repos), envTotal)
+		return
+	}
+	fmt.Printf("Total: %d repos\n", len(repos))
+}
+
+// WriteRepoURLs writes the clone URL of each repo, one per line, for other tools or
+// mirror --targets-file. Uses SSH URLs if ssh is set.
+func WriteRepoURLs(w io.Writer, repos []provider.Repository, ssh bool) {
+	for _, repo := range repos {
+		fmt.Fprintln(w, repoURL(repo, ssh))
+	}
+}
+
+// WriteRepoEnv writes each repo's clone URL as a NAME=url assignment, named after its
+// full path (company/infra-tools -> COMPANY_INFRA_TOOLS), for sourcing into a shell or
+// loading as an env file. Names that would repeat get a _2, _3, ... suffix.
+func WriteRepoEnv(w io.Writer, repos []provider.Repository, ssh bool) {
+	seen := make(map[string]int)
+	for _, repo := range repos {
+		name := envVarName(repo.FullPath)
+		seen[name]++
+		if n := seen[name]; n > 1 {
+			name = fmt.Sprintf("%s_%d", name, n)
+		}
+		fmt.Fprintf(w, "%s=%s\n", name, repoURL(repo, ssh))
+	}
+}
+
+// repoURL returns the SSH or HTTPS clone URL of a repo
+func repoURL(repo provider.Repository, ssh bool) string {
+	if ssh {
+		return repo.SSHUrl
+	}
+	return repo.CloneURL
+}
+
+// envVarName turns a full path into an environment variable name: upper case, with
+// every character other than a letter or digit replaced by _
+func envVarName(fullPath string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, fullPath)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// EnvironmentCounts looks up the number of deployment environments of each repo, at most
+// Parallel at a time. Repos whose environments cannot be listed map to -1. The map is
+// empty if the provider does not support environments.
+func (m *Mirror) EnvironmentCounts(ctx context.Context, repos []provider.Repository) map[string]int {
+	counts := make(map[string]int, len(repos))
+	if !m.provider.Capabilities().Environments {
+		return counts
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, max(m.options.Parallel, 1))
+	for _, repo := range repos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			count := -1
+			if envs, err := m.provider.ListEnvironments(ctx, repo.FullPath); err == nil {
+				count = len(envs)
+			} else if m.options.Verbose {
+				fmt.Printf("  %s %s: environments unknown: %v\n", yellow("!"), repo.FullPath, err)
+			}
+			mu.Lock()
+			counts[repo.FullPath] = count
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return counts
+}
+
+// SizeBucket is a range of repository sizes in a SizeBreakdown
+type SizeBucket struct {
+	Label string
+	Max   int64 // Exclusive upper bound in bytes (0 = no limit)
+	Count int
+	Bytes int64
+}
+
+// SizeBreakdown summarizes where the bytes are in a set of repos
+type SizeBreakdown struct {
+	Largest []provider.Repository // Largest repos first
+	Buckets []SizeBucket
+	Total   int64
+	Unknown int // Repos with no reported size, left out of Largest, Buckets, and Total
+}
+
+// BreakDownSizes computes the top largest repos, size buckets, and total size of the
+// repos whose size the provider reported. Repos listed with a size of 0 are empty and
+// counted in the smallest bucket; repos listed without one are only counted as Unknown.
+func BreakDownSizes(repos []provider.Repository, top int) SizeBreakdown {
+	const MB = 1024 * 1024
+	b := SizeBreakdown{
+		Buckets: []SizeBucket{
+			{Label: "< 1 MB", Max: MB},
+			{Label: "1-10 MB", Max: 10 * MB},
+			{Label: "10-100 MB", Max: 100 * MB},
+			{Label: "> 100 MB"},
+		},
+	}
+
+	var sized []provider.Repository
+	for _, repo := range repos {
+		if !repo.SizeKnown {
+			b.Unknown++
+			continue
+		}
+		sized = append(sized, repo)
+		b.Total += repo.Size
+		for i := range b.Buckets {
+			if b.Buckets[i].Max == 0 || repo.Size < b.Buckets[i].Max {
+				b.Buckets[i].Count++
+				b.Buckets[i].Bytes += repo.Size
+				break
+			}
+		}
+	}
+
+	sorted := sized
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Size > sorted[j].Size
+	})
+	if top < len(sorted) {
+		sorted = sorted[:top]
+	}
+	b.Largest = sorted
+
+	return b
+}
+
+// PrintSizeBreakdown prints the largest repos, a size histogram, and the total
+func PrintSizeBreakdown(b SizeBreakdown, repoCount int) {
+	fmt.Println()
+	fmt.Printf("%s\n", bold("Largest repos"))
+	for i, repo := range b.Largest {
+		fmt.Printf("  %2d. %-50s %10s\n", i+1, repo.FullPath, formatSize(repo.Size))
+	}
+
+	fmt.Println()
+	fmt.Printf("%s\n", bold("Size distribution"))
+	for _, bucket := range b.Buckets {
+		fmt.Printf("  %-10s %6d repos  %10s\n", bucket.Label, bucket.Count, formatSize(bucket.Bytes))
+	}
+
+	fmt.Println()
+	fmt.Printf("Total size: %s across %d repos\n", formatSize(b.Total), repoCount)
+	if b.Unknown > 0 {
+		fmt.Printf("  %s %d repo(s) have no reported size and are not included above\n", yellow("!"), b.Unknown)
+	}
+}
@@ -0,0 +1,52 @@
+package mirror
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ReadMarker reads the timestamp stored in a marker file (see Options.ChangedAfter).
+// A missing or empty file returns the zero time, so the first run mirrors everything.
+func ReadMarker(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to read marker: %w", err)
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return time.Time{}, nil
+	}
+	marker, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse marker %s: %w", path, err)
+	}
+	return marker, nil
+}
+
+// WriteMarker atomically stores a timestamp in a marker file, as a single RFC 3339 line
+func WriteMarker(path string, marker time.Time) error {
+	if err := writeFileAtomic(path, []byte(marker.UTC().Format(time.RFC3339Nano)+"\n")); err != nil {
+		return fmt.Errorf("failed to write marker: %w", err)
+	}
+	return nil
+}
+
+// NextMarker returns the latest LastUpdated of the repos in results that were synced,
+// or prev if none is later
+func NextMarker(prev time.Time, results []Result) time.Time {
+	next := prev
+	for _, r := range results {
+		switch r.Action {
+		case "cloned", "updated", "unchanged", "empty":
+			if r.Repository.LastUpdated.After(next) {
+				next = r.Repository.LastUpdated
+			}
+		}
+	}
+	return next
+}
@@ -0,0 +1,76 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// memberDir is the directory under BaseDir holding member-owned repos (member/<user>/<repo>)
+const memberDir = "member"
+
+// listMemberRepos lists the repos owned by each member of a group/org.
+// Repos are recorded as member-owned so they are laid out under member/<user>/.
+func (m *Mirror) listMemberRepos(ctx context.Context, group string) ([]provider.Repository, error) {
+	members, err := m.provider.ListOrgMembers(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(m.progress(), "%s Fetching repos of %s members of %s...\n", cyan("→"), bold(fmt.Sprintf("%d", len(members))), bold(group))
+
+	var repos []provider.Repository
+	for _, member := range members {
+		memberRepos, err := m.provider.ListUserProjects(ctx, member)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repo := range memberRepos {
+			// Repos the member can push to but does not own are listed under their owner
+			if !strings.EqualFold(path.Dir(repo.FullPath), member) {
+				continue
+			}
+			if m.members == nil {
+				m.members = make(map[string]string)
+			}
+			m.members[repo.FullPath] = member
+			repos = append(repos, repo)
+		}
+	}
+
+	return repos, nil
+}
+
+// memberCounts counts results per member for member-owned repos
+func memberCounts(results []Result) map[string]int {
+	counts := make(map[string]int)
+	for _, r := range results {
+		if r.Member != "" {
+			counts[r.Member]++
+		}
+	}
+	return counts
+}
+
+// printMemberCounts writes the per-member repo counts of a run (Summary.Members)
+func printMemberCounts(w io.Writer, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "  Member repos:\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "    %-20s %d\n", name, counts[name])
+	}
+}
@@ -3,11 +3,14 @@ package mirror
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -45,23 +48,147 @@ func formatSize(bytes int64) string {
 	}
 }
 
+// errEmptyRepo is returned by updateRepo for a clone of a repo with no commits yet
+var errEmptyRepo = errors.New("repository is empty")
+
+// errNoCloneURL fails repos listed without an HTTPS or SSH clone URL. Providers leave
+// them out when the token can see a repo but not read its contents.
+var errNoCloneURL = errors.New("no-clone-url")
+
+// errRepoNotFound is returned by cloneRepo when git reports that the remote repository
+// does not exist or is not visible
+var errRepoNotFound = errors.New("repository not found")
+
+// notFoundMarkers are git and server messages for a remote repository that does not
+// exist. GitHub and GitLab send the same messages for repos the credentials cannot
+// see, so each is checked with the provider. Authentication failures (e.g. git asking
+// for a username) are not among them: those are failures, not missing repos.
+var notFoundMarkers = []string{
+	"repository not found",
+	"could not be found or you don't have permission",
+	"does not appear to be a git repository",
+	"' not found",
+	"' does not exist",
+}
+
+// ErrMaxRuntime marks repos that were cancelled or never started because the run's deadline passed
+var ErrMaxRuntime = errors.New("run time limit reached")
+
 // Result represents the result of a mirror operation
 type Result struct {
 	Repository provider.Repository
-	Action     string // "cloned", "updated", "skipped", "failed"
+	Action     string // "cloned", "updated", "unchanged", "fresh", "skipped", "stale", "too-large", "empty", "now-archived", "failed", "disappeared", "aborted", "timed-out", "collision"
 	Error      error
 	Duration   time.Duration
+	Collision  bool // Local path collided with another repo (see Options.OnCollision)
+
+	RemoteUpdated bool   // origin URL was changed to match the provider
+	MovedFrom     string // Previous local path if the clone was moved after an upstream rename
+	Member        string // Owning org member for repos listed with IncludeMembers
+	Commit        string // HEAD commit SHA after clone/update
+
+	SubmoduleError error // Submodule init/update failed; the repo itself was mirrored
+
+	TrackedBranches int // Local branches kept for origin branches matching TrackBranches
+
+	Replica        string // Destination repo the mirror was pushed to (Options.Replicate)
+	ReplicaCreated bool   // The destination repo was created by this run
+
+	DryRun bool // Planned by a dry run: "cloned" and "updated" mean would be (Options.DryRun)
 }
 
 // Options configures the mirror operation
 type Options struct {
-	BaseDir       string
-	Parallel      int
-	SkipArchived  bool
-	Verbose       bool
-	MaxAgeMonths  int  // Skip repos not updated in this many months (0 = no limit)
-	SkipPreflight bool // Skip credential validation before cloning
-	SSH           bool // Use SSH URLs instead of HTTPS for git operations
+	BaseDir        string
+	Parallel       int
+	SkipArchived   bool
+	Verbose        bool
+	MaxAgeMonths   int    // Skip repos not updated in this many months (0 = no limit)
+	StrictAge      bool   // Age repos by the default branch's last commit instead of provider activity
+	SkipPreflight  bool   // Skip credential validation before cloning
+	SSH            bool   // Use SSH URLs instead of HTTPS for git operations
+	StripPrefix    string // Leading path segments removed from FullPath for the local layout
+	MirrorReleases bool   // Download release assets into <repo>/.ztigit-releases/<tag>/
+	FailFast       bool   // Stop starting new repos after the first failure
+
+	PreferSSHForPrivate bool // Clone private repos over SSH and public repos over HTTPS
+
+	RefreshDefaultBranch bool // Point origin/HEAD at the provider's default branch on update
+	UpdateRemotes        bool // Repoint origin when the provider's clone URL changed
+	FollowRedirects      bool // Move local clones of repos renamed or transferred upstream
+
+	GitConfig []GitConfig // Config applied to every git command via the environment only
+
+	SSHConfig string // SSH config file for git over SSH (ssh -F via GIT_SSH_COMMAND)
+
+	Flatten     bool   // Clone to BaseDir/<repo-name> instead of preserving the namespace
+	OnCollision string // Repos sharing a local path: "suffix" (default), "skip", or "fail"
+
+	RecurseSubmodules bool // Clone and update submodules recursively
+	SubmoduleJobs     int  // Parallel submodule fetches per repo (git --jobs)
+	FetchJobs         int  // Parallel remote/submodule fetches per update (fetch.parallel; 0 = git default)
+
+	Filter           string // Partial clone filter for new clones (git clone --filter, e.g. blob:none)
+	FilterSubmodules bool   // Also apply Filter to submodules (git 2.36+)
+
+	Depth int // Shallow clones with this many commits (git clone --depth), kept on update; 0 = full history
+
+	TrackBranches string // Glob of origin branches to keep as local branches (e.g. release/*)
+
+	PruneTags bool // Delete local tags (and remote-tracking branches) that no longer exist upstream on update
+
+	ResumePartial bool // Remove stale index.lock files and re-clone clones an interrupted run left unfinished
+
+	CloneParallel  int // Max new clones at once, within Parallel (0 = Parallel)
+	UpdateParallel int // Max updates of existing clones at once, within Parallel (0 = Parallel)
+
+	ExcludeSizeZero bool // Skip repos the provider lists with a known size of 0 (usually empty) as "empty"
+
+	MarkArchived bool // Stop updating existing clones of repos archived upstream and write an ARCHIVED marker into them
+
+	Bare bool // Keep bare mirrors (git clone --mirror) at <path>.git instead of working trees
+
+	Replicate *Replica // Push each synced bare mirror to a destination, creating missing repos (requires Bare)
+
+	IncludeMembers bool // Also mirror repos owned by each org member into member/<user>/<repo>
+
+	UpdatedSince time.Time            // Leave existing clones of repos not updated since this time untouched (zero = update all)
+	SyncedSince  map[string]time.Time // Per-repo UpdatedSince by full path, e.g. repos an interrupted run already synced
+
+	ChangedAfter time.Time // Only mirror repos with LastUpdated after this time (zero = all); repos without one are kept
+
+	RefreshStaleOnly time.Duration // Leave existing clones fetched within this long untouched (0 = update all)
+
+	DryRun bool // Report what would be cloned or updated without running git or touching BaseDir
+
+	CheckpointInterval time.Duration  // How often Checkpoint is called during a run (0 = never)
+	Checkpoint         func([]Result) // Receives the results collected so far; called from one goroutine at a time
+
+	Deadline time.Time // Cancel in-flight repos and start no new ones after this time (zero = no limit)
+
+	Grep string // Only mirror repos whose name, path, or description contains this (case-insensitive)
+
+	PathPrefix string // Only mirror repos whose FullPath is this group path or below it (e.g. company/infra)
+
+	ExcludeOwner string // Skip repos in this top-level namespace (case-insensitive), e.g. the authenticated user's
+
+	NameRegex        *regexp.Regexp // Only mirror repos whose name matches (nil = all)
+	NameRegexExclude *regexp.Regexp // Skip repos whose name matches; wins over NameRegex
+
+	Languages []string // Only mirror repos whose primary language is one of these (case-insensitive)
+
+	MaxSize   int64 // Skip repos larger than this many bytes (0 = no limit); repos of unknown size are kept
+	ProbeSize bool  // Look up sizes the listing did not include before applying MaxSize
+
+	Log io.Writer // Output of every git command is appended here, regardless of Verbose
+
+	Audit io.Writer // One NDJSON record per git command (redacted command line, repo, times, exit code)
+
+	Progress io.Writer // Messages printed while listing and filtering repos (nil = stdout)
+
+	DirMode os.FileMode // Mode for created directories, including each clone's top directory (0 = 0755, umask applies)
+
+	LinkDest string // Previous snapshot whose clones seed new ones, hardlinking unchanged objects
 }
 
 // DefaultOptions returns the default mirror options
@@ -83,6 +210,15 @@ func DefaultOptions() Options {
 type Mirror struct {
 	provider provider.Provider
 	options  Options
+	members  map[string]string // FullPath -> owning member, for member-owned repos
+	log      *gitLog
+	audit    *auditLog
+
+	cloneSlots  chan struct{} // Limits concurrent clones (nil = Parallel only)
+	updateSlots chan struct{} // Limits concurrent updates (nil = Parallel only)
+
+	replicaMu    sync.Mutex
+	replicaNames map[string]string // Lowercased replica name -> FullPath of the repo replicated there
 }
 
 // New creates a new Mirror instance
@@ -90,18 +226,45 @@ func New(p provider.Provider, opts Options) *Mirror {
 	if opts.Parallel < 1 {
 		opts.Parallel = 1
 	}
-	return &Mirror{
+	m := &Mirror{
 		provider: p,
 		options:  opts,
 	}
+	if opts.Log != nil {
+		m.log = &gitLog{w: opts.Log}
+	}
+	if opts.Audit != nil {
+		m.audit = &auditLog{w: opts.Audit}
+	}
+	if opts.CloneParallel > 0 {
+		m.cloneSlots = make(chan struct{}, opts.CloneParallel)
+	}
+	if opts.UpdateParallel > 0 {
+		m.updateSlots = make(chan struct{}, opts.UpdateParallel)
+	}
+	return m
 }
 
 // MirrorGroups mirrors all repositories from the specified groups
 func (m *Mirror) MirrorGroups(ctx context.Context, groups []string) ([]Result, error) {
+	if m.canStream() {
+		return m.streamGroups(ctx, groups)
+	}
+
+	allRepos, err := m.ListRepos(ctx, groups)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.preflightAndMirror(ctx, allRepos)
+}
+
+// ListRepos lists all repositories from the specified groups
+func (m *Mirror) ListRepos(ctx context.Context, groups []string) ([]provider.Repository, error) {
 	var allRepos []provider.Repository
 
 	for _, group := range groups {
-		fmt.Printf("%s Fetching repos from %s...\n", cyan("→"), bold(group))
+		fmt.Fprintf(m.progress(), "%s Fetching repos from %s...\n", cyan("→"), bold(group))
 		repos, err := m.provider.ListGroupProjects(ctx, group)
 		if err != nil {
 			return nil, fmt.Errorf("failed to list projects for group %s: %w", group, err)
@@ -113,24 +276,66 @@ func (m *Mirror) MirrorGroups(ctx context.Context, groups []string) ([]Result, e
 			totalSize += r.Size
 		}
 
-		fmt.Printf("%s Found %s repos %s\n\n", cyan("→"), bold(fmt.Sprintf("%d", len(repos))), faint("("+formatSize(totalSize)+")"))
+		fmt.Fprintf(m.progress(), "%s Found %s repos %s\n\n", cyan("→"), bold(fmt.Sprintf("%d", len(repos))), faint("("+formatSize(totalSize)+")"))
 		allRepos = append(allRepos, repos...)
+
+		if m.options.IncludeMembers {
+			memberRepos, err := m.listMemberRepos(ctx, group)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list member repos for group %s: %w", group, err)
+			}
+			fmt.Fprintf(m.progress(), "%s Found %s member repos\n\n", cyan("→"), bold(fmt.Sprintf("%d", len(memberRepos))))
+			allRepos = append(allRepos, memberRepos...)
+		}
 	}
 
-	// Preflight credential check
-	if len(allRepos) > 0 && !m.options.SkipPreflight {
-		fmt.Printf("%s Checking git credentials...\n", cyan("→"))
-		result, err := m.Preflight(ctx, allRepos)
-		if err != nil {
+	return allRepos, nil
+}
+
+// MirrorSearch mirrors all repositories returned by a provider search query
+func (m *Mirror) MirrorSearch(ctx context.Context, query string) ([]Result, error) {
+	repos, err := m.SearchRepos(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.preflightAndMirror(ctx, repos)
+}
+
+// SearchRepos lists all repositories returned by a provider search query
+func (m *Mirror) SearchRepos(ctx context.Context, query string) ([]provider.Repository, error) {
+	fmt.Fprintf(m.progress(), "%s Searching repos matching %s...\n", cyan("→"), bold(query))
+	repos, err := m.provider.SearchRepositories(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search repositories: %w", err)
+	}
+
+	var totalSize int64
+	for _, r := range repos {
+		totalSize += r.Size
+	}
+
+	fmt.Fprintf(m.progress(), "%s Found %s repos %s\n\n", cyan("→"), bold(fmt.Sprintf("%d", len(repos))), faint("("+formatSize(totalSize)+")"))
+
+	return repos, nil
+}
+
+// progress returns where listing and filtering messages go (see Options.Progress)
+func (m *Mirror) progress() io.Writer {
+	if m.options.Progress != nil {
+		return m.options.Progress
+	}
+	return os.Stdout
+}
+
+// preflightAndMirror validates git credentials and mirrors the given repositories
+func (m *Mirror) preflightAndMirror(ctx context.Context, allRepos []provider.Repository) ([]Result, error) {
+	// Preflight credential check, against a repo git can reach; repos without a clone
+	// URL fail on their own as no-clone-url
+	if repo, ok := firstCloneable(allRepos); ok && !m.options.SkipPreflight {
+		if err := m.preflight(ctx, repo); err != nil {
 			return nil, err
 		}
-		// Use the method that works - override SSH if needed
-		if result.Method == "ssh" && !m.options.SSH {
-			m.options.SSH = true
-			fmt.Printf("%s HTTPS unavailable, using SSH\n\n", green("✓"))
-		} else {
-			fmt.Printf("%s Git credentials OK (%s)\n\n", green("✓"), strings.ToUpper(result.Method))
-		}
 	}
 
 	return m.mirrorRepos(ctx, allRepos)
@@ -138,86 +343,283 @@ func (m *Mirror) MirrorGroups(ctx context.Context, groups []string) ([]Result, e
 
 // MirrorRepos mirrors the specified repositories
 func (m *Mirror) mirrorRepos(ctx context.Context, repos []provider.Repository) ([]Result, error) {
-	results := make([]Result, 0, len(repos))
-	resultsChan := make(chan Result, len(repos))
+	repos = m.applyStrictAge(ctx, m.applySizes(ctx, m.applyLanguages(ctx, repos)))
+	active, filtered := m.filterRepos(repos)
+	if filter := m.nameFilter(); filter != "" {
+		fmt.Fprintf(m.progress(), "%s %s of %d repos match %s\n\n", cyan("→"), bold(fmt.Sprintf("%d", len(active)+len(filtered))), len(repos), filter)
+	}
+
+	// Resolve local paths up front so colliding repos never clone over each other
+	plan := m.planLayout(active)
+
+	// Move clones of renamed/transferred repos so they update instead of cloning again
+	if m.options.FollowRedirects && !m.options.DryRun {
+		moved := m.relocateMoved(ctx, plan, repos)
+		for i := range plan {
+			plan[i].movedFrom = moved[plan[i].repo.FullPath]
+		}
+	}
+
+	planned := make(chan plannedRepo, len(filtered)+len(plan))
+	for _, r := range filtered {
+		planned <- plannedRepo{repo: r.Repository, result: &r}
+	}
+	for _, pr := range plan {
+		planned <- pr
+	}
+	close(planned)
+
+	return m.dispatch(ctx, planned), nil
+}
+
+// dispatch syncs planned repos as they arrive, at most Parallel at a time,
+// and returns their results once the channel is closed and all repos are done
+func (m *Mirror) dispatch(ctx context.Context, planned <-chan plannedRepo) []Result {
+	var results []Result
+	var mu sync.Mutex
 	semaphore := make(chan struct{}, m.options.Parallel)
 
+	// runCtx cancels in-flight repos once Deadline passes. dispatchCtx also stops new
+	// repos from starting (--fail-fast); in-flight repos keep runCtx so an abort lets
+	// them finish cleanly instead of leaving partial clones.
+	runCtx := ctx
+	if !m.options.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithDeadline(ctx, m.options.Deadline)
+		defer cancel()
+	}
+	dispatchCtx, abort := context.WithCancel(runCtx)
+	defer abort()
+
+	collect := func(result Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Member = m.members[result.Repository.FullPath]
+		result.DryRun = m.options.DryRun
+		if m.options.FailFast && result.Action == "failed" && dispatchCtx.Err() == nil {
+			fmt.Printf("  %s %s failed, aborting remaining repos (--fail-fast)\n", red("✗"), result.Repository.FullPath)
+			abort()
+		}
+		// An expired token fails every remaining API call the same way
+		if provider.IsUnauthorized(result.Error) && dispatchCtx.Err() == nil {
+			fmt.Printf("  %s %s: token rejected, aborting remaining repos\n", red("✗"), result.Repository.FullPath)
+			abort()
+		}
+		results = append(results, result)
+	}
+
+	stopCheckpoints := m.startCheckpoints(&mu, &results)
+	defer stopCheckpoints()
+
+	var wg sync.WaitGroup
+	for pr := range planned {
+		if pr.result != nil {
+			collect(*pr.result)
+			continue
+		}
+
+		wg.Add(1)
+		go func(pr plannedRepo) {
+			defer wg.Done()
+
+			select {
+			case <-dispatchCtx.Done():
+				collect(m.notStartedResult(ctx, dispatchCtx, pr.repo))
+				return
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			}
+
+			// Abort may have raced with acquiring the semaphore
+			if dispatchCtx.Err() != nil {
+				collect(m.notStartedResult(ctx, dispatchCtx, pr.repo))
+				return
+			}
+
+			start := time.Now()
+			result := m.syncRepo(runCtx, pr.repo, pr.relPath)
+			if result.Action == "failed" && ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+				result = Result{Repository: pr.repo, Action: "timed-out", Error: ErrMaxRuntime}
+			}
+			result.Duration = time.Since(start)
+			result.Collision = pr.collision
+			result.MovedFrom = pr.movedFrom
+			collect(result)
+		}(pr)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// filterRepos splits repos into those to mirror and results for archived/stale repos.
+// Repos not matching Grep are dropped without a result.
+func (m *Mirror) filterRepos(repos []provider.Repository) ([]provider.Repository, []Result) {
 	// Calculate cutoff date for stale repos
 	var cutoffDate time.Time
 	if m.options.MaxAgeMonths > 0 {
 		cutoffDate = time.Now().AddDate(0, -m.options.MaxAgeMonths, 0)
 	}
 
-	var wg sync.WaitGroup
-
+	var active []provider.Repository
+	var filtered []Result
 	for _, repo := range repos {
-		if m.options.SkipArchived && repo.Archived {
-			resultsChan <- Result{
+		if !m.matchesNameFilters(repo) {
+			continue
+		}
+		if len(m.options.Languages) > 0 && !matchesLanguage(repo, m.options.Languages) {
+			continue
+		}
+		if !m.options.ChangedAfter.IsZero() && !repo.LastUpdated.IsZero() && !repo.LastUpdated.After(m.options.ChangedAfter) {
+			continue
+		}
+
+		// With MarkArchived, syncRepo decides once it knows whether a clone exists
+		if m.options.SkipArchived && repo.Archived && !m.options.MarkArchived {
+			filtered = append(filtered, Result{
 				Repository: repo,
 				Action:     "skipped",
-			}
+			})
 			continue
 		}
 
 		// Skip stale repos (not updated within MaxAgeMonths)
 		if m.options.MaxAgeMonths > 0 && !repo.LastUpdated.IsZero() && repo.LastUpdated.Before(cutoffDate) {
-			resultsChan <- Result{
+			filtered = append(filtered, Result{
 				Repository: repo,
 				Action:     "stale",
-			}
+			})
 			continue
 		}
 
-		wg.Add(1)
-		go func(r provider.Repository) {
-			defer wg.Done()
+		if m.options.MaxSize > 0 && repo.Size > m.options.MaxSize {
+			filtered = append(filtered, Result{
+				Repository: repo,
+				Action:     "too-large",
+			})
+			continue
+		}
 
-			select {
-			case <-ctx.Done():
-				resultsChan <- Result{
-					Repository: r,
-					Action:     "failed",
-					Error:      ctx.Err(),
-				}
-				return
-			case semaphore <- struct{}{}:
-				defer func() { <-semaphore }()
-			}
+		// A repo listed without a size is not known to be empty, so it is kept
+		if m.options.ExcludeSizeZero && repo.SizeKnown && repo.Size == 0 {
+			filtered = append(filtered, Result{
+				Repository: repo,
+				Action:     "empty",
+			})
+			continue
+		}
 
-			start := time.Now()
-			result := m.mirrorRepo(ctx, r)
-			result.Duration = time.Since(start)
-			resultsChan <- result
-		}(repo)
+		active = append(active, repo)
 	}
 
-	// Wait for all goroutines and close channel
-	go func() {
-		wg.Wait()
-		close(resultsChan)
-	}()
+	return active, filtered
+}
 
-	// Collect results
-	for result := range resultsChan {
-		results = append(results, result)
+// matchesGrep reports whether a repo's name, full path, or description
+// contains term, ignoring case
+func matchesGrep(repo provider.Repository, term string) bool {
+	term = strings.ToLower(term)
+	for _, field := range []string{repo.Name, repo.FullPath, repo.Description} {
+		if strings.Contains(strings.ToLower(field), term) {
+			return true
+		}
+	}
+	return false
+}
+
+// nameFilter describes the active name and language filters for progress output, or "" if there are none
+func (m *Mirror) nameFilter() string {
+	var parts []string
+	if m.options.PathPrefix != "" {
+		parts = append(parts, "path "+strings.Trim(m.options.PathPrefix, "/")+"/")
+	}
+	if m.options.ExcludeOwner != "" {
+		parts = append(parts, "not owned by "+m.options.ExcludeOwner)
+	}
+	if m.options.Grep != "" {
+		parts = append(parts, strconv.Quote(m.options.Grep))
 	}
+	if m.options.NameRegex != nil {
+		parts = append(parts, "name =~ /"+m.options.NameRegex.String()+"/")
+	}
+	if m.options.NameRegexExclude != nil {
+		parts = append(parts, "name !~ /"+m.options.NameRegexExclude.String()+"/")
+	}
+	if len(m.options.Languages) > 0 {
+		parts = append(parts, "language "+strings.Join(m.options.Languages, "/"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// matchesNameFilters reports whether a repo passes PathPrefix, ExcludeOwner, Grep, and the name regexes
+func (m *Mirror) matchesNameFilters(repo provider.Repository) bool {
+	if m.options.PathPrefix != "" && !hasPathPrefix(repo.FullPath, m.options.PathPrefix) {
+		return false
+	}
+	if m.options.ExcludeOwner != "" && strings.EqualFold(repo.Owner, m.options.ExcludeOwner) {
+		return false
+	}
+	if m.options.Grep != "" && !matchesGrep(repo, m.options.Grep) {
+		return false
+	}
+	return matchesNameRegex(repo, m.options.NameRegex, m.options.NameRegexExclude)
+}
+
+// hasPathPrefix reports whether fullPath is prefix or lies below it, matching whole
+// path segments so company/infra does not match company/infrastructure
+func hasPathPrefix(fullPath, prefix string) bool {
+	prefix = strings.Trim(prefix, "/")
+	return fullPath == prefix || strings.HasPrefix(fullPath, prefix+"/")
+}
 
-	return results, nil
+// matchesNameRegex reports whether a repo's name matches include (if set) and
+// does not match exclude (if set)
+func matchesNameRegex(repo provider.Repository, include, exclude *regexp.Regexp) bool {
+	if exclude != nil && exclude.MatchString(repo.Name) {
+		return false
+	}
+	return include == nil || include.MatchString(repo.Name)
+}
+
+// notStartedResult builds the result for a repo that was never started, either
+// because the parent context was cancelled, the deadline passed, or the run was aborted
+func (m *Mirror) notStartedResult(ctx, dispatchCtx context.Context, repo provider.Repository) Result {
+	if ctx.Err() != nil {
+		return Result{
+			Repository: repo,
+			Action:     "failed",
+			Error:      ctx.Err(),
+		}
+	}
+	if errors.Is(dispatchCtx.Err(), context.DeadlineExceeded) {
+		return Result{
+			Repository: repo,
+			Action:     "timed-out",
+			Error:      ErrMaxRuntime,
+		}
+	}
+	return Result{
+		Repository: repo,
+		Action:     "aborted",
+	}
 }
 
 // mirrorRepo clones or updates a single repository
 func (m *Mirror) mirrorRepo(ctx context.Context, repo provider.Repository) Result {
-	// Validate the path before using it
-	if err := validatePath(repo.FullPath); err != nil {
+	relPath, err := m.localPath(repo)
+	if err != nil {
 		return Result{
 			Repository: repo,
 			Action:     "failed",
-			Error:      fmt.Errorf("invalid path %q: %w", repo.FullPath, err),
+			Error:      err,
 		}
 	}
+	return m.syncRepo(ctx, repo, relPath)
+}
 
-	// Clone into BaseDir/<full-path> to preserve hierarchy
-	repoDir := filepath.Join(m.options.BaseDir, repo.FullPath)
+// syncRepo clones or updates a repository at BaseDir/<relPath>
+func (m *Mirror) syncRepo(ctx context.Context, repo provider.Repository, relPath string) Result {
+	repoDir := filepath.Join(m.options.BaseDir, relPath)
 
 	// Validate the full absolute path length (critical for Windows MAX_PATH)
 	if err := validateFullPathLength(repoDir); err != nil {
@@ -235,53 +637,207 @@ func (m *Mirror) mirrorRepo(ctx context.Context, repo provider.Repository) Resul
 	}
 
 	// Check if repository already exists
-	if isGitRepo(repoDir) {
-		fmt.Printf("  %s %s%s\n", cyan("↻"), repo.FullPath, sizeStr)
-		err := m.updateRepo(ctx, repoDir)
+	exists := isGitRepo(repoDir)
+	if m.options.Bare {
+		exists = isBareRepo(repoDir)
+	}
+	if exists && m.options.ResumePartial && !m.options.DryRun {
+		usable, err := m.repairPartial(ctx, repo, repoDir)
 		if err != nil {
 			return Result{
 				Repository: repo,
 				Action:     "failed",
-				Error:      fmt.Errorf("update failed: %w", err),
+				Error:      fmt.Errorf("repairing interrupted clone failed: %w", err),
+			}
+		}
+		exists = usable
+	}
+	if repo.Archived && m.options.MarkArchived {
+		if exists && !m.options.DryRun {
+			if err := m.markArchived(repoDir); err != nil {
+				return Result{
+					Repository: repo,
+					Action:     "failed",
+					Error:      fmt.Errorf("marking archived clone failed: %w", err),
+				}
 			}
 		}
+		if exists {
+			return Result{
+				Repository: repo,
+				Action:     "now-archived",
+			}
+		}
+		if m.options.SkipArchived {
+			return Result{
+				Repository: repo,
+				Action:     "skipped",
+			}
+		}
+	}
+	// Repos not updated since the last run have nothing to fetch; new ones are still cloned
+	if since := m.updatedSince(repo); exists && !since.IsZero() && !repo.LastUpdated.IsZero() && repo.LastUpdated.Before(since) {
+		commit := m.plannedCommit(ctx, repoDir)
 		return Result{
 			Repository: repo,
-			Action:     "updated",
+			Action:     "unchanged",
+			Commit:     commit,
 		}
 	}
+	// For rolling refreshes, clones fetched recently are left for a later run
+	if exists && m.options.RefreshStaleOnly > 0 {
+		if fetched := m.lastFetched(repoDir); !fetched.IsZero() && time.Since(fetched) < m.options.RefreshStaleOnly {
+			commit := m.plannedCommit(ctx, repoDir)
+			return Result{
+				Repository: repo,
+				Action:     "fresh",
+				Commit:     commit,
+			}
+		}
+	}
+
+	// Without a URL git would only report a confusing error; existing clones fetch from their origin
+	if !exists && !hasCloneURL(repo) {
+		return Result{
+			Repository: repo,
+			Action:     "failed",
+			Error:      fmt.Errorf("%w: the provider listed this repo without a clone URL, usually because the token lacks read access to it", errNoCloneURL),
+		}
+	}
+
+	// A dry run stops at the decision; the listing and BaseDir are all it looks at
+	if m.options.DryRun {
+		action := "cloned"
+		if exists {
+			action = "updated"
+		}
+		return Result{Repository: repo, Action: action}
+	}
+
+	release, err := m.slot(ctx, exists)
+	if err != nil {
+		return Result{
+			Repository: repo,
+			Action:     "failed",
+			Error:      err,
+		}
+	}
+	defer release()
+
+	if exists {
+		fmt.Printf("  %s %s%s\n", cyan("↻"), repo.FullPath, sizeStr)
+
+		// Repoint origin before fetching if the provider URL changed (e.g., server migration)
+		remoteUpdated := false
+		if m.options.UpdateRemotes {
+			updated, err := m.updateRemote(ctx, repoDir, repo)
+			if err != nil {
+				return Result{
+					Repository: repo,
+					Action:     "failed",
+					Error:      fmt.Errorf("remote update failed: %w", err),
+				}
+			}
+			remoteUpdated = updated
+		}
+
+		var err error
+		if m.options.Bare {
+			err = m.updateBareRepo(ctx, repoDir)
+		} else {
+			err = m.updateRepo(ctx, repoDir, repo.DefaultBranch)
+		}
+		if errors.Is(err, errEmptyRepo) {
+			return Result{
+				Repository:    repo,
+				Action:        "empty",
+				RemoteUpdated: remoteUpdated,
+			}
+		}
+		if err != nil {
+			return Result{
+				Repository:    repo,
+				Action:        "failed",
+				Error:         fmt.Errorf("update failed: %w", err),
+				RemoteUpdated: remoteUpdated,
+			}
+		}
+		result := m.afterSync(ctx, repo, repoDir, "updated")
+		result.RemoteUpdated = remoteUpdated
+		return result
+	}
 
-	// Clone the repository - order depends on SSH option
+	// Seed from the previous snapshot so only upstream changes are fetched
+	if prevDir := m.linkedClone(relPath); prevDir != "" {
+		fmt.Printf("  %s %s%s %s\n", cyan("↓"), repo.FullPath, sizeStr, faint("(linked)"))
+		err := m.linkClone(ctx, repo, prevDir, repoDir)
+		if err == nil {
+			return m.afterSync(ctx, repo, repoDir, "cloned")
+		}
+		fmt.Printf("    %s linking from previous snapshot failed, cloning: %v\n", yellow("!"), err)
+		if err := os.RemoveAll(repoDir); err != nil {
+			return Result{
+				Repository: repo,
+				Action:     "failed",
+				Error:      fmt.Errorf("failed to clean up partial clone: %w", err),
+			}
+		}
+	}
+
+	// Clone the repository - order depends on SSH option (or repo visibility)
 	fmt.Printf("  %s %s%s\n", cyan("↓"), repo.FullPath, sizeStr)
 
 	var primaryURL, fallbackURL string
 	var primaryMethod, fallbackMethod string
 
-	if m.options.SSH {
+	if m.useSSH(repo) {
 		primaryURL, fallbackURL = repo.SSHUrl, repo.CloneURL
 		primaryMethod, fallbackMethod = "SSH", "HTTPS"
 	} else {
 		primaryURL, fallbackURL = repo.CloneURL, repo.SSHUrl
 		primaryMethod, fallbackMethod = "HTTPS", "SSH"
 	}
+	if primaryURL == "" {
+		// Only the other protocol has a URL; there is nothing to fall back to
+		primaryURL, fallbackURL = fallbackURL, ""
+		primaryMethod = fallbackMethod
+	}
 
-	err := m.cloneRepo(ctx, primaryURL, repoDir)
+	err = m.cloneRepo(ctx, primaryURL, repoDir)
+	if err != nil && ctx.Err() != nil {
+		// A killed git clone leaves its directory behind; remove it so the next run clones afresh
+		os.RemoveAll(repoDir)
+		return Result{
+			Repository: repo,
+			Action:     "failed",
+			Error:      fmt.Errorf("clone cancelled: %w", ctx.Err()),
+		}
+	}
+	if errors.Is(err, errRepoNotFound) {
+		if result, ok := m.checkDisappeared(ctx, repo, err); ok {
+			return result
+		}
+	}
 	if err != nil {
 		// Try fallback if primary fails
 		if fallbackURL != "" {
 			fmt.Printf("    %s %s failed, trying %s...\n", yellow("!"), primaryMethod, fallbackMethod)
 			fallbackErr := m.cloneRepo(ctx, fallbackURL, repoDir)
 			if fallbackErr != nil {
+				if ctx.Err() != nil {
+					os.RemoveAll(repoDir)
+				} else if errors.Is(fallbackErr, errRepoNotFound) {
+					if result, ok := m.checkDisappeared(ctx, repo, fallbackErr); ok {
+						return result
+					}
+				}
 				return Result{
 					Repository: repo,
 					Action:     "failed",
 					Error:      fmt.Errorf("clone failed (%s: %v, %s: %v)", primaryMethod, err, fallbackMethod, fallbackErr),
 				}
 			}
-			return Result{
-				Repository: repo,
-				Action:     "cloned",
-			}
+			return m.afterSync(ctx, repo, repoDir, "cloned")
 		}
 		return Result{
 			Repository: repo,
@@ -290,39 +846,250 @@ func (m *Mirror) mirrorRepo(ctx context.Context, repo provider.Repository) Resul
 		}
 	}
 
+	return m.afterSync(ctx, repo, repoDir, "cloned")
+}
+
+// plannedCommit returns the HEAD commit of a clone left as is, or "" in a dry run,
+// which runs no git commands
+func (m *Mirror) plannedCommit(ctx context.Context, repoDir string) string {
+	if m.options.DryRun {
+		return ""
+	}
+	commit, _ := m.headCommit(ctx, repoDir)
+	return commit
+}
+
+// checkDisappeared looks up repo, which git could not find (cloneErr), with the provider.
+// If the provider does not find it either, it was deleted or made private after the
+// repo list was fetched: the result is "disappeared". If the lookup fails for another
+// reason (an expired token, a server error), the result is a failure wrapping both
+// errors, so an expired token is still recognized. ok is false if the provider still
+// returns the repo, which then failed to clone for another reason (e.g. the
+// credentials git used).
+func (m *Mirror) checkDisappeared(ctx context.Context, repo provider.Repository, cloneErr error) (Result, bool) {
+	_, err := m.provider.GetProject(ctx, repo.FullPath)
+	switch {
+	case err == nil || ctx.Err() != nil:
+		return Result{}, false
+	case provider.IsNotFound(err):
+		return Result{
+			Repository: repo,
+			Action:     "disappeared",
+			Error:      fmt.Errorf("deleted or hidden upstream since it was listed: %w", err),
+		}, true
+	default:
+		return Result{
+			Repository: repo,
+			Action:     "failed",
+			Error:      fmt.Errorf("clone failed: %w (looking up the repo failed: %w)", cloneErr, err),
+		}, true
+	}
+}
+
+// updatedSince returns the cutoff before which an existing clone of repo is left
+// untouched: the later of UpdatedSince and the repo's SyncedSince entry
+func (m *Mirror) updatedSince(repo provider.Repository) time.Time {
+	since := m.options.UpdatedSince
+	if synced, ok := m.options.SyncedSince[repo.FullPath]; ok && synced.After(since) {
+		since = synced
+	}
+	return since
+}
+
+// lastFetched returns when a clone was last fetched, from the modification time of its
+// FETCH_HEAD, or the zero time if it has not been fetched since it was cloned
+func (m *Mirror) lastFetched(repoDir string) time.Time {
+	gitDir := filepath.Join(repoDir, ".git")
+	if m.options.Bare {
+		gitDir = repoDir
+	}
+	info, err := os.Stat(filepath.Join(gitDir, "FETCH_HEAD"))
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// useSSH reports whether a repo should be cloned over SSH first.
+// With PreferSSHForPrivate, private repos use SSH and public repos use anonymous HTTPS.
+func (m *Mirror) useSSH(repo provider.Repository) bool {
+	if m.options.PreferSSHForPrivate {
+		return repo.Private
+	}
+	return m.options.SSH
+}
+
+// afterSync runs optional post-clone/update steps and builds the final result
+func (m *Mirror) afterSync(ctx context.Context, repo provider.Repository, repoDir, action string) Result {
+	// A clone of an empty repo has no branch to set up or push until the first commit
+	if empty, err := m.isEmptyClone(ctx, repoDir); err == nil && empty {
+		return Result{
+			Repository: repo,
+			Action:     "empty",
+		}
+	}
+
+	// Bare mirrors keep whatever HEAD the server sent; make it match the default branch
+	if m.options.Bare {
+		if err := m.setBareHead(ctx, repoDir, repo.DefaultBranch); err != nil {
+			return Result{
+				Repository: repo,
+				Action:     "failed",
+				Error:      fmt.Errorf("%s, but %w", action, err),
+			}
+		}
+	}
+
+	if m.options.MirrorReleases {
+		count, err := m.mirrorReleases(ctx, repo, repoDir)
+		if err != nil {
+			return Result{
+				Repository: repo,
+				Action:     "failed",
+				Error:      fmt.Errorf("%s, but release download failed: %w", action, err),
+			}
+		}
+		if count > 0 {
+			fmt.Printf("    %s %d release asset(s) for %s\n", green("✓"), count, repo.FullPath)
+		}
+	}
+
+	var tracked int
+	if m.options.TrackBranches != "" && !m.options.Bare {
+		count, err := m.trackBranches(ctx, repoDir)
+		if err != nil {
+			return Result{
+				Repository: repo,
+				Action:     "failed",
+				Error:      fmt.Errorf("%s, but branch tracking failed: %w", action, err),
+			}
+		}
+		tracked = count
+	}
+
+	var replica string
+	var replicaCreated bool
+	if m.options.Replicate != nil && m.options.Bare {
+		target, created, err := m.replicate(ctx, repo, repoDir)
+		if err != nil {
+			return Result{
+				Repository:     repo,
+				Action:         "failed",
+				Error:          fmt.Errorf("%s, but replication failed: %w", action, err),
+				ReplicaCreated: created,
+			}
+		}
+		replica, replicaCreated = target.FullPath, created
+	}
+
+	// A broken submodule shouldn't discard an otherwise good clone; it is reported separately
+	var submoduleErr error
+	if m.options.RecurseSubmodules && !m.options.Bare {
+		submoduleErr = m.updateSubmodules(ctx, repo, repoDir)
+		if submoduleErr != nil {
+			fmt.Printf("    %s %s: %v\n", yellow("!"), repo.FullPath, submoduleErr)
+		}
+	}
+
+	// Record the commit this run captured (best effort; an empty repo has no HEAD)
+	commit, _ := m.headCommit(ctx, repoDir)
+
 	return Result{
-		Repository: repo,
-		Action:     "cloned",
+		Repository:      repo,
+		Action:          action,
+		Commit:          commit,
+		SubmoduleError:  submoduleErr,
+		TrackedBranches: tracked,
+		Replica:         replica,
+		ReplicaCreated:  replicaCreated,
 	}
 }
 
 // cloneRepo clones a repository to the specified directory
 func (m *Mirror) cloneRepo(ctx context.Context, url, dir string) error {
 	// Create parent directory
-	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+	if err := m.mkdirAll(filepath.Dir(dir)); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	cmd := exec.CommandContext(ctx, "git", "clone", url, dir)
+	args := []string{"clone"}
+	if m.options.Bare {
+		args = append(args, "--mirror")
+	}
+	if m.options.Filter != "" {
+		args = append(args, "--filter="+m.options.Filter)
+	}
+	if m.options.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(m.options.Depth))
+		if m.options.TrackBranches != "" {
+			// --depth implies --single-branch, which would leave nothing to track
+			args = append(args, "--no-single-branch")
+		}
+	}
+	args = append(args, url, dir)
+
+	// stderr is kept to tell a missing repo from other failures
+	stderr := &syncBuffer{}
+	cmd := m.gitCommand(ctx, args...)
 	cmd.Stdout = nil
-	cmd.Stderr = nil
+	cmd.Stderr = stderr
 
 	if m.options.Verbose {
 		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
 	}
 
-	if err := cmd.Run(); err != nil {
+	if err := m.run(cmd); err != nil {
+		if repoNotFound(stderr.Bytes()) {
+			return fmt.Errorf("git clone failed: %w: %w", errRepoNotFound, err)
+		}
 		return fmt.Errorf("git clone failed: %w", err)
 	}
 
-	return nil
+	return m.applyDirMode(dir)
 }
 
-// updateRepo updates an existing repository
-func (m *Mirror) updateRepo(ctx context.Context, dir string) error {
-	// Fetch all remotes
-	fetchCmd := exec.CommandContext(ctx, "git", "-C", dir, "fetch", "--all")
+// repoNotFound reports whether git's stderr says the remote repository does not exist
+func repoNotFound(stderr []byte) bool {
+	msg := strings.ToLower(string(stderr))
+	for _, marker := range notFoundMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchArgs returns the git arguments that fetch all remotes of the clone at dir
+func (m *Mirror) fetchArgs(dir string) []string {
+	args := []string{"-C", dir, "fetch", "--all"}
+	if m.options.PruneTags {
+		// --prune-tags only takes effect together with --prune
+		args = append(args, "--prune", "--prune-tags")
+	}
+	if m.options.FetchJobs > 0 {
+		args = append(args, "--jobs", strconv.Itoa(m.options.FetchJobs))
+	}
+	return args
+}
+
+// updateRepo updates an existing repository.
+// defaultBranch is the provider-reported default branch; if empty, origin/HEAD is used.
+func (m *Mirror) updateRepo(ctx context.Context, dir, defaultBranch string) error {
+	fetchArgs := m.fetchArgs(dir)
+	// Shallow clones stay at Depth; full clones are never truncated
+	var depthArgs []string
+	if m.options.Depth > 0 {
+		shallow, err := m.isShallow(ctx, dir)
+		if err != nil {
+			return err
+		}
+		if shallow {
+			depthArgs = []string{"--depth", strconv.Itoa(m.options.Depth)}
+			fetchArgs = append(fetchArgs, depthArgs...)
+		}
+	}
+	fetchCmd := m.gitCommand(ctx, fetchArgs...)
 	fetchCmd.Stdout = nil
 	fetchCmd.Stderr = nil
 
@@ -331,29 +1098,46 @@ func (m *Mirror) updateRepo(ctx context.Context, dir string) error {
 		fetchCmd.Stderr = os.Stderr
 	}
 
-	if err := fetchCmd.Run(); err != nil {
+	if err := m.run(fetchCmd); err != nil {
 		return fmt.Errorf("git fetch failed: %w", err)
 	}
 
-	// Get the default branch from git
-	branch, err := m.getDefaultBranch(ctx, dir)
+	// Nothing to check out until the first push; origin/HEAD and the default branch do not exist yet
+	empty, err := m.isEmptyClone(ctx, dir)
 	if err != nil {
 		return err
 	}
+	if empty {
+		return errEmptyRepo
+	}
+
+	// Prefer the provider's default branch; origin/HEAD may be unset or stale
+	branch := defaultBranch
+	if branch == "" {
+		var err error
+		branch, err = m.getDefaultBranch(ctx, dir)
+		if err != nil {
+			return err
+		}
+	} else if m.options.RefreshDefaultBranch {
+		if err := m.setRemoteHead(ctx, dir, branch); err != nil {
+			return err
+		}
+	}
 
 	// Check if there are local changes
-	statusCmd := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain")
-	statusOutput, err := statusCmd.Output()
+	statusCmd := m.gitCommand(ctx, "-C", dir, "status", "--porcelain")
+	statusOutput, err := m.output(statusCmd)
 	if err != nil {
 		return fmt.Errorf("git status failed: %w", err)
 	}
 
 	if len(statusOutput) > 0 {
 		// Stash local changes
-		stashCmd := exec.CommandContext(ctx, "git", "-C", dir, "stash", "push", "-m", "ztigit auto-stash")
+		stashCmd := m.gitCommand(ctx, "-C", dir, "stash", "push", "-m", "ztigit auto-stash")
 		stashCmd.Stdout = nil
 		stashCmd.Stderr = nil
-		_ = stashCmd.Run() // Ignore errors, might not have anything to stash
+		_ = m.run(stashCmd) // Ignore errors, might not have anything to stash
 	}
 
 	// Switch to default branch
@@ -361,8 +1145,9 @@ func (m *Mirror) updateRepo(ctx context.Context, dir string) error {
 		return fmt.Errorf("failed to checkout %s: %w", branch, err)
 	}
 
-	// Pull latest changes
-	pullCmd := exec.CommandContext(ctx, "git", "-C", dir, "pull", "origin", branch)
+	// Pull latest changes; a shallow pull that cannot fast-forward falls back to the reset below
+	pullArgs := append([]string{"-C", dir, "pull"}, depthArgs...)
+	pullCmd := m.gitCommand(ctx, append(pullArgs, "origin", branch)...)
 	pullCmd.Stdout = nil
 	pullCmd.Stderr = nil
 
@@ -371,12 +1156,12 @@ func (m *Mirror) updateRepo(ctx context.Context, dir string) error {
 		pullCmd.Stderr = os.Stderr
 	}
 
-	if err := pullCmd.Run(); err != nil {
+	if err := m.run(pullCmd); err != nil {
 		// Try reset to origin if pull fails
-		resetCmd := exec.CommandContext(ctx, "git", "-C", dir, "reset", "--hard", "origin/"+branch)
+		resetCmd := m.gitCommand(ctx, "-C", dir, "reset", "--hard", "origin/"+branch)
 		resetCmd.Stdout = nil
 		resetCmd.Stderr = nil
-		if resetErr := resetCmd.Run(); resetErr != nil {
+		if resetErr := m.run(resetCmd); resetErr != nil {
 			return fmt.Errorf("git pull and reset failed: %w", err)
 		}
 	}
@@ -386,8 +1171,8 @@ func (m *Mirror) updateRepo(ctx context.Context, dir string) error {
 
 // getDefaultBranch gets the default branch from git
 func (m *Mirror) getDefaultBranch(ctx context.Context, dir string) (string, error) {
-	cmd := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--abbrev-ref", "origin/HEAD")
-	output, err := cmd.Output()
+	cmd := m.gitCommand(ctx, "-C", dir, "rev-parse", "--abbrev-ref", "origin/HEAD")
+	output, err := m.output(cmd)
 	if err != nil {
 		return "", fmt.Errorf("failed to get default branch: %w", err)
 	}
@@ -396,21 +1181,66 @@ func (m *Mirror) getDefaultBranch(ctx context.Context, dir string) (string, erro
 	return strings.TrimPrefix(ref, "origin/"), nil
 }
 
+// updateRemote sets origin to the provider's clone URL when it no longer matches
+// either the HTTPS or SSH URL. Returns true if the remote was changed.
+func (m *Mirror) updateRemote(ctx context.Context, dir string, repo provider.Repository) (bool, error) {
+	getCmd := m.gitCommand(ctx, "-C", dir, "remote", "get-url", "origin")
+	output, err := m.output(getCmd)
+	if err != nil {
+		return false, fmt.Errorf("failed to read origin URL: %w", err)
+	}
+	current := strings.TrimSpace(string(output))
+
+	// Either URL is fine - the clone may have used the fallback method
+	if current == repo.CloneURL || current == repo.SSHUrl {
+		return false, nil
+	}
+
+	want := repo.CloneURL
+	if m.useSSH(repo) && repo.SSHUrl != "" {
+		want = repo.SSHUrl
+	}
+	if want == "" {
+		return false, nil
+	}
+
+	setCmd := m.gitCommand(ctx, "-C", dir, "remote", "set-url", "origin", want)
+	setCmd.Stdout = nil
+	setCmd.Stderr = nil
+	if err := m.run(setCmd); err != nil {
+		return false, fmt.Errorf("failed to set origin URL: %w", err)
+	}
+
+	fmt.Printf("    %s origin: %s → %s\n", yellow("!"), current, want)
+	return true, nil
+}
+
+// setRemoteHead points origin/HEAD at the given branch so git tools agree with the provider
+func (m *Mirror) setRemoteHead(ctx context.Context, dir, branch string) error {
+	cmd := m.gitCommand(ctx, "-C", dir, "remote", "set-head", "origin", branch)
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	if err := m.run(cmd); err != nil {
+		return fmt.Errorf("failed to set origin/HEAD to %s: %w", branch, err)
+	}
+	return nil
+}
+
 // checkoutBranch switches to a branch, creating it from remote if needed
 func (m *Mirror) checkoutBranch(ctx context.Context, dir, branch string) error {
 	// First try simple checkout (branch exists locally)
-	checkoutCmd := exec.CommandContext(ctx, "git", "-C", dir, "checkout", branch)
+	checkoutCmd := m.gitCommand(ctx, "-C", dir, "checkout", branch)
 	checkoutCmd.Stdout = nil
 	checkoutCmd.Stderr = nil
-	if err := checkoutCmd.Run(); err == nil {
+	if err := m.run(checkoutCmd); err == nil {
 		return nil
 	}
 
 	// Branch doesn't exist locally, create from remote
-	createCmd := exec.CommandContext(ctx, "git", "-C", dir, "checkout", "-b", branch, "origin/"+branch)
+	createCmd := m.gitCommand(ctx, "-C", dir, "checkout", "-b", branch, "origin/"+branch)
 	createCmd.Stdout = nil
 	createCmd.Stderr = nil
-	if err := createCmd.Run(); err != nil {
+	if err := m.run(createCmd); err != nil {
 		return fmt.Errorf("branch %s not found locally or on remote", branch)
 	}
 
@@ -472,7 +1302,6 @@ func validateFullPathLength(absolutePath string) error {
 	if runtime.GOOS == "windows" {
 		// Windows MAX_PATH is 260 characters (including null terminator)
 		// Use 259 as the safe limit
-		const windowsMaxPath = 259
 		if pathLen > windowsMaxPath {
 			return fmt.Errorf("absolute path length %d exceeds Windows MAX_PATH limit of %d", pathLen, windowsMaxPath)
 		}
@@ -548,47 +1377,164 @@ func getMaxRelativePathLength() int {
 	}
 }
 
-// PrintResults prints the mirror results to stdout
+// syncNote describes extra work done for a synced repo (tracked branches, replica), or "" if none
+func syncNote(r Result) string {
+	var note string
+	if r.TrackedBranches > 0 {
+		note += fmt.Sprintf(", %d branch(es) tracked", r.TrackedBranches)
+	}
+	if r.Replica != "" {
+		note += ", pushed to " + r.Replica
+		if r.ReplicaCreated {
+			note += " (created)"
+		}
+	}
+	return note
+}
+
+// Summary formats for WriteResults
+const (
+	SummaryVerbose = "verbose" // A line per count, then notes (the default)
+	SummaryCompact = "compact" // A single key=value line, for dashboards and logs
+	SummaryNone    = "none"    // No summary, only the line per repo
+)
+
+// PrintResults prints the mirror results and a verbose summary to stdout
 func PrintResults(results []Result) {
-	var cloned, updated, skipped, stale, failed int
+	WriteResults(os.Stdout, results, SummaryVerbose, 0)
+}
 
-	fmt.Println()
+// WriteResults writes a line per repo to w, followed by the summary in format.
+// elapsed is the run's wall-clock time for the compact summary (0 = not shown).
+func WriteResults(w io.Writer, results []Result, format string, elapsed time.Duration) {
+	fmt.Fprintln(w)
 	for _, r := range results {
 		switch r.Action {
-		case "cloned":
-			cloned++
-			fmt.Printf("  %s %s %s\n", green("✓"), r.Repository.FullPath, faint(r.Duration.Round(time.Millisecond).String()))
-		case "updated":
-			updated++
-			fmt.Printf("  %s %s %s\n", green("✓"), r.Repository.FullPath, faint(r.Duration.Round(time.Millisecond).String()))
+		case "cloned", "updated":
+			if r.DryRun {
+				planned := "(would clone)"
+				if r.Action == "updated" {
+					planned = "(would update)"
+				}
+				fmt.Fprintf(w, "  %s %s %s\n", cyan("→"), r.Repository.FullPath, faint(planned))
+				continue
+			}
+			fmt.Fprintf(w, "  %s %s %s\n", green("✓"), r.Repository.FullPath, faint(r.Duration.Round(time.Millisecond).String()+syncNote(r)))
+		case "unchanged":
+			fmt.Fprintf(w, "  %s %s %s\n", green("✓"), r.Repository.FullPath, faint("(unchanged since last run)"))
+		case "fresh":
+			fmt.Fprintf(w, "  %s %s %s\n", yellow("○"), r.Repository.FullPath, faint("(fetched recently, not refreshed)"))
 		case "skipped":
-			skipped++
-			fmt.Printf("  %s %s %s\n", yellow("○"), r.Repository.FullPath, faint("(archived)"))
+			fmt.Fprintf(w, "  %s %s %s\n", yellow("○"), r.Repository.FullPath, faint("(archived)"))
+		case "now-archived":
+			fmt.Fprintf(w, "  %s %s %s\n", yellow("○"), r.Repository.FullPath, faint("(archived upstream, no longer updated)"))
 		case "stale":
-			stale++
-			fmt.Printf("  %s %s %s\n", yellow("○"), r.Repository.FullPath, faint("(stale: "+r.Repository.LastUpdated.Format("2006-01-02")+")"))
+			fmt.Fprintf(w, "  %s %s %s\n", yellow("○"), r.Repository.FullPath, faint("(stale: "+r.Repository.LastUpdated.Format("2006-01-02")+")"))
+		case "too-large":
+			fmt.Fprintf(w, "  %s %s %s\n", yellow("○"), r.Repository.FullPath, faint("(too large: "+formatSize(r.Repository.Size)+")"))
+		case "empty":
+			fmt.Fprintf(w, "  %s %s %s\n", yellow("○"), r.Repository.FullPath, faint("(empty)"))
 		case "failed":
-			failed++
-			fmt.Printf("  %s %s %s\n", red("✗"), r.Repository.FullPath, faint(r.Error.Error()))
+			fmt.Fprintf(w, "  %s %s %s\n", red("✗"), r.Repository.FullPath, faint(r.Error.Error()))
+		case "disappeared":
+			fmt.Fprintf(w, "  %s %s %s\n", yellow("○"), r.Repository.FullPath, faint("(disappeared: "+r.Error.Error()+")"))
+		case "aborted":
+			fmt.Fprintf(w, "  %s %s %s\n", yellow("○"), r.Repository.FullPath, faint("(not run: aborted)"))
+		case "timed-out":
+			fmt.Fprintf(w, "  %s %s %s\n", yellow("○"), r.Repository.FullPath, faint("(timed out: --max-runtime)"))
+		case "collision":
+			fmt.Fprintf(w, "  %s %s %s\n", yellow("○"), r.Repository.FullPath, faint("("+r.Error.Error()+")"))
+		}
+		if r.SubmoduleError != nil {
+			fmt.Fprintf(w, "    %s %s\n", yellow("!"), faint(r.SubmoduleError.Error()))
 		}
 	}
 
-	fmt.Println()
-	fmt.Printf("%s\n", bold("Summary"))
-	if cloned > 0 {
-		fmt.Printf("  %s Cloned:  %d\n", green("✓"), cloned)
+	s := Summarize(results)
+	switch format {
+	case SummaryNone:
+	case SummaryCompact:
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, CompactSummary(s, elapsed))
+	default:
+		writeSummary(w, s)
+	}
+}
+
+// writeSummary writes the verbose summary: a line per non-zero count, then notes
+func writeSummary(w io.Writer, s Summary) {
+	fmt.Fprintln(w)
+	if s.DryRun {
+		fmt.Fprintf(w, "%s\n", bold("[DRY-RUN] Summary"))
+		if s.Cloned > 0 {
+			fmt.Fprintf(w, "  %s Would clone:  %d\n", cyan("→"), s.Cloned)
+		}
+		if s.Updated > 0 {
+			fmt.Fprintf(w, "  %s Would update: %d\n", cyan("→"), s.Updated)
+		}
+	} else {
+		fmt.Fprintf(w, "%s\n", bold("Summary"))
+		if s.Cloned > 0 {
+			fmt.Fprintf(w, "  %s Cloned:  %d\n", green("✓"), s.Cloned)
+		}
+		if s.Updated > 0 {
+			fmt.Fprintf(w, "  %s Updated: %d\n", green("✓"), s.Updated)
+		}
+	}
+	if s.Unchanged > 0 {
+		fmt.Fprintf(w, "  %s Unchanged: %d (not updated since last run)\n", green("✓"), s.Unchanged)
+	}
+	if s.Fresh > 0 {
+		fmt.Fprintf(w, "  %s Fresh:   %d (fetched within --refresh-stale-only)\n", yellow("○"), s.Fresh)
+	}
+	if s.Skipped > 0 {
+		fmt.Fprintf(w, "  %s Skipped: %d (archived)\n", yellow("○"), s.Skipped)
+	}
+	if s.NowArchived > 0 {
+		fmt.Fprintf(w, "  %s Now archived: %d (clones marked %s)\n", yellow("○"), s.NowArchived, archivedMarker)
+	}
+	if s.Stale > 0 {
+		fmt.Fprintf(w, "  %s Stale:   %d\n", yellow("○"), s.Stale)
+	}
+	if s.TooLarge > 0 {
+		fmt.Fprintf(w, "  %s Too large: %d (over --max-size)\n", yellow("○"), s.TooLarge)
+	}
+	if s.Empty > 0 {
+		fmt.Fprintf(w, "  %s Empty:   %d (no commits yet)\n", yellow("○"), s.Empty)
+	}
+	if s.Failed > 0 {
+		fmt.Fprintf(w, "  %s Failed:  %d\n", red("✗"), s.Failed)
+	}
+	if s.Disappeared > 0 {
+		fmt.Fprintf(w, "  %s Disappeared: %d (removed upstream during the run)\n", yellow("○"), s.Disappeared)
+	}
+	if s.Aborted > 0 {
+		fmt.Fprintf(w, "  %s Aborted: %d (not run)\n", yellow("○"), s.Aborted)
+	}
+	if s.TimedOut > 0 {
+		fmt.Fprintf(w, "  %s Timed out: %d (cancelled or not started)\n", yellow("○"), s.TimedOut)
+	}
+	if s.Collisions > 0 {
+		fmt.Fprintf(w, "  %s Collisions: %d (repos sharing a local path)\n", yellow("!"), s.Collisions)
+	}
+	if s.RemotesUpdated > 0 {
+		fmt.Fprintf(w, "  %s Remotes updated: %d\n", yellow("!"), s.RemotesUpdated)
+	}
+	if s.Moved > 0 {
+		fmt.Fprintf(w, "  %s Moved:   %d (renamed or transferred upstream)\n", yellow("!"), s.Moved)
 	}
-	if updated > 0 {
-		fmt.Printf("  %s Updated: %d\n", green("✓"), updated)
+	if s.SubmodulesFailed > 0 {
+		fmt.Fprintf(w, "  %s Submodules failed: %d (repos mirrored without all submodules)\n", yellow("!"), s.SubmodulesFailed)
 	}
-	if skipped > 0 {
-		fmt.Printf("  %s Skipped: %d (archived)\n", yellow("○"), skipped)
+	if s.ReplicasCreated > 0 {
+		fmt.Fprintf(w, "  %s Replicas created: %d\n", green("✓"), s.ReplicasCreated)
 	}
-	if stale > 0 {
-		fmt.Printf("  %s Stale:   %d\n", yellow("○"), stale)
+	fmt.Fprintf(w, "  Total:   %d\n", s.Total)
+	printMemberCounts(w, s.Members)
+	if s.TimedOut > 0 {
+		fmt.Fprintf(w, "\n%s Run time-limited: %d repo(s) not finished before --max-runtime\n", yellow("!"), s.TimedOut)
 	}
-	if failed > 0 {
-		fmt.Printf("  %s Failed:  %d\n", red("✗"), failed)
+	if s.Aborted > 0 {
+		fmt.Fprintf(w, "\n%s Run aborted early after the first failure (--fail-fast)\n", red("✗"))
 	}
-	fmt.Printf("  Total:   %d\n", len(results))
 }
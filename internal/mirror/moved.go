@@ -0,0 +1,184 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// localClone is an existing clone under BaseDir
+type localClone struct {
+	relPath string
+	origin  string
+}
+
+// relocateMoved moves local clones of repos that were renamed or transferred upstream.
+// Clones that are not part of the plan are looked up by their origin path; providers
+// redirect old paths to the repo's canonical path, so a different FullPath means the
+// repo moved. listed holds every repo from the listing (including archived/stale ones),
+// whose clones are never looked up. Returns the previous relative path of each moved
+// repo, keyed by FullPath.
+func (m *Mirror) relocateMoved(ctx context.Context, plan []plannedRepo, listed []provider.Repository) map[string]string {
+	moved := make(map[string]string)
+
+	known := make(map[string]bool, len(listed))
+	for _, repo := range listed {
+		known[strings.ToLower(repo.FullPath)] = true
+	}
+
+	planned := make(map[string]bool, len(plan)) // collision keys of paths about to sync
+	hosts := make(map[string]bool)
+	for _, pr := range plan {
+		if pr.result != nil {
+			continue
+		}
+		planned[collisionKey(pr.relPath)] = true
+		for _, u := range []string{pr.repo.CloneURL, pr.repo.SSHUrl} {
+			if host, _, ok := parseRemote(u); ok {
+				hosts[host] = true
+			}
+		}
+	}
+
+	for _, clone := range m.findLocalClones(ctx) {
+		if planned[collisionKey(clone.relPath)] {
+			continue
+		}
+
+		host, oldPath, ok := parseRemote(clone.origin)
+		if !ok || !hosts[host] || known[strings.ToLower(oldPath)] {
+			continue
+		}
+
+		repo, err := m.provider.GetProject(ctx, oldPath)
+		if err != nil || repo == nil {
+			if m.options.Verbose {
+				fmt.Printf("  %s %s: could not resolve %s\n", faint("○"), clone.relPath, oldPath)
+			}
+			continue
+		}
+		if strings.EqualFold(repo.FullPath, oldPath) {
+			continue
+		}
+
+		// Only relocate into a path this run is about to sync
+		var target *plannedRepo
+		for i := range plan {
+			if plan[i].result == nil && strings.EqualFold(plan[i].repo.FullPath, repo.FullPath) {
+				target = &plan[i]
+				break
+			}
+		}
+		if target == nil {
+			continue
+		}
+
+		src := filepath.Join(m.options.BaseDir, clone.relPath)
+		dst := filepath.Join(m.options.BaseDir, target.relPath)
+		if _, err := os.Stat(dst); err == nil {
+			fmt.Printf("  %s %s moved to %s, but %s already exists\n", yellow("!"), oldPath, repo.FullPath, target.relPath)
+			continue
+		}
+		if err := m.mkdirAll(filepath.Dir(dst)); err != nil {
+			fmt.Printf("  %s %s moved to %s, but failed to create directory: %v\n", yellow("!"), oldPath, repo.FullPath, err)
+			continue
+		}
+		if err := os.Rename(src, dst); err != nil {
+			fmt.Printf("  %s %s moved to %s, but failed to move clone: %v\n", yellow("!"), oldPath, repo.FullPath, err)
+			continue
+		}
+
+		// Keep the protocol the clone was made with
+		newURL := target.repo.CloneURL
+		if !strings.HasPrefix(clone.origin, "http") && target.repo.SSHUrl != "" {
+			newURL = target.repo.SSHUrl
+		}
+		if newURL != "" {
+			setCmd := m.gitCommand(ctx, "-C", dst, "remote", "set-url", "origin", newURL)
+			_ = m.run(setCmd) // Old URLs keep working through the provider's redirect
+		}
+
+		fmt.Printf("  %s %s → %s %s\n", yellow("➜"), clone.relPath, target.relPath, faint("(moved upstream)"))
+		moved[target.repo.FullPath] = clone.relPath
+	}
+
+	if len(moved) > 0 {
+		fmt.Println()
+	}
+	return moved
+}
+
+// findLocalClones lists git repos under BaseDir with their origin URL
+func (m *Mirror) findLocalClones(ctx context.Context) []localClone {
+	var clones []localClone
+	_ = filepath.WalkDir(m.options.BaseDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != m.options.BaseDir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		isRepo := isGitRepo(path)
+		if m.options.Bare {
+			isRepo = isBareRepo(path)
+		}
+		if !isRepo {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(m.options.BaseDir, path)
+		if err != nil || relPath == "." {
+			return nil
+		}
+
+		output, err := m.output(m.gitCommand(ctx, "-C", path, "remote", "get-url", "origin"))
+		if err == nil {
+			clones = append(clones, localClone{
+				relPath: filepath.ToSlash(relPath),
+				origin:  strings.TrimSpace(string(output)),
+			})
+		}
+		// Don't descend into repos (submodules, release assets)
+		return filepath.SkipDir
+	})
+	return clones
+}
+
+// parseRemote extracts the host and repository path from an HTTPS, ssh:// or
+// scp-style (git@host:group/repo.git) remote URL
+func parseRemote(remote string) (host, repoPath string, ok bool) {
+	if remote == "" {
+		return "", "", false
+	}
+
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil || u.Host == "" {
+			return "", "", false
+		}
+		host, repoPath = u.Hostname(), u.Path
+	} else {
+		userHost, p, found := strings.Cut(remote, ":")
+		if !found {
+			return "", "", false
+		}
+		_, host, _ = strings.Cut(userHost, "@")
+		if host == "" {
+			host = userHost
+		}
+		repoPath = p
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	if host == "" || !strings.Contains(repoPath, "/") {
+		return "", "", false
+	}
+	return strings.ToLower(host), repoPath, true
+}
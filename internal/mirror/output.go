@@ -0,0 +1,271 @@
+package mirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Summary holds per-action counts and totals for a mirror run
+type Summary struct {
+	Cloned  int `json:"cloned"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+	Stale   int `json:"stale"`
+	Failed  int `json:"failed"`
+	Aborted int `json:"aborted"`
+	Total   int `json:"total"`
+
+	TimedOut int `json:"timed_out"` // Repos cancelled or not started when the Deadline passed
+
+	Collisions     int `json:"collisions"`      // Repos whose local path collided (any action)
+	RemotesUpdated int `json:"remotes_updated"` // Repos whose origin URL was changed
+	Moved          int `json:"moved"`           // Clones moved after an upstream rename
+	Unchanged      int `json:"unchanged"`       // Existing clones not updated since the last run
+	Fresh          int `json:"fresh"`           // Existing clones fetched within RefreshStaleOnly, not updated
+	TooLarge       int `json:"too_large"`       // Repos over MaxSize, not cloned or updated
+	NowArchived    int `json:"now_archived"`    // Existing clones of repos archived upstream, not updated
+	Empty          int `json:"empty"`           // Repos with no commits yet (cloned, or skipped with ExcludeSizeZero)
+	Disappeared    int `json:"disappeared"`     // Repos deleted or hidden upstream after they were listed
+
+	SubmodulesFailed int `json:"submodules_failed"` // Mirrored repos whose submodules failed to update
+	ReplicasCreated  int `json:"replicas_created"`  // Destination repos created by a replicate run
+
+	Bytes    int64         `json:"bytes"`       // Provider-reported size of cloned and updated repos (0 if not listed)
+	Duration time.Duration `json:"duration_ns"` // Time spent cloning and updating, summed over repos
+
+	Members map[string]int `json:"members,omitempty"` // Member-owned repos per member
+
+	DryRun bool `json:"dry_run,omitempty"` // Counts are planned actions (Options.DryRun)
+}
+
+// Summarize counts results by action and totals the size and time of synced repos
+func Summarize(results []Result) Summary {
+	s := Summary{Total: len(results)}
+	if counts := memberCounts(results); len(counts) > 0 {
+		s.Members = counts
+	}
+	for _, r := range results {
+		switch r.Action {
+		case "cloned":
+			s.Cloned++
+			s.Bytes += r.Repository.Size
+		case "updated":
+			s.Updated++
+			s.Bytes += r.Repository.Size
+		case "unchanged":
+			s.Unchanged++
+		case "fresh":
+			s.Fresh++
+		case "skipped":
+			s.Skipped++
+		case "stale":
+			s.Stale++
+		case "too-large":
+			s.TooLarge++
+		case "now-archived":
+			s.NowArchived++
+		case "empty":
+			s.Empty++
+		case "failed":
+			s.Failed++
+		case "disappeared":
+			s.Disappeared++
+		case "aborted":
+			s.Aborted++
+		case "timed-out":
+			s.TimedOut++
+		}
+		s.Duration += r.Duration
+		if r.Collision {
+			s.Collisions++
+		}
+		if r.RemoteUpdated {
+			s.RemotesUpdated++
+		}
+		if r.MovedFrom != "" {
+			s.Moved++
+		}
+		if r.SubmoduleError != nil {
+			s.SubmodulesFailed++
+		}
+		if r.ReplicaCreated {
+			s.ReplicasCreated++
+		}
+		if r.DryRun {
+			s.DryRun = true
+		}
+	}
+	return s
+}
+
+// CompactSummary formats s as a single line of key=value counts for dashboards and logs,
+// e.g. "cloned=90 updated=50 skipped=12 stale=8 failed=3 total=163 in 4m12s". The common
+// counts are always included; the others only when non-zero. elapsed is omitted if 0.
+func CompactSummary(s Summary, elapsed time.Duration) string {
+	counts := []struct {
+		key    string
+		n      int
+		always bool
+	}{
+		{"cloned", s.Cloned, true},
+		{"updated", s.Updated, true},
+		{"unchanged", s.Unchanged, false},
+		{"fresh", s.Fresh, false},
+		{"skipped", s.Skipped, true},
+		{"now_archived", s.NowArchived, false},
+		{"stale", s.Stale, true},
+		{"too_large", s.TooLarge, false},
+		{"empty", s.Empty, false},
+		{"failed", s.Failed, true},
+		{"disappeared", s.Disappeared, false},
+		{"aborted", s.Aborted, false},
+		{"timed_out", s.TimedOut, false},
+		{"total", s.Total, true},
+	}
+	var parts []string
+	for _, c := range counts {
+		if c.always || c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", c.key, c.n))
+		}
+	}
+	line := strings.Join(parts, " ")
+	if s.DryRun {
+		line = "[DRY-RUN] " + line
+	}
+	if elapsed > 0 {
+		line += " in " + elapsed.Round(time.Second).String()
+	}
+	return line
+}
+
+// PrintAnnotations writes GitHub Actions workflow commands for the results:
+// ::error for failed repos, ::warning for stale, disappeared, aborted or timed-out repos and failed submodules, and a ::notice summary
+func PrintAnnotations(w io.Writer, results []Result) {
+	for _, r := range results {
+		path := r.Repository.FullPath
+		switch r.Action {
+		case "failed":
+			annotate(w, "error", path, "Mirror failed", r.Error.Error())
+		case "stale":
+			annotate(w, "warning", path, "Stale repo", "Not updated since "+r.Repository.LastUpdated.Format("2006-01-02"))
+		case "disappeared":
+			annotate(w, "warning", path, "Disappeared", "Deleted or hidden upstream since it was listed")
+		case "aborted":
+			annotate(w, "warning", path, "Not run", "Run aborted after an earlier failure")
+		case "timed-out":
+			annotate(w, "warning", path, "Timed out", "Run time limit reached before this repo finished")
+		case "collision":
+			annotate(w, "warning", path, "Path collision", r.Error.Error())
+		}
+		if r.SubmoduleError != nil {
+			annotate(w, "warning", path, "Submodules failed", r.SubmoduleError.Error())
+		}
+	}
+
+	s := Summarize(results)
+	fmt.Fprintf(w, "::notice title=ztigit mirror::%s\n", escapeData(fmt.Sprintf(
+		"cloned=%d updated=%d skipped=%d stale=%d failed=%d aborted=%d total=%d",
+		s.Cloned, s.Updated, s.Skipped, s.Stale, s.Failed, s.Aborted, s.Total)))
+}
+
+// annotate writes one workflow command about a repo. The repo path goes in file, so the
+// Actions UI shows which repo each annotation is about; kind leads the message.
+func annotate(w io.Writer, level, path, kind, message string) {
+	fmt.Fprintf(w, "::%s file=%s::%s\n", level, escapeProperty(path), escapeData(kind+": "+message))
+}
+
+// WriteLockfile writes the commit captured for each cloned or updated repo as
+// a JSON object of full path to commit SHA, sorted by path
+func WriteLockfile(path string, results []Result) error {
+	commits := make(map[string]string)
+	for _, r := range results {
+		if r.Commit != "" {
+			commits[r.Repository.FullPath] = r.Commit
+		}
+	}
+
+	data, err := json.MarshalIndent(commits, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
+
+// RunSummary is a self-contained record of a mirror run, as written by WriteRunSummary
+type RunSummary struct {
+	Started  time.Time    `json:"started"`
+	Finished time.Time    `json:"finished"`
+	Args     []string     `json:"args"` // Command-line arguments, credentials redacted
+	Summary  Summary      `json:"summary"`
+	Results  []RepoResult `json:"results,omitempty"` // Per-repo results, if requested
+}
+
+// RepoResult is the outcome of one repo in a RunSummary
+type RepoResult struct {
+	Repo       string `json:"repo"` // Full path
+	Action     string `json:"action"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Commit     string `json:"commit,omitempty"`
+}
+
+// WriteRunSummary atomically writes the RunSummary of a run that started at started
+// with the given command-line arguments, as indented JSON. With perRepo, the result
+// of every repo is included too.
+func WriteRunSummary(path string, started time.Time, args []string, results []Result, perRepo bool) error {
+	run := RunSummary{
+		Started:  started,
+		Finished: time.Now(),
+		Args:     make([]string, len(args)),
+		Summary:  Summarize(results),
+	}
+	for i, arg := range args {
+		run.Args[i] = redactCredentials(arg)
+	}
+	if perRepo {
+		run.Results = make([]RepoResult, len(results))
+		for i, r := range results {
+			run.Results[i] = RepoResult{
+				Repo:       r.Repository.FullPath,
+				Action:     r.Action,
+				DurationMs: r.Duration.Milliseconds(),
+				Commit:     r.Commit,
+			}
+			if r.Error != nil {
+				run.Results[i].Error = r.Error.Error()
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write summary file: %w", err)
+	}
+	return nil
+}
+
+// escapeData escapes a workflow command message
+func escapeData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	s = strings.ReplaceAll(s, "\n", "%0A")
+	return s
+}
+
+// escapeProperty escapes a workflow command property value
+func escapeProperty(s string) string {
+	s = escapeData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	s = strings.ReplaceAll(s, ",", "%2C")
+	return s
+}
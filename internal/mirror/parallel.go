@@ -0,0 +1,40 @@
+package mirror
+
+import "context"
+
+// Limits for --parallel auto, so a large machine does not trip server rate limits
+const (
+	minAutoClones  = 4
+	maxAutoClones  = 32
+	minAutoUpdates = 2
+	maxAutoUpdates = 16
+)
+
+// AutoParallel sizes clone and update concurrency from the CPU count. Updates of
+// existing clones are mostly local git work (small fetches, checkout, merge), so
+// one runs per CPU. New clones spend most of their time waiting on the network, so
+// twice as many run at once.
+func AutoParallel(cpus int) (clone, update int) {
+	clone = min(max(2*cpus, minAutoClones), maxAutoClones)
+	update = min(max(cpus, minAutoUpdates), maxAutoUpdates)
+	return clone, update
+}
+
+// slot waits for a free clone or update slot (CloneParallel, UpdateParallel) and
+// returns the function that frees it
+func (m *Mirror) slot(ctx context.Context, update bool) (func(), error) {
+	slots := m.cloneSlots
+	if update {
+		slots = m.updateSlots
+	}
+	if slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
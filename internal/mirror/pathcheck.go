@@ -0,0 +1,83 @@
+package mirror
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// windowsMaxPath is the longest absolute path Windows accepts without long path support
+const windowsMaxPath = 259
+
+// PathIssue describes a repo whose local destination would fail or is risky
+type PathIssue struct {
+	Repository provider.Repository
+	Dir        string // Absolute destination directory (empty if it could not be computed)
+	Error      error
+	Warning    bool // Valid on this platform, but exceeds Windows MAX_PATH
+}
+
+// CheckPaths computes the destination of every repo that would be mirrored and
+// validates it without cloning. Paths longer than Windows MAX_PATH are reported
+// as warnings on other platforms, so mirrors meant for Windows can be checked anywhere.
+// Returns the issues and the number of repos checked (archived/stale repos are excluded).
+func (m *Mirror) CheckPaths(repos []provider.Repository) ([]PathIssue, int) {
+	active, _ := m.filterRepos(repos)
+
+	var issues []PathIssue
+	for _, pr := range m.planLayout(active) {
+		if pr.result != nil {
+			issues = append(issues, PathIssue{
+				Repository: pr.repo,
+				Error:      pr.result.Error,
+			})
+			continue
+		}
+
+		dir := filepath.Join(m.options.BaseDir, pr.relPath)
+		if err := validateFullPathLength(dir); err != nil {
+			issues = append(issues, PathIssue{
+				Repository: pr.repo,
+				Dir:        dir,
+				Error:      err,
+			})
+			continue
+		}
+
+		// validateFullPathLength already enforces this on Windows
+		if len(dir) > windowsMaxPath {
+			issues = append(issues, PathIssue{
+				Repository: pr.repo,
+				Dir:        dir,
+				Error:      fmt.Errorf("absolute path length %d exceeds Windows MAX_PATH limit of %d", len(dir), windowsMaxPath),
+				Warning:    true,
+			})
+		}
+	}
+
+	return issues, len(active)
+}
+
+// PrintPathIssues prints the path check report and returns the number of errors
+func PrintPathIssues(issues []PathIssue, checked int) int {
+	errs := 0
+	for _, issue := range issues {
+		if issue.Warning {
+			fmt.Printf("  %s %s %s\n", yellow("!"), issue.Repository.FullPath, faint(issue.Error.Error()))
+			continue
+		}
+		errs++
+		fmt.Printf("  %s %s %s\n", red("✗"), issue.Repository.FullPath, faint(issue.Error.Error()))
+	}
+
+	fmt.Println()
+	if len(issues) == 0 {
+		fmt.Printf("%s All %d repo paths are within limits\n", green("✓"), checked)
+		return 0
+	}
+
+	fmt.Printf("%s %d of %d repo paths have problems (%d would fail here)\n", yellow("!"), len(issues), checked, errs)
+	fmt.Printf("  Shorten paths with a shorter --dir (e.g., C:\\src), --flatten, or --strip-prefix\n")
+	return errs
+}
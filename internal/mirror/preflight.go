@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os/exec"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,6 +24,41 @@ func CheckGitInstalled() error {
 	return nil
 }
 
+// GitAtLeast reports whether the installed git is at least major.minor
+func GitAtLeast(major, minor int) (bool, error) {
+	out, err := exec.Command("git", "--version").Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to get git version: %w", err)
+	}
+	gotMajor, gotMinor, err := parseGitVersion(string(out))
+	if err != nil {
+		return false, err
+	}
+	return gotMajor > major || (gotMajor == major && gotMinor >= minor), nil
+}
+
+// parseGitVersion extracts major and minor from `git --version` output such as
+// "git version 2.39.5", "git version 2.39.5.windows.1", or "git version 2.39.5 (Apple Git-143)"
+func parseGitVersion(s string) (int, int, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 3 || fields[0] != "git" || fields[1] != "version" {
+		return 0, 0, fmt.Errorf("unexpected git version output: %q", strings.TrimSpace(s))
+	}
+	parts := strings.SplitN(fields[2], ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("unexpected git version: %q", fields[2])
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected git version: %q", fields[2])
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected git version: %q", fields[2])
+	}
+	return major, minor, nil
+}
+
 // gitNotFoundMessage returns platform-specific installation instructions
 func gitNotFoundMessage() string {
 	var instructions string
@@ -85,6 +121,22 @@ type PreflightResult struct {
 	Error      error
 }
 
+// hasCloneURL reports whether git can reach a repo: it was listed with an HTTPS or
+// SSH clone URL
+func hasCloneURL(repo provider.Repository) bool {
+	return repo.CloneURL != "" || repo.SSHUrl != ""
+}
+
+// firstCloneable returns the first repo with a clone URL, for the credential check
+func firstCloneable(repos []provider.Repository) (provider.Repository, bool) {
+	for _, repo := range repos {
+		if hasCloneURL(repo) {
+			return repo, true
+		}
+	}
+	return provider.Repository{}, false
+}
+
 // credentialMethod represents a git credential method to test
 type credentialMethod struct {
 	name string // "ssh" or "https"
@@ -94,16 +146,15 @@ type credentialMethod struct {
 // Preflight checks git credentials before starting clone operations
 // Returns the preferred method (https or ssh) that works, or an error if neither works
 func (m *Mirror) Preflight(ctx context.Context, repos []provider.Repository) (*PreflightResult, error) {
-	if len(repos) == 0 {
+	// Test with the first repo that has a clone URL; with none there is nothing to test
+	testRepo, ok := firstCloneable(repos)
+	if !ok {
 		if m.options.SSH {
 			return &PreflightResult{Method: "ssh"}, nil
 		}
 		return &PreflightResult{Method: "https"}, nil
 	}
 
-	// Use first repo for testing
-	testRepo := repos[0]
-
 	// Build ordered list of methods to test
 	var methods []credentialMethod
 	if m.options.SSH {
@@ -182,7 +233,7 @@ func (m *Mirror) testCredentials(ctx context.Context, url string) bool {
 	// Use git ls-remote to test credentials without cloning
 	// --exit-code returns non-zero if no refs found (but auth succeeded)
 	// We just care about whether auth works, not if refs exist
-	cmd := exec.CommandContext(timeoutCtx, "git", "ls-remote", "--quiet", url)
+	cmd := m.gitCommand(timeoutCtx, "ls-remote", "--quiet", url)
 
 	// Suppress output
 	cmd.Stdout = nil
@@ -191,7 +242,7 @@ func (m *Mirror) testCredentials(ctx context.Context, url string) bool {
 	// Set GIT_TERMINAL_PROMPT=0 to prevent git from prompting for credentials
 	cmd.Env = append(cmd.Environ(), "GIT_TERMINAL_PROMPT=0")
 
-	err := cmd.Run()
+	err := m.run(cmd)
 	return err == nil
 }
 
@@ -0,0 +1,147 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// releasesDirName is the directory inside each clone that holds downloaded release assets
+const releasesDirName = ".ztigit-releases"
+
+// mirrorReleases downloads release assets into <repoDir>/.ztigit-releases/<tag>/
+// Assets already present with the expected size are skipped.
+// Returns the number of assets downloaded.
+func (m *Mirror) mirrorReleases(ctx context.Context, repo provider.Repository, repoDir string) (int, error) {
+	releases, err := m.provider.ListReleases(ctx, repo.FullPath)
+	if err != nil {
+		return 0, err
+	}
+	if len(releases) == 0 {
+		return 0, nil
+	}
+
+	// Keep downloaded assets out of git status so updates don't see local changes
+	if err := excludeFromGit(repoDir, releasesDirName+"/"); err != nil {
+		return 0, err
+	}
+
+	downloaded := 0
+	for _, release := range releases {
+		if err := validateAssetName(release.TagName); err != nil {
+			return downloaded, fmt.Errorf("release tag %q: %w", release.TagName, err)
+		}
+		tagDir := filepath.Join(repoDir, releasesDirName, release.TagName)
+
+		for _, asset := range release.Assets {
+			if err := validateAssetName(asset.Name); err != nil {
+				return downloaded, fmt.Errorf("asset %q: %w", asset.Name, err)
+			}
+			dest := filepath.Join(tagDir, asset.Name)
+			if assetExists(dest, asset.Size) {
+				continue
+			}
+
+			if m.options.Verbose {
+				fmt.Printf("    %s %s/%s\n", cyan("↓"), release.TagName, asset.Name)
+			}
+			if err := m.downloadAsset(ctx, repo, asset, dest); err != nil {
+				return downloaded, err
+			}
+			downloaded++
+		}
+	}
+
+	return downloaded, nil
+}
+
+// downloadAsset downloads a single asset to dest via a temporary file
+func (m *Mirror) downloadAsset(ctx context.Context, repo provider.Repository, asset provider.ReleaseAsset, dest string) error {
+	if err := m.mkdirAll(filepath.Dir(dest)); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+asset.Name+".*.part")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // No-op after a successful rename
+
+	if err := m.provider.DownloadReleaseAsset(ctx, repo.FullPath, asset, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write asset %s: %w", asset.Name, err)
+	}
+
+	if err := os.Rename(tmpName, dest); err != nil {
+		return fmt.Errorf("failed to save asset %s: %w", asset.Name, err)
+	}
+	return nil
+}
+
+// assetExists reports whether an asset was already downloaded.
+// When the provider reports no size, existence alone is enough.
+func assetExists(path string, size int64) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return size == 0 || info.Size() == size
+}
+
+// validateAssetName ensures a tag or asset name is a single safe path component
+func validateAssetName(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return fmt.Errorf("invalid name")
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("name contains a path separator")
+	}
+	for _, char := range getInvalidPathChars() {
+		if strings.ContainsRune(name, char) {
+			return fmt.Errorf("name contains invalid character: %q", char)
+		}
+	}
+	return nil
+}
+
+// excludeFromGit adds a pattern to the repository's .git/info/exclude if missing
+func excludeFromGit(repoDir, pattern string) error {
+	infoDir := filepath.Join(repoDir, ".git", "info")
+	excludeFile := filepath.Join(infoDir, "exclude")
+
+	existing, err := os.ReadFile(excludeFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", excludeFile, err)
+	}
+	for _, line := range strings.Split(string(existing), "\n") {
+		if strings.TrimSpace(line) == pattern {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(infoDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.OpenFile(excludeFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", excludeFile, err)
+	}
+	defer f.Close()
+
+	prefix := ""
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		prefix = "\n"
+	}
+	if _, err := f.WriteString(prefix + pattern + "\n"); err != nil {
+		return fmt.Errorf("failed to write %s: %w", excludeFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package mirror
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// Replica configures pushing every synced bare mirror to a destination provider
+type Replica struct {
+	Provider  provider.Provider
+	Namespace string // Org or group the replicas live in
+	Token     string // Destination token, sent with HTTPS pushes (empty = git's own credentials)
+}
+
+// errReplicaCollision is returned for a repo whose replica name another repo of the run
+// already uses, since pushing both with --prune would erase the first replica
+var errReplicaCollision = errors.New("replica name collision")
+
+// replicaRefspecs are pushed to replicas. Provider-internal refs such as refs/pull/*
+// (GitHub) or refs/merge-requests/* (GitLab) are rejected by the destination, so
+// only branches and tags are replicated.
+var replicaRefspecs = []string{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"}
+
+// ReplicaName returns the name of the destination repo for a source repo: its path
+// below the source group or org, with subgroups joined by "-" since not every
+// destination can nest them (e.g. acme/platform/api becomes platform-api)
+func ReplicaName(repo provider.Repository) string {
+	segments := strings.Split(repo.FullPath, "/")
+	if len(segments) > 1 {
+		segments = segments[1:]
+	}
+	return strings.Join(segments, "-")
+}
+
+// replicate pushes the branches and tags of the bare mirror in repoDir to the
+// destination repo, creating it first if it does not exist. Branches and tags
+// deleted at the source are deleted at the destination. Returns the destination
+// repo and whether it was created.
+func (m *Mirror) replicate(ctx context.Context, repo provider.Repository, repoDir string) (*provider.Repository, bool, error) {
+	dest := m.options.Replicate
+	destPath := dest.Namespace + "/" + ReplicaName(repo)
+	if err := m.claimReplica(repo); err != nil {
+		return nil, false, err
+	}
+
+	created := false
+	target, err := dest.Provider.GetProject(ctx, destPath)
+	if err != nil {
+		// Anything but a missing repo (an expired token, a server error) must not
+		// lead to creating a second one
+		if !provider.IsNotFound(err) {
+			return nil, false, err
+		}
+		replica := repo
+		replica.Name = ReplicaName(repo)
+		target, err = dest.Provider.CreateRepository(ctx, dest.Namespace, replica)
+		if err != nil {
+			return nil, false, err
+		}
+		created = true
+	}
+
+	// An empty repo has nothing to push, and git fails on refspecs that match nothing
+	refs, err := m.output(m.gitCommand(ctx, "-C", repoDir, "for-each-ref", "--count=1", "refs/heads/", "refs/tags/"))
+	if err != nil {
+		return target, created, fmt.Errorf("failed to list refs: %w", err)
+	}
+	if len(refs) == 0 {
+		return target, created, nil
+	}
+
+	pushURL := target.CloneURL
+	if m.useSSH(*target) && target.SSHUrl != "" {
+		pushURL = target.SSHUrl
+	}
+
+	args := append([]string{"-C", repoDir, "push", "--prune", pushURL}, replicaRefspecs...)
+	cmd := m.gitCommand(ctx, args...)
+	if dest.Token != "" && strings.HasPrefix(pushURL, "https://") {
+		// Passed in the environment, so the token is never in the command line or the logs
+		cmd.Env = withGitConfig(cmd.Environ(), []GitConfig{{Key: "http.extraHeader", Value: pushAuthHeader(dest.Provider.Name(), dest.Token)}})
+	}
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	if m.options.Verbose {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+	if err := m.run(cmd); err != nil {
+		return target, created, fmt.Errorf("git push to %s failed: %w", target.FullPath, err)
+	}
+	return target, created, nil
+}
+
+// claimReplica reserves the replica name of repo for this run. Names are flattened,
+// so e.g. acme/a-b/c and acme/a/b-c both map to a-b-c; the second repo fails instead
+// of overwriting the first one's replica.
+func (m *Mirror) claimReplica(repo provider.Repository) error {
+	name := strings.ToLower(ReplicaName(repo))
+
+	m.replicaMu.Lock()
+	defer m.replicaMu.Unlock()
+	if m.replicaNames == nil {
+		m.replicaNames = make(map[string]string)
+	}
+	if other, ok := m.replicaNames[name]; ok && other != repo.FullPath {
+		return fmt.Errorf("%w: %s and %s both replicate to %s/%s", errReplicaCollision, other, repo.FullPath,
+			m.options.Replicate.Namespace, ReplicaName(repo))
+	}
+	m.replicaNames[name] = repo.FullPath
+	return nil
+}
+
+// pushAuthHeader returns the HTTP Authorization header authenticating a git push with
+// a provider token. Git hosts take tokens as the password of basic auth, with a
+// provider-specific user name; Bitbucket tokens are already "user:app-password".
+func pushAuthHeader(providerName, token string) string {
+	credentials := token
+	switch providerName {
+	case string(provider.ProviderGitHub):
+		credentials = "x-access-token:" + token
+	case string(provider.ProviderGitLab):
+		credentials = "oauth2:" + token
+	default:
+		if !strings.Contains(token, ":") {
+			credentials = "x-token-auth:" + token
+		}
+	}
+	return "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+}
@@ -0,0 +1,82 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// staleLockAge is how old an index.lock must be before ResumePartial treats it as left
+// behind by a killed git process rather than held by one that is still running
+const staleLockAge = 10 * time.Minute
+
+// repairPartial fixes an existing clone left behind by an interrupted run. A stale
+// index.lock is removed. A clone with no HEAD commit, or a working tree clone with no
+// index, never finished and is deleted so it is cloned again. A clone with no refs at
+// all is kept if origin has none either: it is a complete clone of an empty repo.
+// A clone is only deleted when git ran and found no HEAD: if ctx is done (--max-runtime,
+// a fail-fast abort) or git cannot run, the clone is kept and the error returned.
+// Returns whether repoDir still holds a clone to update.
+func (m *Mirror) repairPartial(ctx context.Context, repo provider.Repository, repoDir string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return true, err
+	}
+
+	gitDir := repoDir
+	if !m.options.Bare {
+		gitDir = filepath.Join(repoDir, ".git")
+	}
+
+	lock := filepath.Join(gitDir, "index.lock")
+	if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > staleLockAge {
+		if err := os.Remove(lock); err != nil {
+			return true, fmt.Errorf("failed to remove stale %s: %w", lock, err)
+		}
+		fmt.Printf("  %s %s %s\n", yellow("!"), repo.FullPath, faint("(removed stale index.lock)"))
+	}
+
+	// An empty repo has no HEAD commit and no index either
+	if empty, err := m.isEmptyClone(ctx, repoDir); err == nil && empty && m.emptyUpstream(ctx, repoDir) {
+		return true, nil
+	}
+
+	complete := true
+	if _, err := m.headCommit(ctx, repoDir); err != nil {
+		// A git killed by the context exits non-zero too, so check the context first
+		if ctx.Err() != nil {
+			return true, ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return true, err
+		}
+		complete = false
+	} else if !m.options.Bare {
+		if _, err := os.Stat(filepath.Join(gitDir, "index")); err != nil {
+			complete = false // Killed before the checkout finished
+		}
+	}
+	if complete {
+		return true, nil
+	}
+
+	fmt.Printf("  %s %s %s\n", yellow("!"), repo.FullPath, faint("(interrupted clone, cloning again)"))
+	if err := os.RemoveAll(repoDir); err != nil {
+		return true, fmt.Errorf("failed to remove interrupted clone: %w", err)
+	}
+	return false, nil
+}
+
+// emptyUpstream reports whether origin of a clone has no refs, i.e. the repo has nothing
+// pushed yet. False if origin cannot be reached, so an unverifiable clone is treated
+// as interrupted.
+func (m *Mirror) emptyUpstream(ctx context.Context, repoDir string) bool {
+	output, err := m.output(m.gitCommand(ctx, "-C", repoDir, "ls-remote", "origin"))
+	return err == nil && len(output) == 0
+}
@@ -0,0 +1,95 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// sizeUnits are the suffixes accepted by ParseSize, longest first, in powers of 1024
+// to match how sizes are printed
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as "500MB", "1.5G", or "2048" (bytes)
+func ParseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	factor := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			factor = unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	// !(n > 0) also rejects NaN
+	if err != nil || !(n > 0) || math.IsInf(n, 1) {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 500MB or 2GB)", s)
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, so equal is already out of range
+	bytes := n * float64(factor)
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return int64(bytes), nil
+}
+
+// applySizes fills in Size for repos listed without one (GitLab without statistics
+// access), but only with ProbeSize and MaxSize or ExcludeSizeZero set, and only for
+// repos the other filters would keep. Repos whose size cannot be fetched stay unknown
+// and are kept by both MaxSize and ExcludeSizeZero.
+func (m *Mirror) applySizes(ctx context.Context, repos []provider.Repository) []provider.Repository {
+	if !m.options.ProbeSize || (m.options.MaxSize == 0 && !m.options.ExcludeSizeZero) {
+		return repos
+	}
+
+	updated := make([]provider.Repository, len(repos))
+	copy(updated, repos)
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, m.options.Parallel)
+	for i := range updated {
+		repo := &updated[i]
+		if repo.Size != 0 || repo.SizeKnown || !m.matchesNameFilters(*repo) {
+			continue
+		}
+		if len(m.options.Languages) > 0 && !matchesLanguage(*repo, m.options.Languages) {
+			continue
+		}
+		if m.options.SkipArchived && repo.Archived {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			size, err := m.provider.RepositorySize(ctx, repo.FullPath)
+			if err != nil {
+				if m.options.Verbose {
+					fmt.Fprintf(m.progress(), "  %s %s: size unknown: %v\n", yellow("!"), repo.FullPath, err)
+				}
+				return
+			}
+			repo.Size = size
+			repo.SizeKnown = true
+		}()
+	}
+	wg.Wait()
+
+	return updated
+}
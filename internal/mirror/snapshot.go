@@ -0,0 +1,120 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// snapshotLayout names snapshot directories; it sorts chronologically as a string
+const snapshotLayout = "2006-01-02-150405"
+
+// SnapshotDir returns the directory of a snapshot taken at t under baseDir
+func SnapshotDir(baseDir string, t time.Time) string {
+	return filepath.Join(baseDir, t.Format(snapshotLayout))
+}
+
+// ListSnapshots returns the snapshot directories under baseDir, oldest first.
+// Other entries in baseDir are ignored.
+func ListSnapshots(baseDir string) ([]string, error) {
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshots: %w", err)
+	}
+
+	var snapshots []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(snapshotLayout, entry.Name()); err != nil {
+			continue
+		}
+		snapshots = append(snapshots, filepath.Join(baseDir, entry.Name()))
+	}
+	sort.Strings(snapshots)
+	return snapshots, nil
+}
+
+// PruneSnapshots removes the oldest snapshots under baseDir so that at most keep remain.
+// Returns the removed directories.
+func PruneSnapshots(baseDir string, keep int) ([]string, error) {
+	snapshots, err := ListSnapshots(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) <= keep {
+		return nil, nil
+	}
+
+	var removed []string
+	for _, dir := range snapshots[:len(snapshots)-keep] {
+		if err := os.RemoveAll(dir); err != nil {
+			return removed, fmt.Errorf("failed to remove snapshot %s: %w", dir, err)
+		}
+		removed = append(removed, dir)
+	}
+	return removed, nil
+}
+
+// linkedClone returns the clone of relPath in the LinkDest snapshot, or "" if there is none
+func (m *Mirror) linkedClone(relPath string) string {
+	if m.options.LinkDest == "" {
+		return ""
+	}
+	prevDir := filepath.Join(m.options.LinkDest, relPath)
+	if m.options.Bare {
+		if isBareRepo(prevDir) {
+			return prevDir
+		}
+	} else if isGitRepo(prevDir) {
+		return prevDir
+	}
+	return ""
+}
+
+// linkClone creates repoDir as a local clone of prevDir, which hardlinks the existing
+// objects instead of copying them, then points origin back at the remote and updates
+// so only what changed upstream is fetched
+func (m *Mirror) linkClone(ctx context.Context, repo provider.Repository, prevDir, repoDir string) error {
+	if err := m.mkdirAll(filepath.Dir(repoDir)); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	originCmd := m.gitCommand(ctx, "-C", prevDir, "remote", "get-url", "origin")
+	origin, err := m.output(originCmd)
+	if err != nil {
+		return fmt.Errorf("failed to read origin of previous snapshot: %w", err)
+	}
+
+	args := []string{"clone", "--local"}
+	if m.options.Bare {
+		args = append(args, "--mirror")
+	}
+	args = append(args, prevDir, repoDir)
+	if err := m.run(m.gitCommand(ctx, args...)); err != nil {
+		return fmt.Errorf("git clone from previous snapshot failed: %w", err)
+	}
+	if err := m.applyDirMode(repoDir); err != nil {
+		return err
+	}
+
+	setURLCmd := m.gitCommand(ctx, "-C", repoDir, "remote", "set-url", "origin", strings.TrimSpace(string(origin)))
+	if err := m.run(setURLCmd); err != nil {
+		return fmt.Errorf("failed to set origin: %w", err)
+	}
+
+	if m.options.Bare {
+		return m.updateBareRepo(ctx, repoDir)
+	}
+	return m.updateRepo(ctx, repoDir, repo.DefaultBranch)
+}
@@ -0,0 +1,122 @@
+package mirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// stateFile is kept in the base directory, next to the clones it describes
+const stateFile = ".ztigit-state.json"
+
+// State records the last successful mirror run into a base directory, and the
+// progress of a run that has not finished successfully since
+type State struct {
+	LastRunStarted   time.Time   `json:"last_run_started"`
+	LastRunCompleted time.Time   `json:"last_run_completed"`
+	Checkpoint       *Checkpoint `json:"checkpoint,omitempty"`
+}
+
+// Checkpoint lists the repos an unfinished run had already synced
+type Checkpoint struct {
+	RunStarted time.Time `json:"run_started"`
+	Written    time.Time `json:"written"`
+	Synced     []string  `json:"synced"` // Full paths of repos cloned, updated, or unchanged
+}
+
+// NewCheckpoint records the repos in results that were synced by a run started at runStarted
+func NewCheckpoint(runStarted time.Time, results []Result) *Checkpoint {
+	c := &Checkpoint{RunStarted: runStarted, Written: time.Now(), Synced: []string{}}
+	for _, r := range results {
+		switch r.Action {
+		case "cloned", "updated", "unchanged", "empty":
+			c.Synced = append(c.Synced, r.Repository.FullPath)
+		}
+	}
+	sort.Strings(c.Synced)
+	return c
+}
+
+// SyncedSince returns the per-repo cutoffs for resuming from the checkpoint: every repo
+// it lists was up to date as of the start of the run that wrote it
+func (c *Checkpoint) SyncedSince() map[string]time.Time {
+	if c == nil {
+		return nil
+	}
+	since := make(map[string]time.Time, len(c.Synced))
+	for _, path := range c.Synced {
+		since[path] = c.RunStarted
+	}
+	return since
+}
+
+// StatePath returns the path of the state file for baseDir
+func StatePath(baseDir string) string {
+	return filepath.Join(baseDir, stateFile)
+}
+
+// LoadState reads the state file in baseDir. A missing file returns an empty State.
+func LoadState(baseDir string) (State, error) {
+	var state State
+	data, err := os.ReadFile(StatePath(baseDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, fmt.Errorf("failed to read state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse state %s: %w", StatePath(baseDir), err)
+	}
+	return state, nil
+}
+
+// SaveState atomically writes state to the state file in baseDir
+func SaveState(baseDir string, state State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := writeFileAtomic(StatePath(baseDir), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+// atomicTempSuffix names the temporary files of writeFileAtomic: <name>.<random>.tmp,
+// where os.CreateTemp replaces the * with random digits (see strayTempFile)
+const atomicTempSuffix = ".*.tmp"
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into
+// place, so a crash mid-write never leaves a truncated file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+atomicTempSuffix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Since returns the cutoff for an incremental run: the start of the last successful
+// run, so repos pushed to while it was in progress are picked up again.
+// Zero if no run has been recorded.
+func (s State) Since() time.Time {
+	if !s.LastRunStarted.IsZero() {
+		return s.LastRunStarted
+	}
+	return s.LastRunCompleted
+}
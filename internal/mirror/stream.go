@@ -0,0 +1,120 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// canStream reports whether repos can be mirrored while their group is still being
+// listed. Flatten and FollowRedirects need the full listing to plan local paths,
+// and member repos are only known after the group itself is listed.
+func (m *Mirror) canStream() bool {
+	return !m.options.Flatten && !m.options.FollowRedirects && !m.options.IncludeMembers
+}
+
+// streamGroups mirrors the repos of each group as the provider returns them, so the
+// first page starts cloning while later pages are still being fetched. If listing
+// fails part way, repos already sent finish, and their results are returned with the error.
+func (m *Mirror) streamGroups(ctx context.Context, groups []string) ([]Result, error) {
+	planned := make(chan plannedRepo, m.options.Parallel)
+	done := make(chan []Result, 1)
+	go func() {
+		done <- m.dispatch(ctx, planned)
+	}()
+
+	listErr := m.listStream(ctx, groups, planned)
+	close(planned)
+	results := <-done
+	return results, listErr
+}
+
+// listStream lists each group and sends its repos to planned as pages arrive.
+// Credentials are checked against the first repo with a clone URL before it is sent.
+func (m *Mirror) listStream(ctx context.Context, groups []string, planned chan<- plannedRepo) error {
+	checked := m.options.SkipPreflight
+	seen := make(map[string]bool)
+	var listed, matched int
+	var preflightErr error
+
+	for _, group := range groups {
+		fmt.Fprintf(m.progress(), "%s Fetching repos from %s...\n", cyan("→"), bold(group))
+
+		var count int
+		var totalSize int64
+		err := m.provider.ListGroupProjectsStream(ctx, group, func(repo provider.Repository) error {
+			count++
+			totalSize += repo.Size
+
+			// Groups may overlap (e.g. a group and one of its subgroups)
+			if seen[repo.FullPath] {
+				return nil
+			}
+			seen[repo.FullPath] = true
+			listed++
+
+			active, filtered := m.filterRepos(m.applyStrictAge(ctx, m.applySizes(ctx, m.applyLanguages(ctx, []provider.Repository{repo}))))
+			for _, r := range filtered {
+				matched++
+				planned <- plannedRepo{repo: r.Repository, result: &r}
+			}
+			for _, r := range active {
+				matched++
+				if !checked && hasCloneURL(r) {
+					if preflightErr = m.preflight(ctx, r); preflightErr != nil {
+						return preflightErr
+					}
+					checked = true
+				}
+				planned <- m.planRepo(r)
+			}
+			return nil
+		})
+		if preflightErr != nil {
+			return preflightErr
+		}
+		if err != nil {
+			return err // Providers name the group in their listing errors
+		}
+
+		fmt.Fprintf(m.progress(), "%s Found %s repos %s\n\n", cyan("→"), bold(fmt.Sprintf("%d", count)), faint("("+formatSize(totalSize)+")"))
+	}
+
+	if filter := m.nameFilter(); filter != "" {
+		fmt.Fprintf(m.progress(), "%s %s of %d repos match %s\n\n", cyan("→"), bold(fmt.Sprintf("%d", matched)), listed, filter)
+	}
+	return nil
+}
+
+// planRepo resolves the local path of a single repo. Without Flatten, distinct
+// repos never share a path, so no collision policy is needed.
+func (m *Mirror) planRepo(repo provider.Repository) plannedRepo {
+	relPath, err := m.localPath(repo)
+	if err != nil {
+		return plannedRepo{repo: repo, result: &Result{
+			Repository: repo,
+			Action:     "failed",
+			Error:      err,
+		}}
+	}
+	return plannedRepo{repo: repo, relPath: relPath}
+}
+
+// preflight checks git credentials against repo, switching to SSH if only SSH works
+func (m *Mirror) preflight(ctx context.Context, repo provider.Repository) error {
+	fmt.Printf("%s Checking git credentials...\n", cyan("→"))
+	result, err := m.Preflight(ctx, []provider.Repository{repo})
+	if err != nil {
+		return err
+	}
+	// Use the method that works - override SSH if needed
+	if result.Method == "ssh" && !m.options.SSH {
+		m.options.SSH = true
+		fmt.Printf("%s HTTPS unavailable, using SSH\n\n", green("✓"))
+	} else {
+		fmt.Printf("%s Git credentials OK (%s)\n\n", green("✓"), strings.ToUpper(result.Method))
+	}
+	return nil
+}
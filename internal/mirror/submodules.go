@@ -0,0 +1,88 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// updateSubmodules initializes and updates submodules recursively. Submodules on the
+// repo's own host are fetched with the same protocol as the repo, so they use the
+// credentials preflight found working rather than whatever .gitmodules names.
+func (m *Mirror) updateSubmodules(ctx context.Context, repo provider.Repository, dir string) error {
+	args := []string{"-C", dir, "submodule", "update", "--init", "--recursive"}
+	if m.options.FilterSubmodules && m.options.Filter != "" {
+		// Newly initialized submodules become partial clones too; the superproject is
+		// cloned without --recurse-submodules, so this stands in for --also-filter-submodules
+		args = append(args, "--filter="+m.options.Filter)
+	}
+	if m.options.SubmoduleJobs > 0 {
+		args = append(args, "--jobs", strconv.Itoa(m.options.SubmoduleJobs))
+	}
+
+	cmd := m.gitCommand(ctx, args...)
+	if rewrite := m.submoduleRewrite(repo); len(rewrite) > 0 {
+		cmd.Env = withGitConfig(cmd.Environ(), rewrite)
+	}
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+
+	if m.options.Verbose {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+
+	if err := m.run(cmd); err != nil {
+		return fmt.Errorf("submodule update failed: %w", err)
+	}
+
+	return nil
+}
+
+// submoduleRewrite returns url.<base>.insteadOf entries that point submodule URLs
+// for the repo's host at the protocol the repo is cloned with
+func (m *Mirror) submoduleRewrite(repo provider.Repository) []GitConfig {
+	httpsPrefix := httpsURLPrefix(repo.CloneURL)
+	sshPrefix := sshURLPrefix(repo.SSHUrl)
+	if httpsPrefix == "" || sshPrefix == "" {
+		return nil
+	}
+
+	if m.useSSH(repo) {
+		return []GitConfig{{Key: "url." + sshPrefix + ".insteadOf", Value: httpsPrefix}}
+	}
+	return []GitConfig{{Key: "url." + httpsPrefix + ".insteadOf", Value: sshPrefix}}
+}
+
+// httpsURLPrefix returns "https://host/" for an HTTPS clone URL
+func httpsURLPrefix(cloneURL string) string {
+	u, err := url.Parse(cloneURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + "/"
+}
+
+// sshURLPrefix returns "git@host:" for scp-style SSH URLs and "ssh://git@host/" for ssh:// URLs
+func sshURLPrefix(sshURL string) string {
+	if strings.HasPrefix(sshURL, "ssh://") {
+		u, err := url.Parse(sshURL)
+		if err != nil || u.Host == "" {
+			return ""
+		}
+		prefix := "ssh://"
+		if u.User != nil {
+			prefix += u.User.Username() + "@"
+		}
+		return prefix + u.Host + "/"
+	}
+	if host, _, ok := strings.Cut(sshURL, ":"); ok && strings.Contains(host, "@") {
+		return host + ":"
+	}
+	return ""
+}
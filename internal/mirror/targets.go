@@ -0,0 +1,114 @@
+package mirror
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// ReadTargets reads a list of repos to mirror, one per line, as full paths
+// (group/subgroup/repo) or clone URLs. Blank lines and lines starting with # are ignored.
+func ReadTargets(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read targets: %w", err)
+	}
+	defer f.Close()
+
+	var targets []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		target := targetPath(text)
+		if target == "" {
+			return nil, fmt.Errorf("%s:%d: not a repo path or clone URL: %q", file, line, text)
+		}
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read targets: %w", err)
+	}
+	return targets, nil
+}
+
+// targetPath returns the full path of a repo named by a full path, an HTTPS clone
+// URL (https://host/group/repo.git), or an SSH clone URL (git@host:group/repo.git)
+func targetPath(s string) string {
+	s = strings.TrimSuffix(s, ".git")
+	if i := strings.Index(s, "://"); i >= 0 {
+		rest := s[i+3:]
+		j := strings.Index(rest, "/")
+		if j < 0 {
+			return ""
+		}
+		s = rest[j+1:]
+	} else if at, colon := strings.Index(s, "@"), strings.Index(s, ":"); at >= 0 && colon > at {
+		s = s[colon+1:]
+	}
+	return strings.Trim(s, "/")
+}
+
+// TargetRepos looks up repos by full path, e.g. as read by ReadTargets. Repos that
+// cannot be looked up are returned as failed results so they can be retried.
+func (m *Mirror) TargetRepos(ctx context.Context, paths []string) ([]provider.Repository, []Result) {
+	fmt.Fprintf(m.progress(), "%s Looking up %s repos...\n", cyan("→"), bold(fmt.Sprintf("%d", len(paths))))
+
+	var repos []provider.Repository
+	var failed []Result
+	var totalSize int64
+	for _, p := range paths {
+		repo, err := m.provider.GetProject(ctx, p)
+		if err != nil {
+			failed = append(failed, Result{
+				Repository: provider.Repository{Name: path.Base(p), FullPath: p},
+				Action:     "failed",
+				Error:      fmt.Errorf("lookup failed: %w", err),
+			})
+			continue
+		}
+		repos = append(repos, *repo)
+		totalSize += repo.Size
+	}
+
+	fmt.Fprintf(m.progress(), "%s Found %s repos %s\n\n", cyan("→"), bold(fmt.Sprintf("%d", len(repos))), faint("("+formatSize(totalSize)+")"))
+	return repos, failed
+}
+
+// MirrorTargets mirrors repos by full path; repos that cannot be looked up are reported as failed
+func (m *Mirror) MirrorTargets(ctx context.Context, paths []string) ([]Result, error) {
+	repos, failed := m.TargetRepos(ctx, paths)
+	results, err := m.preflightAndMirror(ctx, repos)
+	if err != nil {
+		return nil, err
+	}
+	return append(failed, results...), nil
+}
+
+// WriteFailures writes the full path of every repo that failed, was aborted, or timed
+// out, one per line, for a later run with ReadTargets. The file is written even if no
+// repo failed, so an empty file means there is nothing left to retry.
+func WriteFailures(file string, results []Result) error {
+	var b strings.Builder
+	for _, r := range results {
+		switch r.Action {
+		case "failed", "aborted", "timed-out":
+			b.WriteString(r.Repository.FullPath + "\n")
+		}
+	}
+	if err := writeFileAtomic(file, []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to write failures file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package mirror
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// RepoTree is a group in the hierarchy of repos built by BuildRepoTree
+type RepoTree struct {
+	Name    string
+	Groups  []*RepoTree           // Subgroups, sorted by name
+	Repos   []provider.Repository // Repos directly in this group, sorted by name
+	Count   int                   // Repos in this group and all subgroups
+	Size    int64                 // Total size of those repos in bytes, of the known sizes
+	Unknown int                   // Those repos with no reported size, left out of Size
+}
+
+// BuildRepoTree arranges repos by the segments of their FullPath. The returned
+// root has no name; its groups are the top-level groups or orgs.
+func BuildRepoTree(repos []provider.Repository) *RepoTree {
+	root := &RepoTree{}
+	for _, repo := range repos {
+		segments := strings.Split(repo.FullPath, "/")
+		node := root
+		node.add(repo)
+		for _, name := range segments[:len(segments)-1] {
+			node = node.group(name)
+			node.add(repo)
+		}
+		node.Repos = append(node.Repos, repo)
+	}
+	root.sort()
+	return root
+}
+
+// add counts a repo in the totals of a group
+func (t *RepoTree) add(repo provider.Repository) {
+	t.Count++
+	if repo.SizeKnown {
+		t.Size += repo.Size
+	} else {
+		t.Unknown++
+	}
+}
+
+// group returns the subgroup with the given name, adding it if missing
+func (t *RepoTree) group(name string) *RepoTree {
+	for _, g := range t.Groups {
+		if g.Name == name {
+			return g
+		}
+	}
+	g := &RepoTree{Name: name}
+	t.Groups = append(t.Groups, g)
+	return g
+}
+
+// sort orders groups and repos by name at every level
+func (t *RepoTree) sort() {
+	sort.Slice(t.Groups, func(i, j int) bool { return t.Groups[i].Name < t.Groups[j].Name })
+	sort.Slice(t.Repos, func(i, j int) bool { return t.Repos[i].FullPath < t.Repos[j].FullPath })
+	for _, g := range t.Groups {
+		g.sort()
+	}
+}
+
+// PrintRepoTree prints the tree with repo counts and total sizes per group,
+// subgroups before repos
+func PrintRepoTree(root *RepoTree) {
+	for _, g := range root.Groups {
+		fmt.Printf("%s/ %s\n", bold(g.Name), faint(groupTotals(g)))
+		printTreeChildren(g, "")
+	}
+	for _, repo := range root.Repos {
+		fmt.Println(treeRepoLine(repo))
+	}
+
+	fmt.Println()
+	fmt.Printf("Total: %d repos, %s\n", root.Count, treeSize(root))
+}
+
+// printTreeChildren prints the subgroups and repos of a group below prefix
+func printTreeChildren(t *RepoTree, prefix string) {
+	total := len(t.Groups) + len(t.Repos)
+	i := 0
+	for _, g := range t.Groups {
+		i++
+		branch, next := treeBranch(i == total)
+		fmt.Printf("%s%s%s/ %s\n", prefix, branch, g.Name, faint(groupTotals(g)))
+		printTreeChildren(g, prefix+next)
+	}
+	for _, repo := range t.Repos {
+		i++
+		branch, _ := treeBranch(i == total)
+		fmt.Printf("%s%s%s\n", prefix, branch, treeRepoLine(repo))
+	}
+}
+
+// treeBranch returns the connector for an entry and the prefix for its children
+func treeBranch(last bool) (branch, next string) {
+	if last {
+		return "└── ", "    "
+	}
+	return "├── ", "│   "
+}
+
+// groupTotals describes the repos below a group, e.g. "(12 repos, 1.2 GB)"
+func groupTotals(t *RepoTree) string {
+	return fmt.Sprintf("(%d repos, %s)", t.Count, treeSize(t))
+}
+
+// treeSize describes the total size of a group, marking a total that leaves out repos
+// with no reported size, e.g. "1.2 GB + 3 unknown"
+func treeSize(t *RepoTree) string {
+	if t.Unknown == 0 {
+		return formatSize(t.Size)
+	}
+	if t.Unknown == t.Count {
+		return "size unknown"
+	}
+	return fmt.Sprintf("%s + %d unknown", formatSize(t.Size), t.Unknown)
+}
+
+// treeRepoLine describes a repo by the last segment of its path, size ("?" if not
+// reported), and archived state
+func treeRepoLine(repo provider.Repository) string {
+	note := "?"
+	if repo.SizeKnown {
+		note = formatSize(repo.Size)
+	}
+	if repo.Archived {
+		note += ", archived"
+	}
+	name := repo.FullPath[strings.LastIndex(repo.FullPath, "/")+1:]
+	return fmt.Sprintf("%s %s", name, faint("("+note+")"))
+}
@@ -5,8 +5,12 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/zsoftly/ztigit/internal/batch"
 	"github.com/zsoftly/ztigit/internal/provider"
 )
 
@@ -15,28 +19,81 @@ type Result struct {
 	Environment provider.Environment
 	Action      string // "protected", "skipped", "failed"
 	Error       error
+	TierGuessed bool // Selected by the Tier option with a tier guessed from its name
 }
 
+// GitLab access levels allowed to deploy to a protected environment
+const (
+	AccessLevelDeveloper  = 30
+	AccessLevelMaintainer = 40
+	AccessLevelAdmin      = 60
+)
+
+// maxWaitTimer is the longest GitHub wait timer in minutes (30 days)
+const maxWaitTimer = 43200
+
+// MaxReviewers is the most users or teams GitHub accepts as environment reviewers
+const MaxReviewers = 6
+
+// protectInterval is the least time between two protection requests of a run, however
+// many are sent at once, to stay clear of API rate limits
+const protectInterval = 500 * time.Millisecond
+
 // Options configures the protect operation
 type Options struct {
 	AccessLevel       int // 30=developer, 40=maintainer, 60=admin
 	RequiredApprovals int
 	DryRun            bool
+	Parallel          int    // Environments of a project protected at once (0 = 1)
+	Tier              string // Only environments of this deployment tier (see Tiers); empty = any
+
+	// GitHub only
+	WaitTimer      int      // Minutes to wait before a deployment proceeds
+	DeployBranches []string // Branch patterns allowed to deploy ("protected" = protected branches)
+	Reviewers      []provider.Reviewer
 }
 
 // DefaultOptions returns the default protect options
 func DefaultOptions() Options {
 	return Options{
-		AccessLevel:       30, // Developer
+		AccessLevel:       AccessLevelDeveloper,
 		RequiredApprovals: 1,
 		DryRun:            false,
+		Parallel:          1,
+	}
+}
+
+// Validate checks option values before any API call, so a typo fails up front
+// instead of partway through a run
+func (o Options) Validate() error {
+	switch o.AccessLevel {
+	case AccessLevelDeveloper, AccessLevelMaintainer, AccessLevelAdmin:
+	default:
+		return fmt.Errorf("invalid --access-level %d (must be %d=developer, %d=maintainer, or %d=admin)",
+			o.AccessLevel, AccessLevelDeveloper, AccessLevelMaintainer, AccessLevelAdmin)
+	}
+	if o.Tier != "" && !slices.Contains(Tiers, o.Tier) {
+		return fmt.Errorf("invalid --tier %q (must be one of: %s)", o.Tier, strings.Join(Tiers, ", "))
+	}
+	if o.Parallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1")
 	}
+	if o.WaitTimer < 0 || o.WaitTimer > maxWaitTimer {
+		return fmt.Errorf("--wait-timer must be between 0 and %d minutes", maxWaitTimer)
+	}
+	for _, b := range o.DeployBranches {
+		if b == provider.DeployBranchesProtected && len(o.DeployBranches) > 1 {
+			return fmt.Errorf("--deploy-branches %q cannot be combined with branch patterns", provider.DeployBranchesProtected)
+		}
+	}
+	return nil
 }
 
 // Protector handles environment protection operations
 type Protector struct {
 	provider provider.Provider
 	options  Options
+	limiter  *batch.Limiter // Shared by all environments of a run
 }
 
 // New creates a new Protector instance
@@ -44,10 +101,11 @@ func New(p provider.Provider, opts Options) *Protector {
 	return &Protector{
 		provider: p,
 		options:  opts,
+		limiter:  batch.NewLimiter(protectInterval),
 	}
 }
 
-// ProtectEnvironments protects environments matching the pattern
+// ProtectEnvironments protects environments matching the pattern (and Tier, if set)
 func (p *Protector) ProtectEnvironments(ctx context.Context, projectPath, pattern string) ([]Result, error) {
 	// List all environments
 	envs, err := p.provider.ListEnvironments(ctx, projectPath)
@@ -55,23 +113,117 @@ func (p *Protector) ProtectEnvironments(ctx context.Context, projectPath, patter
 		return nil, fmt.Errorf("failed to list environments: %w", err)
 	}
 
-	// Filter environments by pattern
-	filtered := filterEnvironments(envs, pattern)
+	// Filter environments by pattern and tier
+	filtered := p.selectEnvironments(envs, pattern)
 	if len(filtered) == 0 {
-		return nil, fmt.Errorf("no environments found matching pattern: %s", pattern)
+		return nil, fmt.Errorf("no environments found %s", Selection(pattern, p.options.Tier))
 	}
 
-	results := make([]Result, 0, len(filtered))
+	return p.protectAll(ctx, projectPath, filtered)
+}
 
-	for _, env := range filtered {
-		result := p.protectEnv(ctx, projectPath, env)
-		results = append(results, result)
+// protectAll protects the environments of one project, up to Parallel at a time, and
+// returns their results in the order of envs. An expired token fails every remaining
+// environment the same way, so it stops new requests and is returned; environments
+// not attempted by then are left out of the results.
+func (p *Protector) protectAll(ctx context.Context, projectPath string, envs []provider.Environment) ([]Result, error) {
+	results := make([]Result, len(envs))
+	done := make([]bool, len(envs))
+	var (
+		mu      sync.Mutex
+		stopErr error
+		wg      sync.WaitGroup
+	)
+	sem := make(chan struct{}, max(p.options.Parallel, 1))
+	for i, env := range envs {
+		sem <- struct{}{}
+		mu.Lock()
+		stopped := stopErr != nil
+		mu.Unlock()
+		if stopped {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result := p.protectEnv(ctx, projectPath, env)
+			result.TierGuessed = p.options.Tier != "" && env.Tier == ""
+
+			mu.Lock()
+			results[i], done[i] = result, true
+			if stopErr == nil && provider.IsUnauthorized(result.Error) {
+				stopErr = result.Error
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
 
-		// Small delay to avoid API rate limiting
-		if !p.options.DryRun && result.Action == "protected" {
-			time.Sleep(500 * time.Millisecond)
+	attempted := make([]Result, 0, len(envs))
+	for i, result := range results {
+		if done[i] {
+			attempted = append(attempted, result)
 		}
 	}
+	return attempted, stopErr
+}
+
+// Summary holds per-action counts for a protect run
+type Summary struct {
+	Protected int `json:"protected"`
+	Skipped   int `json:"skipped"` // Already protected
+	Failed    int `json:"failed"`
+	Total     int `json:"total"`
+}
+
+// Summarize counts results by action
+func Summarize(results []Result) Summary {
+	counts := batch.Count(results, func(r Result) string { return r.Action })
+	return Summary{
+		Protected: counts["protected"],
+		Skipped:   counts["skipped"],
+		Failed:    counts["failed"],
+		Total:     len(results),
+	}
+}
+
+// ProjectResults holds the protection results for one project in a group run; Error
+// is set if its environments could not be listed
+type ProjectResults = batch.ProjectResults[Result]
+
+// ProtectGroupEnvironments protects the environments matching pattern (and Tier) in every
+// project of a group or org, including subgroups. Archived projects are skipped, and
+// projects without a matching environment are returned with no results. The same
+// options (including resolved reviewers) are applied to every project; an expired
+// token stops the run.
+func (p *Protector) ProtectGroupEnvironments(ctx context.Context, groupPath, pattern string) ([]ProjectResults, error) {
+	repos, err := p.provider.ListGroupProjects(ctx, groupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects for group %s: %w", groupPath, err)
+	}
+
+	results := make([]ProjectResults, 0, len(repos))
+	for _, repo := range repos {
+		if repo.Archived {
+			continue
+		}
+
+		envs, err := p.provider.ListEnvironments(ctx, repo.FullPath)
+		if provider.IsUnauthorized(err) {
+			return results, err
+		}
+		project := ProjectResults{Project: repo.FullPath, Error: err}
+		if err == nil {
+			project.Results, err = p.protectAll(ctx, repo.FullPath, p.selectEnvironments(envs, pattern))
+			if err != nil {
+				return append(results, project), err
+			}
+		}
+		results = append(results, project)
+	}
 
 	return results, nil
 }
@@ -97,9 +249,16 @@ func (p *Protector) protectEnv(ctx context.Context, projectPath string, env prov
 	rule := provider.ProtectionRule{
 		AccessLevel:       p.options.AccessLevel,
 		RequiredApprovals: p.options.RequiredApprovals,
+		WaitTimer:         p.options.WaitTimer,
+		DeployBranches:    p.options.DeployBranches,
+		Reviewers:         p.options.Reviewers,
 	}
 
-	err := p.provider.ProtectEnvironment(ctx, projectPath, env.Name, rule)
+	// Space out requests to avoid API rate limiting
+	err := p.limiter.Wait(ctx)
+	if err == nil {
+		err = p.provider.ProtectEnvironment(ctx, projectPath, env.Name, rule)
+	}
 	if err != nil {
 		return Result{
 			Environment: env,
@@ -119,6 +278,188 @@ func (p *Protector) ListEnvironments(ctx context.Context, projectPath string) ([
 	return p.provider.ListEnvironments(ctx, projectPath)
 }
 
+// ProjectEnvironments holds the environments of one project in a group listing
+type ProjectEnvironments struct {
+	Project      string
+	Environments []provider.Environment
+	Error        error
+}
+
+// ListGroupEnvironments lists the environments of every project in a group,
+// including subgroups. A project whose environments cannot be listed is reported
+// with its error; an expired token stops the listing.
+func (p *Protector) ListGroupEnvironments(ctx context.Context, groupPath string) ([]ProjectEnvironments, error) {
+	repos, err := p.provider.ListGroupProjects(ctx, groupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects for group %s: %w", groupPath, err)
+	}
+
+	results := make([]ProjectEnvironments, 0, len(repos))
+	for _, repo := range repos {
+		envs, err := p.provider.ListEnvironments(ctx, repo.FullPath)
+		if provider.IsUnauthorized(err) {
+			return results, err
+		}
+		results = append(results, ProjectEnvironments{
+			Project:      repo.FullPath,
+			Environments: envs,
+			Error:        err,
+		})
+	}
+
+	return results, nil
+}
+
+// ProjectScan is the result of a fast unprotected-environment scan of one project
+type ProjectScan struct {
+	Project     string
+	Unprotected bool // At least one environment is not protected
+	Error       error
+}
+
+// ScanGroupUnprotected checks which projects in a group have any unprotected
+// environment. Listing stops at the first unprotected environment of each project,
+// so large audits fetch far fewer pages than ListGroupEnvironments.
+func (p *Protector) ScanGroupUnprotected(ctx context.Context, groupPath string) ([]ProjectScan, error) {
+	repos, err := p.provider.ListGroupProjects(ctx, groupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects for group %s: %w", groupPath, err)
+	}
+
+	results := make([]ProjectScan, 0, len(repos))
+	for _, repo := range repos {
+		unprotected, err := p.provider.HasUnprotectedEnvironment(ctx, repo.FullPath)
+		if provider.IsUnauthorized(err) {
+			return results, err
+		}
+		results = append(results, ProjectScan{
+			Project:     repo.FullPath,
+			Unprotected: unprotected,
+			Error:       err,
+		})
+	}
+
+	return results, nil
+}
+
+// UnprotectedOnly returns the environments that are not protected
+func UnprotectedOnly(envs []provider.Environment) []provider.Environment {
+	var filtered []provider.Environment
+	for _, env := range envs {
+		if !env.Protected {
+			filtered = append(filtered, env)
+		}
+	}
+	return filtered
+}
+
+// ProtectedOnly returns the environments that are protected
+func ProtectedOnly(envs []provider.Environment) []provider.Environment {
+	var filtered []provider.Environment
+	for _, env := range envs {
+		if env.Protected {
+			filtered = append(filtered, env)
+		}
+	}
+	return filtered
+}
+
+// MatchingEnvironments returns the environments whose names match pattern, using the
+// same matching as ProtectEnvironments. An empty pattern matches every environment.
+func MatchingEnvironments(envs []provider.Environment, pattern string) []provider.Environment {
+	if pattern == "" {
+		return envs
+	}
+	return filterEnvironments(envs, pattern)
+}
+
+// ComplianceGaps returns the projects that have at least one unprotected environment,
+// and projects whose environments could not be listed, since they cannot be verified.
+// Environments should already be narrowed to the ones the policy covers.
+func ComplianceGaps(projects []ProjectEnvironments) []ProjectEnvironments {
+	var gaps []ProjectEnvironments
+	for _, p := range projects {
+		if p.Error != nil {
+			gaps = append(gaps, p)
+			continue
+		}
+		if unprotected := UnprotectedOnly(p.Environments); len(unprotected) > 0 {
+			gaps = append(gaps, ProjectEnvironments{Project: p.Project, Environments: unprotected})
+		}
+	}
+	return gaps
+}
+
+// NamesPattern returns a pattern that matches exactly the given environment names,
+// with any regex metacharacters in them escaped. It is anchored at both ends, so it
+// does not depend on how the caller applies it.
+func NamesPattern(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+// Tiers are the deployment tiers GitLab assigns to environments
+var Tiers = []string{"production", "staging", "testing", "development", "other"}
+
+// tierPatterns guess the tier of an environment from its name, in the order GitLab
+// applies them to environments created without an explicit tier. The first match wins,
+// so e.g. prod-int is testing (int) and dev-prod is development.
+var tierPatterns = []struct {
+	tier string
+	re   *regexp.Regexp
+}{
+	{"development", regexp.MustCompile(`(?i)(dev|review|trunk)`)},
+	{"testing", regexp.MustCompile(`(?i)(test|tst|int|ac(ce|)pt|qa|qc|control|quality)`)},
+	{"staging", regexp.MustCompile(`(?i)(st(a|)g|mod(e|)l|pre|demo|non)`)},
+	{"production", regexp.MustCompile(`(?i)(pr(o|)d|live)`)},
+}
+
+// EnvironmentTier returns the deployment tier of an environment. Where the provider
+// does not report one (GitHub, GitLab before 13.10), it is guessed from the name the
+// same way GitLab does; results selected that way are marked TierGuessed.
+func EnvironmentTier(env provider.Environment) string {
+	if env.Tier != "" {
+		return env.Tier
+	}
+	for _, tp := range tierPatterns {
+		if tp.re.MatchString(env.Name) {
+			return tp.tier
+		}
+	}
+	return "other"
+}
+
+// Selection describes the environments a pattern and tier select, for messages
+// (e.g., `matching "prod" in tier production`)
+func Selection(pattern, tier string) string {
+	switch {
+	case tier == "":
+		return fmt.Sprintf("matching %q", pattern)
+	case pattern == "" || pattern == "all" || pattern == "*":
+		return "in tier " + tier
+	default:
+		return fmt.Sprintf("matching %q in tier %s", pattern, tier)
+	}
+}
+
+// selectEnvironments filters environments by pattern, then by the Tier option
+func (p *Protector) selectEnvironments(envs []provider.Environment, pattern string) []provider.Environment {
+	filtered := filterEnvironments(envs, pattern)
+	if p.options.Tier == "" {
+		return filtered
+	}
+	var inTier []provider.Environment
+	for _, env := range filtered {
+		if EnvironmentTier(env) == p.options.Tier {
+			inTier = append(inTier, env)
+		}
+	}
+	return inTier
+}
+
 // filterEnvironments filters environments by pattern
 func filterEnvironments(envs []provider.Environment, pattern string) []provider.Environment {
 	if pattern == "all" || pattern == "*" {
@@ -151,33 +492,56 @@ func filterEnvironments(envs []provider.Environment, pattern string) []provider.
 
 // PrintResults prints the protection results to stdout
 func PrintResults(results []Result, dryRun bool) {
-	var protected, skipped, failed int
-
-	prefix := ""
-	if dryRun {
-		prefix = "[DRY-RUN] "
-	}
-
+	prefix := batch.Prefix(dryRun)
 	for _, r := range results {
-		switch r.Action {
-		case "protected":
-			protected++
-			fmt.Printf("%s[OK] Protected: %s\n", prefix, r.Environment.Name)
-		case "skipped":
-			skipped++
-			fmt.Printf("%s[SKIP] Already protected: %s\n", prefix, r.Environment.Name)
-		case "failed":
-			failed++
-			fmt.Printf("%s[FAIL] Failed: %s - %v\n", prefix, r.Environment.Name, r.Error)
-		}
+		printResultLine(r, prefix)
 	}
 
+	s := Summarize(results)
 	fmt.Println()
 	fmt.Println("Summary:")
-	fmt.Printf("  Protected: %d\n", protected)
-	fmt.Printf("  Skipped:   %d (already protected)\n", skipped)
-	fmt.Printf("  Failed:    %d\n", failed)
-	fmt.Printf("  Total:     %d\n", len(results))
+	fmt.Printf("  Protected: %d\n", s.Protected)
+	fmt.Printf("  Skipped:   %d (already protected)\n", s.Skipped)
+	fmt.Printf("  Failed:    %d\n", s.Failed)
+	fmt.Printf("  Total:     %d\n", s.Total)
+}
+
+// PrintGroupResults prints the protection results of a group run per project,
+// followed by totals. Projects without a matching environment are only counted;
+// selection describes the matching environments (see Selection).
+func PrintGroupResults(projects []ProjectResults, selection string, dryRun bool) {
+	all, without, listFailed := batch.PrintProjects(projects, dryRun, printResultLine)
+
+	s := Summarize(all)
+	fmt.Println("Summary:")
+	fmt.Printf("  Protected: %d\n", s.Protected)
+	fmt.Printf("  Skipped:   %d (already protected)\n", s.Skipped)
+	fmt.Printf("  Failed:    %d\n", s.Failed)
+	fmt.Printf("  Projects:  %d checked, %d without environments %s\n", len(projects), without, selection)
+	if listFailed > 0 {
+		fmt.Printf("  Errors:    %d project(s) whose environments could not be listed\n", listFailed)
+	}
+}
+
+// printResultLine prints one protection result after prefix
+func printResultLine(r Result, prefix string) {
+	switch r.Action {
+	case "protected":
+		fmt.Printf("%s[OK] Protected: %s%s\n", prefix, r.Environment.Name, guessedTier(r))
+	case "skipped":
+		fmt.Printf("%s[SKIP] Already protected: %s%s\n", prefix, r.Environment.Name, guessedTier(r))
+	case "failed":
+		fmt.Printf("%s[FAIL] Failed: %s%s - %v\n", prefix, r.Environment.Name, guessedTier(r), r.Error)
+	}
+}
+
+// guessedTier notes a tier guessed from the environment name, so a wrong guess shows
+// up (in a dry run, before anything is protected)
+func guessedTier(r Result) string {
+	if !r.TierGuessed {
+		return ""
+	}
+	return fmt.Sprintf(" (tier %s guessed from name)", EnvironmentTier(r.Environment))
 }
 
 // PrintEnvironments prints a list of environments
@@ -185,6 +549,102 @@ func PrintEnvironments(envs []provider.Environment) {
 	fmt.Println("Environments:")
 	fmt.Println()
 
+	printEnvironmentLines(envs)
+
+	fmt.Println()
+	fmt.Printf("Total: %d environments\n", len(envs))
+}
+
+// PrintGroupEnvironments prints environments grouped by project. Projects without
+// environments are left out; projects that failed are listed with their error.
+func PrintGroupEnvironments(projects []ProjectEnvironments) {
+	var total, unprotected, listed, failed int
+
+	for _, p := range projects {
+		if p.Error != nil {
+			failed++
+			fmt.Printf("%s\n  [FAIL] %v\n\n", p.Project, p.Error)
+			continue
+		}
+		if len(p.Environments) == 0 {
+			continue
+		}
+
+		listed++
+		fmt.Println(p.Project)
+		printEnvironmentLines(p.Environments)
+		fmt.Println()
+
+		total += len(p.Environments)
+		unprotected += len(UnprotectedOnly(p.Environments))
+	}
+
+	fmt.Println("Summary:")
+	fmt.Printf("  Projects:     %d with environments (of %d)\n", listed, len(projects))
+	fmt.Printf("  Environments: %d\n", total)
+	fmt.Printf("  Unprotected:  %d\n", unprotected)
+	if failed > 0 {
+		fmt.Printf("  Failed:       %d project(s)\n", failed)
+	}
+}
+
+// PrintScanResults prints the projects with unprotected environments found by ScanGroupUnprotected
+func PrintScanResults(scans []ProjectScan) {
+	var unprotected, failed int
+
+	for _, s := range scans {
+		switch {
+		case s.Error != nil:
+			failed++
+			fmt.Printf("  [FAIL] %s - %v\n", s.Project, s.Error)
+		case s.Unprotected:
+			unprotected++
+			fmt.Printf("  %s\n", s.Project)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("Summary:")
+	fmt.Printf("  Projects with unprotected environments: %d (of %d)\n", unprotected, len(scans))
+	if failed > 0 {
+		fmt.Printf("  Failed:                                 %d project(s)\n", failed)
+	}
+}
+
+// PrintComplianceGaps prints the projects found by ComplianceGaps with their unprotected
+// environments, out of total projects checked
+func PrintComplianceGaps(gaps []ProjectEnvironments, total int, pattern string) {
+	scope := "environments"
+	if pattern != "" {
+		scope = fmt.Sprintf("environments matching %q", pattern)
+	}
+
+	if len(gaps) == 0 {
+		fmt.Printf("[OK] All %d project(s) protect their %s\n", total, scope)
+		return
+	}
+
+	var failed int
+	for _, g := range gaps {
+		if g.Error != nil {
+			failed++
+			fmt.Printf("%s\n  [FAIL] %v\n\n", g.Project, g.Error)
+			continue
+		}
+		fmt.Println(g.Project)
+		printEnvironmentLines(g.Environments)
+		fmt.Println()
+	}
+
+	fmt.Println("Summary:")
+	fmt.Printf("  Non-compliant: %d project(s) with unprotected %s (of %d)\n", len(gaps)-failed, scope, total)
+	if failed > 0 {
+		fmt.Printf("  Failed:        %d project(s) could not be checked\n", failed)
+	}
+}
+
+// printEnvironmentLines prints one line per environment with its protection status
+func printEnvironmentLines(envs []provider.Environment) {
 	for _, env := range envs {
 		status := "unprotected"
 		if env.Protected {
@@ -192,7 +652,4 @@ func PrintEnvironments(envs []provider.Environment) {
 		}
 		fmt.Printf("  %-40s [%s] %s\n", env.Name, status, env.State)
 	}
-
-	fmt.Println()
-	fmt.Printf("Total: %d environments\n", len(envs))
 }
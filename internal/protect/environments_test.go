@@ -0,0 +1,371 @@
+package protect
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/zsoftly/ztigit/internal/batch"
+	"github.com/zsoftly/ztigit/internal/provider"
+)
+
+// fakeProvider lists environments and records protection requests. Provider methods
+// the protect package does not call are left to the nil embedded interface.
+type fakeProvider struct {
+	provider.Provider
+
+	envs        map[string][]provider.Environment // ListEnvironments results by project
+	envErrs     map[string]error                  // ListEnvironments errors by project
+	projects    map[string][]provider.Repository  // ListGroupProjects results by group
+	protectErrs map[string]error                  // ProtectEnvironment errors by environment
+
+	mu        sync.Mutex
+	protected []string    // Environments ProtectEnvironment was called for, in order
+	times     []time.Time // When each call was made
+}
+
+func (f *fakeProvider) ListEnvironments(ctx context.Context, projectPath string) ([]provider.Environment, error) {
+	if err := f.envErrs[projectPath]; err != nil {
+		return nil, err
+	}
+	return f.envs[projectPath], nil
+}
+
+func (f *fakeProvider) ListGroupProjects(ctx context.Context, groupPath string) ([]provider.Repository, error) {
+	projects, ok := f.projects[groupPath]
+	if !ok {
+		return nil, provider.ErrNotFound
+	}
+	return projects, nil
+}
+
+func (f *fakeProvider) ProtectEnvironment(ctx context.Context, projectPath, envName string, rule provider.ProtectionRule) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.protected = append(f.protected, envName)
+	f.times = append(f.times, time.Now())
+	return f.protectErrs[envName]
+}
+
+func unauthorized() error {
+	return &github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnauthorized}}
+}
+
+// newTestProtector returns a Protector that spaces out requests by interval
+func newTestProtector(p provider.Provider, opts Options, interval time.Duration) *Protector {
+	protector := New(p, opts)
+	protector.limiter = batch.NewLimiter(interval)
+	return protector
+}
+
+// captureStdout returns what fn prints to standard output
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe error = %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func resultNames(results []Result) []string {
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = r.Environment.Name
+	}
+	return names
+}
+
+func TestProtectEnvironments_Order(t *testing.T) {
+	envs := []provider.Environment{{Name: "prod-a"}, {Name: "prod-b"}, {Name: "prod-c", Protected: true}, {Name: "prod-d"}, {Name: "staging"}}
+	fake := &fakeProvider{envs: map[string][]provider.Environment{"devops/api": envs}}
+
+	opts := DefaultOptions()
+	opts.Parallel = 3
+	const interval = 20 * time.Millisecond
+	results, err := newTestProtector(fake, opts, interval).ProtectEnvironments(context.Background(), "devops/api", "prod")
+	if err != nil {
+		t.Fatalf("ProtectEnvironments() error = %v", err)
+	}
+
+	// Results keep the order of the environments, whatever order requests finish in
+	if got, want := resultNames(results), []string{"prod-a", "prod-b", "prod-c", "prod-d"}; !slices.Equal(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+	if results[2].Action != "skipped" {
+		t.Errorf("prod-c action = %q, want skipped (already protected)", results[2].Action)
+	}
+
+	// Only unprotected environments are requested, spaced out by the shared limiter
+	// even when sent in parallel
+	slices.Sort(fake.protected)
+	if want := []string{"prod-a", "prod-b", "prod-d"}; !slices.Equal(fake.protected, want) {
+		t.Errorf("ProtectEnvironment calls = %v, want %v", fake.protected, want)
+	}
+	slices.SortFunc(fake.times, func(a, b time.Time) int { return a.Compare(b) })
+	for i := 1; i < len(fake.times); i++ {
+		// Allow for timer slack below the interval
+		if gap := fake.times[i].Sub(fake.times[i-1]); gap < interval-5*time.Millisecond {
+			t.Errorf("requests %d and %d were %v apart, want at least %v", i-1, i, gap, interval)
+		}
+	}
+}
+
+func TestProtectEnvironments_StopsOnUnauthorized(t *testing.T) {
+	envs := []provider.Environment{{Name: "prod-a"}, {Name: "prod-b"}, {Name: "prod-c"}}
+	fake := &fakeProvider{
+		envs:        map[string][]provider.Environment{"devops/api": envs},
+		protectErrs: map[string]error{"prod-b": unauthorized()},
+	}
+
+	results, err := newTestProtector(fake, DefaultOptions(), 0).ProtectEnvironments(context.Background(), "devops/api", "prod")
+	if !provider.IsUnauthorized(err) {
+		t.Fatalf("ProtectEnvironments() error = %v, want the 401", err)
+	}
+	if want := []string{"prod-a", "prod-b"}; !slices.Equal(fake.protected, want) {
+		t.Errorf("ProtectEnvironment calls = %v, want %v (none after the 401)", fake.protected, want)
+	}
+	if got, want := resultNames(results), []string{"prod-a", "prod-b"}; !slices.Equal(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+	if results[1].Action != "failed" {
+		t.Errorf("prod-b action = %q, want failed", results[1].Action)
+	}
+}
+
+func TestProtectEnvironments_DryRun(t *testing.T) {
+	envs := []provider.Environment{{Name: "prod-a"}, {Name: "prod-b", Protected: true}}
+	fake := &fakeProvider{envs: map[string][]provider.Environment{"devops/api": envs}}
+
+	opts := DefaultOptions()
+	opts.DryRun = true
+	results, err := New(fake, opts).ProtectEnvironments(context.Background(), "devops/api", "prod")
+	if err != nil {
+		t.Fatalf("ProtectEnvironments() error = %v", err)
+	}
+	if len(fake.protected) != 0 {
+		t.Errorf("dry run sent ProtectEnvironment for %v", fake.protected)
+	}
+	if s := Summarize(results); s.Protected != 1 || s.Skipped != 1 {
+		t.Errorf("Summarize() = %+v, want 1 protected and 1 skipped", s)
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Options)
+		wantErr string // Substring of the error; empty = valid
+	}{
+		{name: "defaults", modify: func(o *Options) {}},
+		{name: "maintainer", modify: func(o *Options) { o.AccessLevel = AccessLevelMaintainer }},
+		{name: "admin", modify: func(o *Options) { o.AccessLevel = AccessLevelAdmin }},
+		{name: "guest access level", modify: func(o *Options) { o.AccessLevel = 10 }, wantErr: "invalid --access-level 10"},
+		{name: "reporter access level", modify: func(o *Options) { o.AccessLevel = 20 }, wantErr: "invalid --access-level 20"},
+		{name: "owner access level", modify: func(o *Options) { o.AccessLevel = 50 }, wantErr: "invalid --access-level 50"},
+		{name: "zero access level", modify: func(o *Options) { o.AccessLevel = 0 }, wantErr: "invalid --access-level 0"},
+		{name: "tier", modify: func(o *Options) { o.Tier = "staging" }},
+		{name: "unknown tier", modify: func(o *Options) { o.Tier = "prod" }, wantErr: "invalid --tier"},
+		{name: "no parallel", modify: func(o *Options) { o.Parallel = 0 }, wantErr: "--parallel"},
+		{name: "wait timer too long", modify: func(o *Options) { o.WaitTimer = maxWaitTimer + 1 }, wantErr: "--wait-timer"},
+		{name: "protected with patterns", modify: func(o *Options) {
+			o.DeployBranches = []string{provider.DeployBranchesProtected, "main"}
+		}, wantErr: "--deploy-branches"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			tt.modify(&opts)
+			err := opts.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvironmentTier(t *testing.T) {
+	tests := []struct {
+		env  provider.Environment
+		want string
+	}{
+		// A tier reported by the API wins over the name
+		{provider.Environment{Name: "prod", Tier: "staging"}, "staging"},
+		{provider.Environment{Name: "sandbox", Tier: "production"}, "production"},
+
+		// Otherwise the first matching pattern, in GitLab's order
+		{provider.Environment{Name: "review/feature-x"}, "development"},
+		{provider.Environment{Name: "dev-prod"}, "development"},
+		{provider.Environment{Name: "qa"}, "testing"},
+		{provider.Environment{Name: "integration"}, "testing"},
+		{provider.Environment{Name: "prod-int"}, "testing"},
+		{provider.Environment{Name: "stage"}, "staging"},
+		{provider.Environment{Name: "preprod"}, "staging"},
+		{provider.Environment{Name: "prod-eu"}, "production"},
+		{provider.Environment{Name: "production"}, "production"},
+		{provider.Environment{Name: "live"}, "production"},
+		{provider.Environment{Name: "sandbox"}, "other"},
+	}
+
+	for _, tt := range tests {
+		if got := EnvironmentTier(tt.env); got != tt.want {
+			t.Errorf("EnvironmentTier(%q, tier %q) = %q, want %q", tt.env.Name, tt.env.Tier, got, tt.want)
+		}
+	}
+}
+
+func TestProtectEnvironments_Tier(t *testing.T) {
+	envs := []provider.Environment{
+		{Name: "prod-eu"},                      // Guessed production
+		{Name: "prod-int"},                     // Guessed testing
+		{Name: "main", Tier: "production"},     // Reported by the API
+		{Name: "prod-us", Tier: "staging"},     // Reported, despite the name
+		{Name: "live", Protected: true},        // Guessed production, already protected
+		{Name: "preprod"},                      // Guessed staging
+		{Name: "sandbox", Tier: "development"}, // Reported
+	}
+	fake := &fakeProvider{envs: map[string][]provider.Environment{"devops/api": envs}}
+
+	opts := DefaultOptions()
+	opts.DryRun = true
+	opts.Tier = "production"
+	results, err := New(fake, opts).ProtectEnvironments(context.Background(), "devops/api", "all")
+	if err != nil {
+		t.Fatalf("ProtectEnvironments() error = %v", err)
+	}
+
+	if got, want := resultNames(results), []string{"prod-eu", "main", "live"}; !slices.Equal(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+	for _, r := range results {
+		if want := r.Environment.Tier == ""; r.TierGuessed != want {
+			t.Errorf("%s TierGuessed = %v, want %v", r.Environment.Name, r.TierGuessed, want)
+		}
+	}
+}
+
+func TestNamesPattern(t *testing.T) {
+	envs := []provider.Environment{
+		{Name: "prod"}, {Name: "preprod"}, {Name: "prod-eu"}, {Name: "production"},
+		{Name: "review/app.1"}, {Name: "review/appx1"}, {Name: "a+b"}, {Name: "aab"},
+	}
+
+	tests := []struct {
+		names []string
+		want  []string
+	}{
+		{[]string{"prod"}, []string{"prod"}},
+		{[]string{"prod", "prod-eu"}, []string{"prod", "prod-eu"}},
+		{[]string{"review/app.1"}, []string{"review/app.1"}},
+		{[]string{"a+b"}, []string{"a+b"}},
+		{[]string{"eu"}, nil},
+	}
+
+	for _, tt := range tests {
+		pattern := NamesPattern(tt.names)
+		if got := envNames(MatchingEnvironments(envs, pattern)); !slices.Equal(got, tt.want) {
+			t.Errorf("MatchingEnvironments(NamesPattern(%q)) = %v, want %v", tt.names, got, tt.want)
+		}
+		// The pattern is anchored on its own, whatever applies it
+		re := regexp.MustCompile(pattern)
+		for _, env := range envs {
+			if re.MatchString(env.Name) != slices.Contains(tt.want, env.Name) {
+				t.Errorf("NamesPattern(%q) = %q matches %q: %v", tt.names, pattern, env.Name, re.MatchString(env.Name))
+			}
+		}
+	}
+}
+
+func envNames(envs []provider.Environment) []string {
+	var names []string
+	for _, env := range envs {
+		names = append(names, env.Name)
+	}
+	return names
+}
+
+func TestListGroupEnvironments(t *testing.T) {
+	fake := &fakeProvider{
+		projects: map[string][]provider.Repository{
+			"devops": {{FullPath: "devops/api"}, {FullPath: "devops/docs"}, {FullPath: "devops/web"}},
+			"locked": {{FullPath: "locked/api"}, {FullPath: "locked/web"}},
+		},
+		envs: map[string][]provider.Environment{
+			"devops/api": {{Name: "prod", Protected: true}, {Name: "staging"}},
+			"devops/web": {{Name: "prod"}},
+		},
+		envErrs: map[string]error{
+			"devops/docs": errors.New("500 Internal Server Error"),
+			"locked/api":  unauthorized(),
+		},
+	}
+	protector := New(fake, DefaultOptions())
+	ctx := context.Background()
+
+	projects, err := protector.ListGroupEnvironments(ctx, "devops")
+	if err != nil {
+		t.Fatalf("ListGroupEnvironments() error = %v", err)
+	}
+	if len(projects) != 3 {
+		t.Fatalf("ListGroupEnvironments() = %d projects, want 3", len(projects))
+	}
+	// A failing project is reported with its error, without stopping the listing
+	if projects[1].Project != "devops/docs" || projects[1].Error == nil {
+		t.Errorf("projects[1] = %+v, want devops/docs with its error", projects[1])
+	}
+	if got := envNames(projects[0].Environments); !slices.Equal(got, []string{"prod", "staging"}) {
+		t.Errorf("devops/api environments = %v, want prod, staging", got)
+	}
+
+	out := captureStdout(t, func() { PrintGroupEnvironments(projects) })
+	for _, want := range []string{
+		"devops/api\n",
+		"devops/docs\n  [FAIL] 500 Internal Server Error",
+		"devops/web\n",
+		"Projects:     2 with environments (of 3)",
+		"Environments: 3",
+		"Unprotected:  2",
+		"Failed:       1 project(s)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("PrintGroupEnvironments() output missing %q:\n%s", want, out)
+		}
+	}
+
+	// An expired token stops the listing, keeping the projects listed so far
+	projects, err = protector.ListGroupEnvironments(ctx, "locked")
+	if !provider.IsUnauthorized(err) || len(projects) != 0 {
+		t.Errorf("ListGroupEnvironments(locked) = %d projects, %v; want none and the 401", len(projects), err)
+	}
+
+	if _, err := protector.ListGroupEnvironments(ctx, "missing"); !provider.IsNotFound(err) {
+		t.Errorf("ListGroupEnvironments(missing) error = %v, want not found", err)
+	}
+}
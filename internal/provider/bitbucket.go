@@ -0,0 +1,562 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zsoftly/ztigit/internal/retry"
+)
+
+// bitbucketAPI is the Bitbucket Cloud REST API root
+const bitbucketAPI = "https://api.bitbucket.org/2.0"
+
+// BitbucketProvider implements the Provider interface for Bitbucket Cloud.
+// Workspaces play the part of groups; they do not nest.
+type BitbucketProvider struct {
+	client   *http.Client
+	apiURL   string
+	username string // Set for app passwords, which use basic auth
+	token    string
+}
+
+// NewBitbucketProvider creates a new Bitbucket Cloud provider instance.
+// Token is optional - unauthenticated access works for public repos. A token of the
+// form "username:app-password" is sent as basic auth, anything else (access tokens,
+// OAuth tokens) as a bearer token. A baseURL other than bitbucket.org is used as the
+// API root.
+func NewBitbucketProvider(token, baseURL string) (*BitbucketProvider, error) {
+	client := debugHTTPClient()
+	if client == nil {
+		client = http.DefaultClient
+	}
+	p := &BitbucketProvider{client: client, apiURL: bitbucketAPI, token: token}
+	if user, password, ok := strings.Cut(token, ":"); ok {
+		p.username, p.token = user, password
+	}
+
+	if baseURL == "" || isBitbucketDotOrg(baseURL) {
+		return p, nil
+	}
+	u, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: must include scheme and host", baseURL)
+	}
+	p.apiURL = strings.TrimRight(u.String(), "/")
+	return p, nil
+}
+
+// isBitbucketDotOrg reports whether a URL points at Bitbucket Cloud
+func isBitbucketDotOrg(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Hostname()) {
+	case "bitbucket.org", "www.bitbucket.org", "api.bitbucket.org":
+		return true
+	}
+	return false
+}
+
+// bitbucketError is an error response from the Bitbucket API
+type bitbucketError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // Wait asked for by the Retry-After header (0 = none)
+}
+
+func (e *bitbucketError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, e.Message)
+}
+
+// bitbucketRetry paces retries of read requests that were rate limited (429) or hit a
+// server error; writes are never retried
+var bitbucketRetry = retry.Policy{
+	MaxAttempts: 4,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	Jitter:      0.2,
+	Retryable:   isBitbucketTransient,
+	RetryAfter:  bitbucketRetryAfter,
+}
+
+// bitbucketRetryAfter returns the wait a Bitbucket error response asked for, if any
+func bitbucketRetryAfter(err error) time.Duration {
+	var errResp *bitbucketError
+	if errors.As(err, &errResp) {
+		return errResp.RetryAfter
+	}
+	return 0
+}
+
+// parseRetryAfter parses a Retry-After header, in seconds or as an HTTP date.
+// It returns 0 if the header is missing, invalid, or in the past.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// isBitbucketTransient reports whether err is a Bitbucket API response worth retrying
+func isBitbucketTransient(err error) bool {
+	var errResp *bitbucketError
+	return errors.As(err, &errResp) &&
+		(errResp.StatusCode == http.StatusTooManyRequests || errResp.StatusCode >= 500)
+}
+
+// isBitbucketUnauthorized reports whether err wraps a 401 response from the Bitbucket API
+func isBitbucketUnauthorized(err error) bool {
+	var errResp *bitbucketError
+	return errors.As(err, &errResp) && errResp.StatusCode == http.StatusUnauthorized
+}
+
+// isBitbucketNotFound reports whether err wraps a 404 response from the Bitbucket API
+func isBitbucketNotFound(err error) bool {
+	var errResp *bitbucketError
+	return errors.As(err, &errResp) && errResp.StatusCode == http.StatusNotFound
+}
+
+// bitbucketNotSupported is returned by the operations Bitbucket Cloud has no API for
+func bitbucketNotSupported(feature string) error {
+	return fmt.Errorf("%s: not supported by bitbucket", feature)
+}
+
+// do sends a request to the API and decodes the JSON response into out (if not nil).
+// path is relative to the API root, or a full URL such as a page's next link. GET
+// requests that are rate limited or hit a server error are retried (bitbucketRetry).
+func (p *BitbucketProvider) do(ctx context.Context, method, path string, body, out any) (*http.Response, error) {
+	if method != http.MethodGet {
+		return p.doOnce(ctx, method, path, body, out)
+	}
+	var resp *http.Response
+	err := retry.Do(ctx, bitbucketRetry, func(ctx context.Context) error {
+		var err error
+		resp, err = p.doOnce(ctx, method, path, body, out)
+		return err
+	})
+	return resp, err
+}
+
+// doOnce sends a single API request; see do
+func (p *BitbucketProvider) doOnce(ctx context.Context, method, path string, body, out any) (*http.Response, error) {
+	target := path
+	if !strings.HasPrefix(path, "https://") && !strings.HasPrefix(path, "http://") {
+		target = p.apiURL + path
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch {
+	case p.username != "":
+		req.SetBasicAuth(p.username, p.token)
+	case p.token != "":
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var errBody struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&errBody)
+		return resp, &bitbucketError{
+			StatusCode: resp.StatusCode,
+			Message:    errBody.Error.Message,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp, fmt.Errorf("invalid response from %s: %w", redactURL(req.URL), err)
+		}
+	}
+	return resp, nil
+}
+
+// bitbucketPage is one page of a paginated listing; Next is the URL of the next page
+type bitbucketPage[T any] struct {
+	Values []T    `json:"values"`
+	Next   string `json:"next"`
+}
+
+// eachBitbucket calls fn for every value of a paginated listing, following the next
+// links page by page
+func eachBitbucket[T any](ctx context.Context, p *BitbucketProvider, path string, fn func(T) error) error {
+	for path != "" {
+		var page bitbucketPage[T]
+		if _, err := p.do(ctx, http.MethodGet, path, nil, &page); err != nil {
+			return err
+		}
+		for _, v := range page.Values {
+			if err := fn(v); err != nil {
+				return err
+			}
+		}
+		path = page.Next
+	}
+	return nil
+}
+
+// bitbucketUser is the authenticated account or a workspace member
+type bitbucketUser struct {
+	Username string `json:"username"`
+	Nickname string `json:"nickname"`
+}
+
+// login returns the name that identifies a user; accounts created since Bitbucket
+// dropped usernames only have a nickname
+func (u bitbucketUser) login() string {
+	if u.Username != "" {
+		return u.Username
+	}
+	return u.Nickname
+}
+
+// bitbucketRepo is a repository as returned by the API
+type bitbucketRepo struct {
+	Name        string    `json:"name"`
+	FullName    string    `json:"full_name"` // workspace/repo-slug
+	Description string    `json:"description"`
+	IsPrivate   bool      `json:"is_private"`
+	UpdatedOn   time.Time `json:"updated_on"`
+	Size        int64     `json:"size"`
+	Language    string    `json:"language"`
+	MainBranch  *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+	Links struct {
+		Clone []struct {
+			Name string `json:"name"`
+			Href string `json:"href"`
+		} `json:"clone"`
+	} `json:"links"`
+}
+
+// convertBitbucketRepo converts a Bitbucket API repository to a Repository
+func convertBitbucketRepo(repo bitbucketRepo) Repository {
+	workspace, _, _ := strings.Cut(repo.FullName, "/")
+	r := Repository{
+		Name:        repo.Name,
+		FullPath:    repo.FullName,
+		Owner:       workspace,
+		Namespace:   workspace,
+		Description: repo.Description,
+		Private:     repo.IsPrivate,
+		LastUpdated: repo.UpdatedOn,
+		Size:        repo.Size,
+		SizeKnown:   true,
+		Language:    repo.Language,
+	}
+	if repo.MainBranch != nil {
+		r.DefaultBranch = repo.MainBranch.Name
+	}
+	for _, link := range repo.Links.Clone {
+		switch link.Name {
+		case "https":
+			// The API includes the requesting user (https://user@bitbucket.org/...);
+			// credentials come from git's credential helpers like for other providers
+			if u, err := url.Parse(link.Href); err == nil {
+				u.User = nil
+				r.CloneURL = u.String()
+			}
+		case "ssh":
+			r.SSHUrl = link.Href
+		}
+	}
+	return r
+}
+
+// splitRepoPath splits workspace/repo-slug into its escaped path segments
+func splitRepoPath(projectPath string) (string, string, error) {
+	parts := strings.SplitN(projectPath, "/", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid project path: %s (expected workspace/repo)", projectPath)
+	}
+	return url.PathEscape(parts[0]), url.PathEscape(parts[1]), nil
+}
+
+// Name returns the provider name
+func (p *BitbucketProvider) Name() string {
+	return "bitbucket"
+}
+
+// Capabilities reports the optional features of Bitbucket Cloud: none of them.
+// Workspaces do not nest, and there are no releases or environment protection.
+func (p *BitbucketProvider) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{}
+}
+
+// TestConnection tests the API connection and token validity
+func (p *BitbucketProvider) TestConnection(ctx context.Context) error {
+	if _, err := p.do(ctx, http.MethodGet, "/user", nil, nil); err != nil {
+		return fmt.Errorf("Bitbucket connection test failed: %w", err)
+	}
+	return nil
+}
+
+// GetCurrentUser returns the authenticated user's username
+func (p *BitbucketProvider) GetCurrentUser(ctx context.Context) (string, error) {
+	var user bitbucketUser
+	if _, err := p.do(ctx, http.MethodGet, "/user", nil, &user); err != nil {
+		return "", fmt.Errorf("failed to get current user: %w", err)
+	}
+	return user.login(), nil
+}
+
+// TokenScopes returns the scopes of an OAuth or access token from the X-OAuth-Scopes
+// header. App passwords do not send it, so their scopes are unknown.
+func (p *BitbucketProvider) TokenScopes(ctx context.Context) ([]string, bool, error) {
+	resp, err := p.do(ctx, http.MethodGet, "/user", nil, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get current user: %w", err)
+	}
+	header := resp.Header.Get("X-OAuth-Scopes")
+	if header == "" {
+		return nil, false, nil
+	}
+	var scopes []string
+	for _, scope := range strings.Split(header, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, true, nil
+}
+
+// ListGroupProjects lists all repositories in a workspace
+func (p *BitbucketProvider) ListGroupProjects(ctx context.Context, workspace string) ([]Repository, error) {
+	var repos []Repository
+	err := p.ListGroupProjectsStream(ctx, workspace, func(repo Repository) error {
+		repos = append(repos, repo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// ListGroupProjectsStream calls fn for each repository of a workspace as pages arrive
+func (p *BitbucketProvider) ListGroupProjectsStream(ctx context.Context, workspace string, fn func(Repository) error) error {
+	return p.eachWorkspaceRepo(ctx, workspace, 100, fn)
+}
+
+// ProbeGroup checks that a workspace can be listed, fetching at most one repository
+func (p *BitbucketProvider) ProbeGroup(ctx context.Context, workspace string) (bool, error) {
+	return probeFirst(func(fn func(Repository) error) error {
+		return p.eachWorkspaceRepo(ctx, workspace, 1, fn)
+	})
+}
+
+// eachWorkspaceRepo calls fn for each repository of a workspace, fetching pagelen
+// repositories per request
+func (p *BitbucketProvider) eachWorkspaceRepo(ctx context.Context, workspace string, pagelen int, fn func(Repository) error) error {
+	path := "/repositories/" + url.PathEscape(workspace) + "?pagelen=" + strconv.Itoa(pagelen)
+	err := eachBitbucket(ctx, p, path, func(repo bitbucketRepo) error {
+		return fn(convertBitbucketRepo(repo))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list repositories for %s: %w", workspace, err)
+	}
+	return nil
+}
+
+// SearchRepositories lists the repositories the user is a member of that match a
+// Bitbucket query language filter (e.g., `name ~ "terraform"`)
+func (p *BitbucketProvider) SearchRepositories(ctx context.Context, query string) ([]Repository, error) {
+	var repos []Repository
+	path := "/repositories?role=member&pagelen=100&q=" + url.QueryEscape(query)
+	err := eachBitbucket(ctx, p, path, func(repo bitbucketRepo) error {
+		repos = append(repos, convertBitbucketRepo(repo))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search repositories: %w", err)
+	}
+	return repos, nil
+}
+
+// ListOrgMembers lists the members of a workspace
+func (p *BitbucketProvider) ListOrgMembers(ctx context.Context, workspace string) ([]string, error) {
+	var members []string
+	path := "/workspaces/" + url.PathEscape(workspace) + "/members?pagelen=100"
+	err := eachBitbucket(ctx, p, path, func(m struct {
+		User bitbucketUser `json:"user"`
+	}) error {
+		members = append(members, m.User.login())
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members of %s: %w", workspace, err)
+	}
+	return members, nil
+}
+
+// ListUserProjects lists the repositories of a user's personal workspace
+func (p *BitbucketProvider) ListUserProjects(ctx context.Context, username string) ([]Repository, error) {
+	return p.ListGroupProjects(ctx, username)
+}
+
+// ListGroups lists the workspaces the user has access to
+func (p *BitbucketProvider) ListGroups(ctx context.Context) ([]Group, error) {
+	var groups []Group
+	err := eachBitbucket(ctx, p, "/user/permissions/workspaces?pagelen=100", func(perm struct {
+		Workspace struct {
+			Slug string `json:"slug"`
+			Name string `json:"name"`
+		} `json:"workspace"`
+	}) error {
+		groups = append(groups, Group{
+			Name:     perm.Workspace.Name,
+			FullPath: perm.Workspace.Slug,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+	return groups, nil
+}
+
+// GetProject gets a single repository by path (workspace/repo)
+func (p *BitbucketProvider) GetProject(ctx context.Context, projectPath string) (*Repository, error) {
+	workspace, slug, err := splitRepoPath(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var repo bitbucketRepo
+	if _, err := p.do(ctx, http.MethodGet, "/repositories/"+workspace+"/"+slug, nil, &repo); err != nil {
+		return nil, fmt.Errorf("failed to get repository %s: %w", projectPath, err)
+	}
+	result := convertBitbucketRepo(repo)
+	return &result, nil
+}
+
+// CreateRepository creates an empty git repository in a workspace. Bitbucket derives
+// repository slugs from the name in lowercase.
+func (p *BitbucketProvider) CreateRepository(ctx context.Context, namespace string, repo Repository) (*Repository, error) {
+	body := map[string]any{
+		"scm":         "git",
+		"is_private":  repo.Private,
+		"description": repo.Description,
+	}
+	path := "/repositories/" + url.PathEscape(namespace) + "/" + url.PathEscape(strings.ToLower(repo.Name))
+
+	var created bitbucketRepo
+	if _, err := p.do(ctx, http.MethodPost, path, body, &created); err != nil {
+		return nil, fmt.Errorf("failed to create repository %s/%s: %w", namespace, repo.Name, err)
+	}
+	result := convertBitbucketRepo(created)
+	return &result, nil
+}
+
+// BranchCommitDate returns the date of the branch's head commit
+func (p *BitbucketProvider) BranchCommitDate(ctx context.Context, projectPath, branch string) (time.Time, error) {
+	workspace, slug, err := splitRepoPath(projectPath)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var ref struct {
+		Target struct {
+			Date time.Time `json:"date"`
+		} `json:"target"`
+	}
+	path := "/repositories/" + workspace + "/" + slug + "/refs/branches/" + url.PathEscape(branch)
+	if _, err := p.do(ctx, http.MethodGet, path, nil, &ref); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get branch %s of %s: %w", branch, projectPath, err)
+	}
+	if ref.Target.Date.IsZero() {
+		return time.Time{}, fmt.Errorf("branch %s of %s has no commit date", branch, projectPath)
+	}
+	return ref.Target.Date, nil
+}
+
+// PrimaryLanguage returns the language set on a repository
+func (p *BitbucketProvider) PrimaryLanguage(ctx context.Context, projectPath string) (string, error) {
+	repo, err := p.GetProject(ctx, projectPath)
+	if err != nil {
+		return "", err
+	}
+	return repo.Language, nil
+}
+
+// RepositorySize returns the size Bitbucket reports for a repository
+func (p *BitbucketProvider) RepositorySize(ctx context.Context, projectPath string) (int64, error) {
+	repo, err := p.GetProject(ctx, projectPath)
+	if err != nil {
+		return 0, err
+	}
+	return repo.Size, nil
+}
+
+// ListReleases is not supported: Bitbucket has no releases
+func (p *BitbucketProvider) ListReleases(ctx context.Context, projectPath string) ([]Release, error) {
+	return nil, bitbucketNotSupported("releases")
+}
+
+// DownloadReleaseAsset is not supported: Bitbucket has no releases
+func (p *BitbucketProvider) DownloadReleaseAsset(ctx context.Context, projectPath string, asset ReleaseAsset, w io.Writer) error {
+	return bitbucketNotSupported("releases")
+}
+
+// ListEnvironments is not supported: Bitbucket deployment environments have no protection API
+func (p *BitbucketProvider) ListEnvironments(ctx context.Context, projectPath string) ([]Environment, error) {
+	return nil, bitbucketNotSupported("environments")
+}
+
+// HasUnprotectedEnvironment is not supported (see ListEnvironments)
+func (p *BitbucketProvider) HasUnprotectedEnvironment(ctx context.Context, projectPath string) (bool, error) {
+	return false, bitbucketNotSupported("environments")
+}
+
+// ProtectEnvironment is not supported (see ListEnvironments)
+func (p *BitbucketProvider) ProtectEnvironment(ctx context.Context, projectPath, envName string, rule ProtectionRule) error {
+	return bitbucketNotSupported("environment protection")
+}
+
+// IsEnvironmentProtected is not supported (see ListEnvironments)
+func (p *BitbucketProvider) IsEnvironmentProtected(ctx context.Context, projectPath, envName string) (bool, error) {
+	return false, bitbucketNotSupported("environment protection")
+}
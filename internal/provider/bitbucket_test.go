@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBitbucketListGroupProjects(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "app-pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"type": "error", "error": {"message": "Invalid credentials"}}`)
+			return
+		}
+		if r.URL.Path != "/repositories/acme" {
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("page") == "" {
+			fmt.Fprintf(w, `{"values": [{"name": "API", "full_name": "acme/api", "is_private": true,
+				"mainbranch": {"name": "main"}, "size": 2048, "updated_on": "2025-01-02T03:04:05.000000+00:00",
+				"links": {"clone": [{"name": "https", "href": "https://alice@bitbucket.org/acme/api.git"},
+				{"name": "ssh", "href": "git@bitbucket.org:acme/api.git"}]}}],
+				"next": "%s/repositories/acme?pagelen=100&page=2"}`, srv.URL)
+			return
+		}
+		fmt.Fprint(w, `{"values": [{"name": "web", "full_name": "acme/web"}]}`)
+	}))
+	defer srv.Close()
+
+	p, err := NewBitbucketProvider("alice:app-pass", srv.URL)
+	if err != nil {
+		t.Fatalf("NewBitbucketProvider() error = %v", err)
+	}
+	repos, err := p.ListGroupProjects(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("ListGroupProjects() error = %v", err)
+	}
+	if len(repos) != 2 || repos[1].FullPath != "acme/web" {
+		t.Fatalf("ListGroupProjects() = %+v, want acme/api and acme/web across two pages", repos)
+	}
+	api := repos[0]
+	if api.CloneURL != "https://bitbucket.org/acme/api.git" || api.SSHUrl != "git@bitbucket.org:acme/api.git" {
+		t.Errorf("clone URLs = %q, %q", api.CloneURL, api.SSHUrl)
+	}
+	if !api.Private || api.DefaultBranch != "main" || api.Size != 2048 || api.LastUpdated.Year() != 2025 ||
+		api.Owner != "acme" || api.Namespace != "acme" {
+		t.Errorf("repo = %+v", api)
+	}
+
+	// A rejected token is reported like the other providers' 401s
+	bad, _ := NewBitbucketProvider("bearer-token", srv.URL)
+	err = bad.TestConnection(context.Background())
+	if !IsUnauthorized(err) || !strings.Contains(err.Error(), "Invalid credentials") {
+		t.Errorf("TestConnection() with a bad token = %v, want a 401 with the API message", err)
+	}
+}
+
+func TestBitbucketProvider(t *testing.T) {
+	if got := DetectProvider("https://bitbucket.org/acme"); got != ProviderBitbucket {
+		t.Errorf("DetectProvider(bitbucket.org) = %s, want bitbucket", got)
+	}
+
+	p, err := NewBitbucketProvider("", "https://bitbucket.org")
+	if err != nil || p.apiURL != bitbucketAPI {
+		t.Fatalf("NewBitbucketProvider(bitbucket.org) API = %q, %v; want %s", p.apiURL, err, bitbucketAPI)
+	}
+	if caps := p.Capabilities(); caps.Environments || caps.Releases {
+		t.Errorf("Capabilities() = %+v, want no environments or releases", caps)
+	}
+	if _, err := p.ListEnvironments(context.Background(), "acme/api"); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("ListEnvironments() error = %v, want not supported", err)
+	}
+}
+
+func TestBitbucketRetriesTransientErrors(t *testing.T) {
+	saved := bitbucketRetry
+	bitbucketRetry.BaseDelay = time.Millisecond
+	defer func() { bitbucketRetry = saved }()
+
+	calls := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.Method+" "+r.URL.Path]++
+		switch {
+		case r.URL.Path == "/user" && calls["GET /user"] < 3:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case r.URL.Path == "/user":
+			fmt.Fprint(w, `{"username": "alice"}`)
+		case r.URL.Path == "/private":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	p, _ := NewBitbucketProvider("token", srv.URL)
+	ctx := context.Background()
+	if user, err := p.GetCurrentUser(ctx); err != nil || user != "alice" {
+		t.Errorf("GetCurrentUser() = %q, %v; want alice after two 429s", user, err)
+	}
+	if _, err := p.do(ctx, http.MethodGet, "/private", nil, nil); err == nil || calls["GET /private"] != 1 {
+		t.Errorf("403 sent %d requests (err = %v), want 1 without retries", calls["GET /private"], err)
+	}
+	if _, err := p.do(ctx, http.MethodGet, "/down", nil, nil); err == nil || calls["GET /down"] != bitbucketRetry.MaxAttempts {
+		t.Errorf("503 sent %d requests (err = %v), want %d", calls["GET /down"], err, bitbucketRetry.MaxAttempts)
+	}
+	if _, err := p.do(ctx, http.MethodPost, "/down", map[string]string{}, nil); err == nil || calls["POST /down"] != 1 {
+		t.Errorf("POST sent %d requests (err = %v), want 1: writes are not retried", calls["POST /down"], err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"0", 0},
+		{"-5", 0},
+		{"soon", 0},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.header); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.header, got, tt.want)
+		}
+	}
+
+	// An HTTP date in the future waits until then (second precision)
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got < 58*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %s, want about a minute", date, got)
+	}
+
+	// The wait reaches the retry policy through the error
+	err := fmt.Errorf("list repos: %w", &bitbucketError{StatusCode: http.StatusTooManyRequests, RetryAfter: 30 * time.Second})
+	if got := bitbucketRetryAfter(err); got != 30*time.Second {
+		t.Errorf("bitbucketRetryAfter() = %s, want 30s", got)
+	}
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DebugOutput, when set before a provider is created, receives one line per API
+// request with its method, URL (credentials redacted), status, and duration
+var DebugOutput io.Writer
+
+// sensitiveParams are query parameters that can carry credentials
+var sensitiveParams = []string{"private_token", "access_token", "job_token", "token", "client_secret"}
+
+// debugTransport logs every request that passes through it
+type debugTransport struct {
+	base http.RoundTripper
+	w    io.Writer
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	duration := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		fmt.Fprintf(t.w, "[debug] %s %s -> error: %v (%s)\n", req.Method, redactURL(req.URL), err, duration)
+		return resp, err
+	}
+	fmt.Fprintf(t.w, "[debug] %s %s -> %d (%s)\n", req.Method, redactURL(req.URL), resp.StatusCode, duration)
+	return resp, nil
+}
+
+// debugHTTPClient returns a client that logs to DebugOutput, or nil when debugging is off
+// so the API libraries use their default client
+func debugHTTPClient() *http.Client {
+	if DebugOutput == nil {
+		return nil
+	}
+	return &http.Client{Transport: &debugTransport{base: http.DefaultTransport, w: DebugOutput}}
+}
+
+// redactURL returns u as a string with user info and credential query parameters masked
+func redactURL(u *url.URL) string {
+	redacted := *u
+	if redacted.User != nil {
+		redacted.User = url.User("REDACTED")
+	}
+
+	query := redacted.Query()
+	changed := false
+	for key := range query {
+		for _, param := range sensitiveParams {
+			if strings.EqualFold(key, param) {
+				query.Set(key, "REDACTED")
+				changed = true
+			}
+		}
+	}
+	if changed {
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.String()
+}
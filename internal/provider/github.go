@@ -2,7 +2,10 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
@@ -12,8 +15,9 @@ import (
 
 // GitHubProvider implements the Provider interface for GitHub
 type GitHubProvider struct {
-	client  *github.Client
-	baseURL string
+	client     *github.Client
+	baseURL    string
+	enterprise bool // GitHub Enterprise Server instead of github.com
 }
 
 // NewGitHubProvider creates a new GitHub provider instance
@@ -22,54 +26,189 @@ func NewGitHubProvider(token, baseURL string) (*GitHubProvider, error) {
 	var client *github.Client
 
 	if token != "" {
-		client = github.NewClient(nil).WithAuthToken(token)
+		client = github.NewClient(ssoHTTPClient()).WithAuthToken(token)
 	} else {
-		client = github.NewClient(nil) // Unauthenticated - works for public repos
+		client = github.NewClient(ssoHTTPClient()) // Unauthenticated - works for public repos
 	}
 
-	// Handle GitHub Enterprise
-	if baseURL != "" && !strings.Contains(baseURL, "github.com") {
-		baseURL = strings.TrimSuffix(baseURL, "/")
-		apiURL := baseURL + "/api/v3/"
-		uploadURL := baseURL + "/api/uploads/"
-
-		parsedAPI, err := url.Parse(apiURL)
-		if err != nil {
-			return nil, fmt.Errorf("invalid base URL: %w", err)
-		}
-		parsedUpload, err := url.Parse(uploadURL)
-		if err != nil {
-			return nil, fmt.Errorf("invalid upload URL: %w", err)
-		}
-
-		client.BaseURL = parsedAPI
-		client.UploadURL = parsedUpload
+	if baseURL == "" || isGitHubDotCom(baseURL) {
+		return &GitHubProvider{
+			client:  client,
+			baseURL: "https://github.com",
+		}, nil
 	}
 
-	if baseURL == "" {
-		baseURL = "https://github.com"
+	// Handle GitHub Enterprise (API at <root>/api/v3/, uploads at <root>/api/uploads/)
+	root, err := enterpriseRoot(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	client, err = client.WithEnterpriseURLs(root, root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
 	}
 
 	return &GitHubProvider{
-		client:  client,
-		baseURL: baseURL,
+		client:     client,
+		baseURL:    root,
+		enterprise: true,
 	}, nil
 }
 
+// isGitHubDotCom reports whether a URL points at public github.com
+func isGitHubDotCom(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Hostname()) {
+	case "github.com", "www.github.com", "api.github.com":
+		return true
+	}
+	return false
+}
+
+// enterpriseRoot normalizes a GitHub Enterprise URL to its web root.
+// Accepts trailing slashes, path-prefixed mounts (https://host/ghe), and URLs
+// that already include the /api/v3 API mount.
+func enterpriseRoot(baseURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid base URL %q: must include scheme and host (e.g., https://github.example.com)", baseURL)
+	}
+
+	path := strings.TrimRight(u.Path, "/")
+	path = strings.TrimSuffix(path, "/api/v3")
+	u.Path = path
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String(), nil
+}
+
 // Name returns the provider name
 func (p *GitHubProvider) Name() string {
 	return "github"
 }
 
+// Capabilities reports the optional features of GitHub. Orgs do not nest.
+func (p *GitHubProvider) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{Environments: true, DeploymentRules: true, Releases: true}
+}
+
 // TestConnection tests the API connection and token validity
 func (p *GitHubProvider) TestConnection(ctx context.Context) error {
-	_, _, err := p.client.Users.Get(ctx, "")
+	_, resp, err := p.client.Users.Get(ctx, "")
+	if ssoErr := asSSORequired(err); ssoErr != nil {
+		return fmt.Errorf("GitHub connection test failed: %w", ssoErr)
+	}
 	if err != nil {
+		// A 404 or non-API response means the URL is not a GitHub Enterprise API mount
+		if p.enterprise && resp != nil && (resp.StatusCode == http.StatusNotFound || !isJSONResponse(resp)) {
+			return fmt.Errorf("GitHub connection test failed: no GitHub Enterprise API found at %s (check the base URL): %w", p.client.BaseURL, err)
+		}
 		return fmt.Errorf("GitHub connection test failed: %w", err)
 	}
 	return nil
 }
 
+// isGitHubUnauthorized reports whether err wraps a 401 response from the GitHub API
+func isGitHubUnauthorized(err error) bool {
+	var errResp *github.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusUnauthorized
+}
+
+// isGitHubNotFound reports whether err wraps a 404 response from the GitHub API
+func isGitHubNotFound(err error) bool {
+	var errResp *github.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound
+}
+
+// SSORequiredError is returned for requests to a GitHub org that enforces SAML single
+// sign-on when the token has not been authorized for that org
+type SSORequiredError struct {
+	Org string // Owner named in the request path; empty if not found
+	URL string // Where to authorize the token; empty if GitHub did not send one
+}
+
+func (e *SSORequiredError) Error() string {
+	org := "the organization"
+	if e.Org != "" {
+		org = "GitHub org " + e.Org
+	}
+	where := "under Settings > Developer settings > Personal access tokens > Configure SSO"
+	if e.URL != "" {
+		where = "at " + e.URL
+	}
+	return fmt.Sprintf("%s enforces SAML single sign-on and the token is not authorized for it; authorize the token %s", org, where)
+}
+
+// ssoTransport turns 403 responses that GitHub marks with "X-GitHub-SSO: required; url=..."
+// into an SSORequiredError, so every API call reports them the same way
+type ssoTransport struct {
+	base http.RoundTripper
+}
+
+func (t *ssoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		return resp, err
+	}
+	sso, ok := strings.CutPrefix(resp.Header.Get("X-GitHub-SSO"), "required")
+	if !ok {
+		return resp, nil
+	}
+	resp.Body.Close()
+	ssoErr := &SSORequiredError{Org: requestOwner(req.URL.Path)}
+	for _, part := range strings.Split(sso, ";") {
+		if u, ok := strings.CutPrefix(strings.TrimSpace(part), "url="); ok {
+			ssoErr.URL = u
+		}
+	}
+	return nil, ssoErr
+}
+
+// ssoHTTPClient returns the GitHub API client, with SSO detection on top of debug logging if on
+func ssoHTTPClient() *http.Client {
+	base := http.DefaultTransport
+	if debug := debugHTTPClient(); debug != nil {
+		base = debug.Transport
+	}
+	return &http.Client{Transport: &ssoTransport{base: base}}
+}
+
+// requestOwner returns the org or owner of an API path such as /orgs/<org>/repos or
+// /api/v3/repos/<owner>/<repo>, or "" for other paths
+func requestOwner(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "orgs" || parts[i] == "repos" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// asSSORequired returns the SSORequiredError wrapped in err, or nil. Callers return it
+// directly instead of err, whose request URL only gets in the way of the message.
+func asSSORequired(err error) *SSORequiredError {
+	var ssoErr *SSORequiredError
+	if errors.As(err, &ssoErr) {
+		return ssoErr
+	}
+	return nil
+}
+
+// isJSONResponse reports whether an API response carries a JSON body
+func isJSONResponse(resp *github.Response) bool {
+	if resp.Response == nil {
+		return false
+	}
+	return strings.Contains(resp.Header.Get("Content-Type"), "json")
+}
+
 // GetCurrentUser returns the authenticated user's username
 func (p *GitHubProvider) GetCurrentUser(ctx context.Context) (string, error) {
 	user, _, err := p.client.Users.Get(ctx, "")
@@ -79,6 +218,26 @@ func (p *GitHubProvider) GetCurrentUser(ctx context.Context) (string, error) {
 	return user.GetLogin(), nil
 }
 
+// TokenScopes returns the scopes of a classic token from the X-OAuth-Scopes header.
+// Fine-grained tokens and app tokens do not send it, so their scopes are unknown.
+func (p *GitHubProvider) TokenScopes(ctx context.Context) ([]string, bool, error) {
+	_, resp, err := p.client.Users.Get(ctx, "")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get current user: %w", err)
+	}
+	header, ok := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]
+	if !ok {
+		return nil, false, nil
+	}
+	var scopes []string
+	for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, true, nil
+}
+
 // ListGroupProjects lists all repositories in an organization or user account
 func (p *GitHubProvider) ListGroupProjects(ctx context.Context, ownerName string) ([]Repository, error) {
 	var repos []Repository
@@ -88,6 +247,10 @@ func (p *GitHubProvider) ListGroupProjects(ctx context.Context, ownerName string
 	if err == nil {
 		return orgRepos, nil
 	}
+	if ssoErr := asSSORequired(err); ssoErr != nil {
+		// The org exists; listing it as a user would only hide why it failed
+		return nil, fmt.Errorf("failed to list repositories for %s: %w", ownerName, ssoErr)
+	}
 
 	// If org fails, try as user
 	userRepos, userErr := p.listUserRepos(ctx, ownerName)
@@ -99,38 +262,83 @@ func (p *GitHubProvider) ListGroupProjects(ctx context.Context, ownerName string
 	return repos, nil
 }
 
+// ListGroupProjectsStream calls fn for each repository of an organization or user
+// account as pages arrive. Like ListGroupProjects, a name that is not an org is
+// listed as a user, as long as nothing was emitted yet.
+func (p *GitHubProvider) ListGroupProjectsStream(ctx context.Context, ownerName string, fn func(Repository) error) error {
+	return p.eachOwnerRepo(ctx, ownerName, 100, fn)
+}
+
+// ProbeGroup checks that an org or user can be listed, fetching at most one repo
+func (p *GitHubProvider) ProbeGroup(ctx context.Context, ownerName string) (bool, error) {
+	return probeFirst(func(fn func(Repository) error) error {
+		return p.eachOwnerRepo(ctx, ownerName, 1, fn)
+	})
+}
+
+// eachOwnerRepo calls fn for each repo of an org, or of a user if ownerName is not an
+// org, fetching perPage repos per request
+func (p *GitHubProvider) eachOwnerRepo(ctx context.Context, ownerName string, perPage int, fn func(Repository) error) error {
+	emitted := false
+	err := p.eachOrgRepo(ctx, ownerName, perPage, func(repo Repository) error {
+		emitted = true
+		return fn(repo)
+	})
+	if err == nil || emitted {
+		return err
+	}
+	if ssoErr := asSSORequired(err); ssoErr != nil {
+		return fmt.Errorf("failed to list repositories for %s: %w", ownerName, ssoErr)
+	}
+
+	if userErr := p.eachUserRepo(ctx, ownerName, perPage, fn); userErr != nil {
+		return fmt.Errorf("failed to list repositories for %s (org error: %v, user error: %w)", ownerName, err, userErr)
+	}
+	return nil
+}
+
 // listOrgRepos lists repositories for an organization
 func (p *GitHubProvider) listOrgRepos(ctx context.Context, orgName string) ([]Repository, error) {
 	var repos []Repository
+	err := p.eachOrgRepo(ctx, orgName, 100, func(repo Repository) error {
+		repos = append(repos, repo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
 
+// eachOrgRepo calls fn for every repository of an organization as each page of perPage
+// arrives. Raw API objects are converted to the lean Repository and dropped page by
+// page, so memory is bounded by what fn keeps rather than by the size of the org.
+func (p *GitHubProvider) eachOrgRepo(ctx context.Context, orgName string, perPage int, fn func(Repository) error) error {
 	opts := &github.RepositoryListByOrgOptions{
 		ListOptions: github.ListOptions{
-			PerPage: 100,
+			PerPage: perPage,
 		},
 	}
 
+	retries := 0
 	for {
 		ghRepos, resp, err := p.client.Repositories.ListByOrg(ctx, orgName, opts)
 		if err != nil {
-			return nil, err
+			if retries >= maxRateLimitRetries {
+				return err
+			}
+			if waitErr := waitForSecondaryRateLimit(ctx, err); waitErr != nil {
+				return waitErr
+			}
+			retries++
+			continue
 		}
+		retries = 0
 
 		for _, repo := range ghRepos {
-			var lastUpdated time.Time
-			if repo.PushedAt != nil {
-				lastUpdated = repo.PushedAt.Time
+			if err := fn(convertGitHubRepo(repo)); err != nil {
+				return err
 			}
-			repos = append(repos, Repository{
-				ID:            repo.GetID(),
-				Name:          repo.GetName(),
-				FullPath:      repo.GetFullName(),
-				CloneURL:      repo.GetCloneURL(),
-				SSHUrl:        repo.GetSSHURL(),
-				DefaultBranch: repo.GetDefaultBranch(),
-				Archived:      repo.GetArchived(),
-				LastUpdated:   lastUpdated,
-				Size:          int64(repo.GetSize()) * 1024, // GitHub returns KB, convert to bytes
-			})
 		}
 
 		if resp.NextPage == 0 {
@@ -139,42 +347,50 @@ func (p *GitHubProvider) listOrgRepos(ctx context.Context, orgName string) ([]Re
 		opts.Page = resp.NextPage
 	}
 
-	return repos, nil
+	return nil
 }
 
 // listUserRepos lists repositories for a user
 func (p *GitHubProvider) listUserRepos(ctx context.Context, username string) ([]Repository, error) {
 	var repos []Repository
+	err := p.eachUserRepo(ctx, username, 100, func(repo Repository) error {
+		repos = append(repos, repo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
 
+// eachUserRepo calls fn for every repository owned by a user as each page of perPage arrives
+func (p *GitHubProvider) eachUserRepo(ctx context.Context, username string, perPage int, fn func(Repository) error) error {
 	opts := &github.RepositoryListByUserOptions{
 		Type: "owner", // Only repos owned by user, not forks
 		ListOptions: github.ListOptions{
-			PerPage: 100,
+			PerPage: perPage,
 		},
 	}
 
+	retries := 0
 	for {
 		ghRepos, resp, err := p.client.Repositories.ListByUser(ctx, username, opts)
 		if err != nil {
-			return nil, err
+			if retries >= maxRateLimitRetries {
+				return err
+			}
+			if waitErr := waitForSecondaryRateLimit(ctx, err); waitErr != nil {
+				return waitErr
+			}
+			retries++
+			continue
 		}
+		retries = 0
 
 		for _, repo := range ghRepos {
-			var lastUpdated time.Time
-			if repo.PushedAt != nil {
-				lastUpdated = repo.PushedAt.Time
+			if err := fn(convertGitHubRepo(repo)); err != nil {
+				return err
 			}
-			repos = append(repos, Repository{
-				ID:            repo.GetID(),
-				Name:          repo.GetName(),
-				FullPath:      repo.GetFullName(),
-				CloneURL:      repo.GetCloneURL(),
-				SSHUrl:        repo.GetSSHURL(),
-				DefaultBranch: repo.GetDefaultBranch(),
-				Archived:      repo.GetArchived(),
-				LastUpdated:   lastUpdated,
-				Size:          int64(repo.GetSize()) * 1024, // GitHub returns KB, convert to bytes
-			})
 		}
 
 		if resp.NextPage == 0 {
@@ -183,9 +399,177 @@ func (p *GitHubProvider) listUserRepos(ctx context.Context, username string) ([]
 		opts.Page = resp.NextPage
 	}
 
+	return nil
+}
+
+// ListOrgMembers lists the logins of an organization's members.
+// Only public members are returned unless the token can see private membership.
+func (p *GitHubProvider) ListOrgMembers(ctx context.Context, orgName string) ([]string, error) {
+	var members []string
+
+	opts := &github.ListMembersOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	retries := 0
+	for {
+		users, resp, err := p.client.Organizations.ListMembers(ctx, orgName, opts)
+		if err != nil {
+			if retries >= maxRateLimitRetries {
+				return nil, fmt.Errorf("failed to list members of %s: %w", orgName, err)
+			}
+			if waitErr := waitForSecondaryRateLimit(ctx, err); waitErr != nil {
+				return nil, fmt.Errorf("failed to list members of %s: %w", orgName, waitErr)
+			}
+			retries++
+			continue
+		}
+		retries = 0
+
+		for _, user := range users {
+			members = append(members, user.GetLogin())
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return members, nil
+}
+
+// ListUserProjects lists repositories owned by a user
+func (p *GitHubProvider) ListUserProjects(ctx context.Context, username string) ([]Repository, error) {
+	repos, err := p.listUserRepos(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories for %s: %w", username, err)
+	}
+	return repos, nil
+}
+
+// convertGitHubRepo converts a GitHub API repository to a Repository
+func convertGitHubRepo(repo *github.Repository) Repository {
+	var lastUpdated time.Time
+	if repo.PushedAt != nil {
+		lastUpdated = repo.PushedAt.Time
+	}
+	return Repository{
+		ID:            repo.GetID(),
+		Name:          repo.GetName(),
+		FullPath:      repo.GetFullName(),
+		Owner:         repo.GetOwner().GetLogin(),
+		Namespace:     repo.GetOwner().GetLogin(),
+		Description:   repo.GetDescription(),
+		CloneURL:      repo.GetCloneURL(),
+		SSHUrl:        repo.GetSSHURL(),
+		DefaultBranch: repo.GetDefaultBranch(),
+		Archived:      repo.GetArchived(),
+		Private:       repo.GetPrivate() || repo.GetVisibility() == "internal",
+		LastUpdated:   lastUpdated,
+		Size:          int64(repo.GetSize()) * 1024, // GitHub returns KB, convert to bytes
+		SizeKnown:     repo.Size != nil,
+		Language:      repo.GetLanguage(),
+	}
+}
+
+// githubSearchMaxResults is the hard cap GitHub places on search results per query
+const githubSearchMaxResults = 1000
+
+// SearchRepositories returns repositories matching a GitHub search query
+// (e.g., "topic:terraform org:zsoftly"). Search has a much lower rate limit
+// than the core API, so rate limit errors wait for the reset and retry.
+func (p *GitHubProvider) SearchRepositories(ctx context.Context, query string) ([]Repository, error) {
+	var repos []Repository
+
+	opts := &github.SearchOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	retries := 0
+	for {
+		result, resp, err := p.client.Search.Repositories(ctx, query, opts)
+		if err != nil {
+			if retries >= maxRateLimitRetries {
+				return nil, fmt.Errorf("failed to search repositories: %w", err)
+			}
+			if waitErr := waitForSearchRateLimit(ctx, err); waitErr != nil {
+				return nil, fmt.Errorf("failed to search repositories: %w", waitErr)
+			}
+			retries++
+			continue
+		}
+		retries = 0
+
+		for _, repo := range result.Repositories {
+			repos = append(repos, convertGitHubRepo(repo))
+		}
+
+		if resp.NextPage == 0 || len(repos) >= githubSearchMaxResults {
+			if result.GetTotal() > githubSearchMaxResults {
+				fmt.Printf("  ! Search matched %d repos; GitHub only returns the first %d\n", result.GetTotal(), githubSearchMaxResults)
+			}
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
 	return repos, nil
 }
 
+// maxRateLimitRetries bounds how often a single request is retried after a rate limit
+const maxRateLimitRetries = 5
+
+// waitForSearchRateLimit sleeps until a search rate limit resets.
+// Search has its own primary limit (30 requests/minute), so hitting it is routine.
+// Returns the original error if it is not a rate limit error, or ctx.Err() if cancelled.
+func waitForSearchRateLimit(ctx context.Context, err error) error {
+	var rateErr *github.RateLimitError
+	if !errors.As(err, &rateErr) {
+		return waitForSecondaryRateLimit(ctx, err)
+	}
+
+	wait := time.Until(rateErr.Rate.Reset.Time) + time.Second
+	if wait < time.Second {
+		wait = time.Second
+	}
+	fmt.Printf("  ! Search rate limit reached, waiting %s...\n", wait.Round(time.Second))
+	return sleepContext(ctx, wait)
+}
+
+// waitForSecondaryRateLimit sleeps for the duration GitHub recommends after a
+// secondary rate limit ("You have exceeded a secondary rate limit"), which is
+// triggered by request bursts rather than the hourly quota.
+// Returns the original error if it is not a secondary rate limit, or ctx.Err() if cancelled.
+func waitForSecondaryRateLimit(ctx context.Context, err error) error {
+	var abuseErr *github.AbuseRateLimitError
+	if !errors.As(err, &abuseErr) {
+		return err
+	}
+
+	// GitHub usually sends Retry-After; otherwise it recommends waiting at least a minute
+	wait := abuseErr.GetRetryAfter()
+	if wait <= 0 {
+		wait = time.Minute
+	}
+	fmt.Printf("  ! GitHub secondary rate limit hit, waiting %s before retrying...\n", wait.Round(time.Second))
+	return sleepContext(ctx, wait)
+}
+
+// sleepContext waits for d or until ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
 // ListGroups lists all accessible organizations
 func (p *GitHubProvider) ListGroups(ctx context.Context) ([]Group, error) {
 	var groups []Group
@@ -194,11 +578,20 @@ func (p *GitHubProvider) ListGroups(ctx context.Context) ([]Group, error) {
 		PerPage: 100,
 	}
 
+	retries := 0
 	for {
 		orgs, resp, err := p.client.Organizations.List(ctx, "", opts)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list organizations: %w", err)
+			if retries >= maxRateLimitRetries {
+				return nil, fmt.Errorf("failed to list organizations: %w", err)
+			}
+			if waitErr := waitForSecondaryRateLimit(ctx, err); waitErr != nil {
+				return nil, fmt.Errorf("failed to list organizations: %w", waitErr)
+			}
+			retries++
+			continue
 		}
+		retries = 0
 
 		for _, org := range orgs {
 			groups = append(groups, Group{
@@ -231,15 +624,139 @@ func (p *GitHubProvider) GetProject(ctx context.Context, projectPath string) (*R
 		return nil, fmt.Errorf("failed to get repository %s: %w", projectPath, err)
 	}
 
-	return &Repository{
-		ID:            repo.GetID(),
-		Name:          repo.GetName(),
-		FullPath:      repo.GetFullName(),
-		CloneURL:      repo.GetCloneURL(),
-		SSHUrl:        repo.GetSSHURL(),
-		DefaultBranch: repo.GetDefaultBranch(),
-		Archived:      repo.GetArchived(),
-	}, nil
+	result := convertGitHubRepo(repo)
+	return &result, nil
+}
+
+// CreateRepository creates an empty repository in an organization, or in the
+// authenticated user's account if namespace is their login
+func (p *GitHubProvider) CreateRepository(ctx context.Context, namespace string, repo Repository) (*Repository, error) {
+	org := namespace
+	if login, err := p.GetCurrentUser(ctx); err == nil && strings.EqualFold(login, namespace) {
+		org = "" // The API creates user repos without an org
+	}
+
+	created, _, err := p.client.Repositories.Create(ctx, org, &github.Repository{
+		Name:        github.String(repo.Name),
+		Description: github.String(repo.Description),
+		Private:     github.Bool(repo.Private),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repository %s/%s: %w", namespace, repo.Name, err)
+	}
+
+	result := convertGitHubRepo(created)
+	return &result, nil
+}
+
+// BranchCommitDate returns the committer date of the branch's head commit
+func (p *GitHubProvider) BranchCommitDate(ctx context.Context, projectPath, branch string) (time.Time, error) {
+	parts := strings.SplitN(projectPath, "/", 2)
+	if len(parts) != 2 {
+		return time.Time{}, fmt.Errorf("invalid project path: %s (expected owner/repo)", projectPath)
+	}
+
+	b, _, err := p.client.Repositories.GetBranch(ctx, parts[0], parts[1], branch, 1)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get branch %s of %s: %w", branch, projectPath, err)
+	}
+	date := b.GetCommit().GetCommit().GetCommitter().GetDate()
+	if date.IsZero() {
+		return time.Time{}, fmt.Errorf("branch %s of %s has no commit date", branch, projectPath)
+	}
+	return date.Time, nil
+}
+
+// PrimaryLanguage returns the language GitHub reports for a repository
+func (p *GitHubProvider) PrimaryLanguage(ctx context.Context, projectPath string) (string, error) {
+	parts := strings.SplitN(projectPath, "/", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid project path: %s (expected owner/repo)", projectPath)
+	}
+
+	repo, _, err := p.client.Repositories.Get(ctx, parts[0], parts[1])
+	if err != nil {
+		return "", fmt.Errorf("failed to get repository %s: %w", projectPath, err)
+	}
+	return repo.GetLanguage(), nil
+}
+
+// RepositorySize returns the size GitHub reports for a repository
+func (p *GitHubProvider) RepositorySize(ctx context.Context, projectPath string) (int64, error) {
+	parts := strings.SplitN(projectPath, "/", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid project path: %s (expected owner/repo)", projectPath)
+	}
+
+	repo, _, err := p.client.Repositories.Get(ctx, parts[0], parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("failed to get repository %s: %w", projectPath, err)
+	}
+	return int64(repo.GetSize()) * 1024, nil // GitHub returns KB
+}
+
+// ListReleases lists all releases and their assets for a repository
+func (p *GitHubProvider) ListReleases(ctx context.Context, projectPath string) ([]Release, error) {
+	parts := strings.SplitN(projectPath, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid project path: %s (expected owner/repo)", projectPath)
+	}
+
+	owner, repoName := parts[0], parts[1]
+
+	var releases []Release
+	opts := &github.ListOptions{
+		PerPage: 100,
+	}
+
+	for {
+		ghReleases, resp, err := p.client.Repositories.ListReleases(ctx, owner, repoName, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list releases for %s: %w", projectPath, err)
+		}
+
+		for _, r := range ghReleases {
+			release := Release{TagName: r.GetTagName()}
+			for _, a := range r.Assets {
+				release.Assets = append(release.Assets, ReleaseAsset{
+					ID:   a.GetID(),
+					Name: a.GetName(),
+					Size: int64(a.GetSize()),
+					URL:  a.GetBrowserDownloadURL(),
+				})
+			}
+			releases = append(releases, release)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return releases, nil
+}
+
+// DownloadReleaseAsset writes a release asset to w.
+// Uses the API endpoint so assets of private repositories are downloaded with the token.
+func (p *GitHubProvider) DownloadReleaseAsset(ctx context.Context, projectPath string, asset ReleaseAsset, w io.Writer) error {
+	parts := strings.SplitN(projectPath, "/", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid project path: %s (expected owner/repo)", projectPath)
+	}
+
+	owner, repoName := parts[0], parts[1]
+
+	rc, _, err := p.client.Repositories.DownloadReleaseAsset(ctx, owner, repoName, asset.ID, http.DefaultClient)
+	if err != nil {
+		return fmt.Errorf("failed to download asset %s: %w", asset.Name, err)
+	}
+	defer rc.Close()
+
+	if _, err := io.Copy(w, rc); err != nil {
+		return fmt.Errorf("failed to download asset %s: %w", asset.Name, err)
+	}
+	return nil
 }
 
 // ListEnvironments lists all environments for a repository
@@ -287,6 +804,41 @@ func (p *GitHubProvider) ListEnvironments(ctx context.Context, projectPath strin
 	return envs, nil
 }
 
+// HasUnprotectedEnvironment reports whether a repo has an environment without
+// protection rules, without fetching the pages after the first one found
+func (p *GitHubProvider) HasUnprotectedEnvironment(ctx context.Context, projectPath string) (bool, error) {
+	parts := strings.SplitN(projectPath, "/", 2)
+	if len(parts) != 2 {
+		return false, fmt.Errorf("invalid project path: %s (expected owner/repo)", projectPath)
+	}
+
+	owner, repoName := parts[0], parts[1]
+
+	opts := &github.EnvironmentListOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	for {
+		envResponse, resp, err := p.client.Repositories.ListEnvironments(ctx, owner, repoName, opts)
+		if err != nil {
+			return false, fmt.Errorf("failed to list environments for %s: %w", projectPath, err)
+		}
+
+		for _, e := range envResponse.Environments {
+			if len(e.ProtectionRules) == 0 {
+				return true, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return false, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
 // ProtectEnvironment protects an environment with the given rules
 // Note: GitHub's environment protection works differently than GitLab
 func (p *GitHubProvider) ProtectEnvironment(ctx context.Context, projectPath, envName string, rule ProtectionRule) error {
@@ -298,10 +850,26 @@ func (p *GitHubProvider) ProtectEnvironment(ctx context.Context, projectPath, en
 	owner, repoName := parts[0], parts[1]
 
 	// Create or update environment with protection
-	createEnv := &github.CreateUpdateEnvironment{
-		// GitHub uses reviewers for approval, different from GitLab's access levels
-		// For now, we'll just create the environment without specific reviewers
-		// as that requires team/user IDs
+	// GitHub uses reviewers for approval instead of GitLab's access levels; they are
+	// given by ID, see ResolveReviewers
+	createEnv := &github.CreateUpdateEnvironment{}
+	for _, r := range rule.Reviewers {
+		createEnv.Reviewers = append(createEnv.Reviewers, &github.EnvReviewers{
+			Type: github.String(r.Type),
+			ID:   github.Int64(r.ID),
+		})
+	}
+	if rule.WaitTimer > 0 {
+		createEnv.WaitTimer = github.Int(rule.WaitTimer)
+	}
+
+	customBranches := len(rule.DeployBranches) > 0 &&
+		!(len(rule.DeployBranches) == 1 && rule.DeployBranches[0] == DeployBranchesProtected)
+	if len(rule.DeployBranches) > 0 {
+		createEnv.DeploymentBranchPolicy = &github.BranchPolicy{
+			ProtectedBranches:    github.Bool(!customBranches),
+			CustomBranchPolicies: github.Bool(customBranches),
+		}
 	}
 
 	_, _, err := p.client.Repositories.CreateUpdateEnvironment(ctx, owner, repoName, envName, createEnv)
@@ -309,6 +877,69 @@ func (p *GitHubProvider) ProtectEnvironment(ctx context.Context, projectPath, en
 		return fmt.Errorf("failed to protect environment %s: %w", envName, err)
 	}
 
+	if customBranches {
+		if err := p.addDeploymentBranchPolicies(ctx, owner, repoName, envName, rule.DeployBranches); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ResolveReviewers looks up deployment reviewers given as user logins or org/team
+// slugs (a leading @ is ignored), so their IDs can be reused for many environments
+func (p *GitHubProvider) ResolveReviewers(ctx context.Context, names []string) ([]Reviewer, error) {
+	reviewers := make([]Reviewer, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+		if name == "" {
+			return nil, fmt.Errorf("empty reviewer name")
+		}
+		if org, slug, ok := strings.Cut(name, "/"); ok {
+			team, _, err := p.client.Teams.GetTeamBySlug(ctx, org, slug)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find team %s: %w", name, err)
+			}
+			reviewers = append(reviewers, Reviewer{Type: ReviewerTeam, ID: team.GetID(), Name: name})
+			continue
+		}
+		user, _, err := p.client.Users.Get(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find user %s: %w", name, err)
+		}
+		reviewers = append(reviewers, Reviewer{Type: ReviewerUser, ID: user.GetID(), Name: user.GetLogin()})
+	}
+	return reviewers, nil
+}
+
+// addDeploymentBranchPolicies adds branch name patterns allowed to deploy to an environment,
+// skipping patterns that already exist
+func (p *GitHubProvider) addDeploymentBranchPolicies(ctx context.Context, owner, repoName, envName string, patterns []string) error {
+	existing, _, err := p.client.Repositories.ListDeploymentBranchPolicies(ctx, owner, repoName, envName)
+	if err != nil {
+		return fmt.Errorf("failed to list deployment branch policies for %s: %w", envName, err)
+	}
+
+	have := make(map[string]bool)
+	if existing != nil {
+		for _, policy := range existing.BranchPolicies {
+			have[policy.GetName()] = true
+		}
+	}
+
+	for _, pattern := range patterns {
+		if have[pattern] {
+			continue
+		}
+		request := &github.DeploymentBranchPolicyRequest{
+			Name: github.String(pattern),
+			Type: github.String("branch"),
+		}
+		if _, _, err := p.client.Repositories.CreateDeploymentBranchPolicy(ctx, owner, repoName, envName, request); err != nil {
+			return fmt.Errorf("failed to add deployment branch %s to %s: %w", pattern, envName, err)
+		}
+	}
+
 	return nil
 }
 
@@ -331,3 +962,61 @@ func (p *GitHubProvider) IsEnvironmentProtected(ctx context.Context, projectPath
 
 	return env.ProtectionRules != nil && len(env.ProtectionRules) > 0, nil
 }
+
+// ListWebhooks lists the webhooks of a repo
+func (p *GitHubProvider) ListWebhooks(ctx context.Context, projectPath string) ([]Webhook, error) {
+	parts := strings.SplitN(projectPath, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid project path: %s (expected owner/repo)", projectPath)
+	}
+
+	owner, repoName := parts[0], parts[1]
+
+	var hooks []Webhook
+	opts := &github.ListOptions{PerPage: 100}
+
+	for {
+		githubHooks, resp, err := p.client.Repositories.ListHooks(ctx, owner, repoName, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list webhooks for %s: %w", projectPath, err)
+		}
+
+		for _, h := range githubHooks {
+			url, _ := h.Config["url"].(string)
+			hooks = append(hooks, Webhook{
+				ID:     h.GetID(),
+				URL:    url,
+				Events: h.Events,
+				Active: h.GetActive(),
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return hooks, nil
+}
+
+// UpdateWebhook changes the settings of a repo webhook. Only the configuration fields
+// given are sent, so the URL, content type, and TLS setting are kept.
+func (p *GitHubProvider) UpdateWebhook(ctx context.Context, projectPath string, hook Webhook, update WebhookUpdate) error {
+	parts := strings.SplitN(projectPath, "/", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid project path: %s (expected owner/repo)", projectPath)
+	}
+
+	owner, repoName := parts[0], parts[1]
+
+	config := &github.HookConfig{}
+	if update.Secret != "" {
+		config.Secret = github.String(update.Secret)
+	}
+
+	if _, _, err := p.client.Repositories.EditHookConfiguration(ctx, owner, repoName, hook.ID, config); err != nil {
+		return fmt.Errorf("failed to update webhook %d of %s: %w", hook.ID, projectPath, err)
+	}
+	return nil
+}
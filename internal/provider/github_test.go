@@ -0,0 +1,547 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewGitHubProvider_EnterpriseURLs(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseURL    string
+		wantAPI    string
+		wantUpload string
+	}{
+		{"plain host", "https://ghe.acme.com", "https://ghe.acme.com/api/v3/", "https://ghe.acme.com/api/uploads/"},
+		{"trailing slash", "https://ghe.acme.com/", "https://ghe.acme.com/api/v3/", "https://ghe.acme.com/api/uploads/"},
+		{"path prefix", "https://acme.com/github", "https://acme.com/github/api/v3/", "https://acme.com/github/api/uploads/"},
+		{"explicit api mount", "https://ghe.acme.com/api/v3/", "https://ghe.acme.com/api/v3/", "https://ghe.acme.com/api/uploads/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewGitHubProvider("", tt.baseURL)
+			if err != nil {
+				t.Fatalf("NewGitHubProvider(%q) error = %v", tt.baseURL, err)
+			}
+			if !p.enterprise {
+				t.Errorf("NewGitHubProvider(%q) not detected as enterprise", tt.baseURL)
+			}
+			if got := p.client.BaseURL.String(); got != tt.wantAPI {
+				t.Errorf("API URL = %q, want %q", got, tt.wantAPI)
+			}
+			if got := p.client.UploadURL.String(); got != tt.wantUpload {
+				t.Errorf("upload URL = %q, want %q", got, tt.wantUpload)
+			}
+		})
+	}
+}
+
+func TestNewGitHubProvider_PublicGitHub(t *testing.T) {
+	for _, baseURL := range []string{"", "https://github.com", "https://github.com/"} {
+		p, err := NewGitHubProvider("", baseURL)
+		if err != nil {
+			t.Fatalf("NewGitHubProvider(%q) error = %v", baseURL, err)
+		}
+		if p.enterprise {
+			t.Errorf("NewGitHubProvider(%q) detected as enterprise", baseURL)
+		}
+		if got := p.client.BaseURL.String(); got != "https://api.github.com/" {
+			t.Errorf("NewGitHubProvider(%q) API URL = %q, want https://api.github.com/", baseURL, got)
+		}
+	}
+}
+
+func TestNewGitHubProvider_InvalidURL(t *testing.T) {
+	if _, err := NewGitHubProvider("", "ghe.acme.com"); err == nil {
+		t.Error("Expected error for base URL without scheme, got nil")
+	}
+}
+
+func TestGitHubTestConnection_EnterprisePathPrefix(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/github/api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"login":"octocat"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := NewGitHubProvider("token", server.URL+"/github")
+	if err != nil {
+		t.Fatalf("NewGitHubProvider error = %v", err)
+	}
+	if err := p.TestConnection(context.Background()); err != nil {
+		t.Errorf("TestConnection with path-prefixed mount failed: %v", err)
+	}
+
+	// Wrong mount: the probe should fail with a hint about the base URL
+	wrong, err := NewGitHubProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitHubProvider error = %v", err)
+	}
+	if err := wrong.TestConnection(context.Background()); err == nil {
+		t.Error("Expected TestConnection to fail for wrong mount, got nil")
+	}
+}
+
+func TestGitHubProtectEnvironment_WaitTimerAndBranches(t *testing.T) {
+	var envBody map[string]any
+	var created []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /api/v3/repos/acme/app/environments/prod", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&envBody)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"prod"}`))
+	})
+	mux.HandleFunc("GET /api/v3/repos/acme/app/environments/prod/deployment-branch-policies", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"total_count":1,"branch_policies":[{"name":"main"}]}`))
+	})
+	mux.HandleFunc("POST /api/v3/repos/acme/app/environments/prod/deployment-branch-policies", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		created = append(created, req["name"])
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := NewGitHubProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitHubProvider error = %v", err)
+	}
+
+	rule := ProtectionRule{WaitTimer: 30, DeployBranches: []string{"main", "release/*"}}
+	if err := p.ProtectEnvironment(context.Background(), "acme/app", "prod", rule); err != nil {
+		t.Fatalf("ProtectEnvironment error = %v", err)
+	}
+
+	if envBody["wait_timer"] != float64(30) {
+		t.Errorf("wait_timer = %v, want 30", envBody["wait_timer"])
+	}
+	policy, _ := envBody["deployment_branch_policy"].(map[string]any)
+	if policy["custom_branch_policies"] != true || policy["protected_branches"] != false {
+		t.Errorf("deployment_branch_policy = %v, want custom branches", policy)
+	}
+	// "main" already exists and must not be added again
+	if len(created) != 1 || created[0] != "release/*" {
+		t.Errorf("created branch policies = %v, want [release/*]", created)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	github, err := NewGitHubProvider("", "")
+	if err != nil {
+		t.Fatalf("NewGitHubProvider error = %v", err)
+	}
+	gitlab, err := NewGitLabProvider("", "")
+	if err != nil {
+		t.Fatalf("NewGitLabProvider error = %v", err)
+	}
+
+	if caps := github.Capabilities(); !caps.DeploymentRules || caps.Subgroups {
+		t.Errorf("GitHub capabilities = %+v, want deployment rules and no subgroups", caps)
+	}
+	if caps := gitlab.Capabilities(); caps.DeploymentRules || !caps.Subgroups {
+		t.Errorf("GitLab capabilities = %+v, want subgroups and no deployment rules", caps)
+	}
+	// Providers with deployment rules must be able to resolve --reviewers
+	for _, p := range []Provider{github, gitlab} {
+		if _, ok := p.(ReviewerResolver); ok != p.Capabilities().DeploymentRules {
+			t.Errorf("%s: ReviewerResolver = %v, DeploymentRules = %v", p.Name(), ok, p.Capabilities().DeploymentRules)
+		}
+	}
+}
+
+func TestGitHubResolveReviewers(t *testing.T) {
+	var envBody map[string]any
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/users/alice", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"login":"alice","id":11}`))
+	})
+	mux.HandleFunc("GET /api/v3/orgs/acme/teams/platform", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"slug":"platform","id":22}`))
+	})
+	mux.HandleFunc("PUT /api/v3/repos/acme/app/environments/prod", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&envBody)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"prod"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := NewGitHubProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitHubProvider error = %v", err)
+	}
+
+	reviewers, err := p.ResolveReviewers(context.Background(), []string{"alice", "@acme/platform"})
+	if err != nil {
+		t.Fatalf("ResolveReviewers error = %v", err)
+	}
+	want := []Reviewer{
+		{Type: ReviewerUser, ID: 11, Name: "alice"},
+		{Type: ReviewerTeam, ID: 22, Name: "acme/platform"},
+	}
+	if len(reviewers) != len(want) || reviewers[0] != want[0] || reviewers[1] != want[1] {
+		t.Errorf("ResolveReviewers = %+v, want %+v", reviewers, want)
+	}
+
+	if err := p.ProtectEnvironment(context.Background(), "acme/app", "prod", ProtectionRule{Reviewers: reviewers}); err != nil {
+		t.Fatalf("ProtectEnvironment error = %v", err)
+	}
+	got, _ := json.Marshal(envBody["reviewers"])
+	if string(got) != `[{"id":11,"type":"User"},{"id":22,"type":"Team"}]` {
+		t.Errorf("reviewers = %s, want users and teams by ID", got)
+	}
+
+	if _, err := p.ResolveReviewers(context.Background(), []string{"ghost"}); err == nil {
+		t.Error("ResolveReviewers of an unknown user should fail")
+	}
+}
+
+func TestGitHubTokenScopes(t *testing.T) {
+	scopes := "repo, admin:org"
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		if scopes != "" {
+			w.Header().Set("X-OAuth-Scopes", scopes)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"login":"alice"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := NewGitHubProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitHubProvider error = %v", err)
+	}
+
+	got, ok, err := p.TokenScopes(context.Background())
+	if err != nil || !ok || strings.Join(got, " ") != "repo admin:org" {
+		t.Fatalf("TokenScopes = %v, %v, %v; want [repo admin:org]", got, ok, err)
+	}
+	// admin:org implies read:org
+	if missing := MissingScopes(got, RequiredScopes(ProviderGitHub, OperationMirror)); len(missing) != 0 {
+		t.Errorf("MissingScopes = %v, want none", missing)
+	}
+
+	// Fine-grained tokens send no scope header
+	scopes = ""
+	if _, ok, err := p.TokenScopes(context.Background()); err != nil || ok {
+		t.Errorf("TokenScopes without header: ok = %v, err = %v; want unknown", ok, err)
+	}
+}
+
+func TestMissingScopes(t *testing.T) {
+	tests := []struct {
+		granted   []string
+		operation string
+		pt        ProviderType
+		want      string
+	}{
+		{[]string{"api"}, OperationMirror, ProviderGitLab, ""},
+		{[]string{"read_api"}, OperationMirror, ProviderGitLab, "read_repository"},
+		{[]string{"read_api", "write_repository"}, OperationProtect, ProviderGitLab, "api"},
+		{[]string{"public_repo"}, OperationMirror, ProviderGitHub, "repo read:org"},
+	}
+	for _, tt := range tests {
+		missing := MissingScopes(tt.granted, RequiredScopes(tt.pt, tt.operation))
+		if got := strings.Join(missing, " "); got != tt.want {
+			t.Errorf("MissingScopes(%v, %s %s) = %q, want %q", tt.granted, tt.pt, tt.operation, got, tt.want)
+		}
+	}
+}
+
+func TestExcessScopes(t *testing.T) {
+	required := RequiredScopes(ProviderGitHub, OperationMirror)
+	excess := ExcessScopes([]string{"repo", "admin:org", "delete_repo"}, required)
+	if strings.Join(excess, " ") != "admin:org delete_repo" {
+		t.Errorf("ExcessScopes = %v, want [admin:org delete_repo]", excess)
+	}
+	if excess := ExcessScopes([]string{"read_api", "read_repository"}, RequiredScopes(ProviderGitLab, OperationMirror)); len(excess) != 0 {
+		t.Errorf("ExcessScopes of an exact GitLab mirror token = %v, want none", excess)
+	}
+}
+
+// registerForTest registers a provider type for the rest of the test and removes it
+// afterwards, so it does not leak into other tests or repeated runs (-count)
+func registerForTest(t *testing.T, name ProviderType, factory Factory) {
+	Register(name, factory)
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		delete(registry, name)
+		registered = slices.DeleteFunc(registered, func(n ProviderType) bool { return n == name })
+	})
+}
+
+func TestRegister(t *testing.T) {
+	var gotURL string
+	registerForTest(t, "example-host", func(token, baseURL string) (Provider, error) {
+		gotURL = baseURL
+		return NewGitHubProvider(token, baseURL)
+	})
+
+	if !IsRegistered("example-host") {
+		t.Fatal("example-host not registered")
+	}
+	if _, err := New("example-host", "", "https://git.example-host.internal"); err != nil || gotURL != "https://git.example-host.internal" {
+		t.Errorf("New() = %v, factory got URL %q", err, gotURL)
+	}
+	if _, err := New("unknown", "", ""); err == nil {
+		t.Error("New() of an unregistered provider succeeded")
+	}
+
+	for url, want := range map[string]ProviderType{
+		"https://git.example-host.internal": "example-host",
+		"https://gitlab.example-host.com":   ProviderGitLab,
+		"https://git.company.com":           ProviderGitLab,
+	} {
+		if got := DetectProvider(url); got != want {
+			t.Errorf("DetectProvider(%q) = %s, want %s", url, got, want)
+		}
+	}
+
+	// Built-in providers come first, in their original order
+	if got := Registered(); len(got) < 3 || got[0] != ProviderGitLab || got[1] != ProviderGitHub {
+		t.Errorf("Registered() = %v", got)
+	}
+
+	for _, name := range []ProviderType{"github", "Bad_Name", ""} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%q) did not panic", name)
+				}
+			}()
+			Register(name, func(token, baseURL string) (Provider, error) { return nil, nil })
+		}()
+	}
+}
+
+func TestGitHubEachOrgRepo_Pages(t *testing.T) {
+	var server *httptest.Server
+	pages := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/orgs/acme/repos", func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`[{"full_name":"acme/c"}]`))
+			return
+		}
+		w.Header().Set("Link", `<`+server.URL+`/api/v3/orgs/acme/repos?page=2>; rel="next"`)
+		w.Write([]byte(`[{"full_name":"acme/a","owner":{"login":"acme"}},{"full_name":"acme/b"}]`))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := NewGitHubProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitHubProvider error = %v", err)
+	}
+
+	repos, err := p.ListGroupProjects(context.Background(), "acme")
+	if err != nil || len(repos) != 3 {
+		t.Fatalf("ListGroupProjects() = %d repos, %v; want 3 repos", len(repos), err)
+	}
+	if repos[0].Owner != "acme" || repos[0].Namespace != "acme" {
+		t.Errorf("owner, namespace = %q, %q; want acme", repos[0].Owner, repos[0].Namespace)
+	}
+
+	// Stopping in the first page must not fetch the next one
+	pages = 0
+	stop := errors.New("stop")
+	err = p.eachOrgRepo(context.Background(), "acme", 100, func(repo Repository) error {
+		if repo.FullPath == "acme/b" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || pages != 1 {
+		t.Errorf("eachOrgRepo() = %v after %d page(s), want stop after 1", err, pages)
+	}
+}
+
+func TestGitHubListGroupProjects_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"Bad credentials"}`))
+	}))
+	defer server.Close()
+
+	p, err := NewGitHubProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitHubProvider error = %v", err)
+	}
+
+	_, err = p.ListGroupProjects(context.Background(), "acme")
+	if !IsUnauthorized(err) {
+		t.Errorf("IsUnauthorized(%v) = false, want true", err)
+	}
+	if IsUnauthorized(errors.New("boom")) {
+		t.Error("IsUnauthorized(plain error) = true, want false")
+	}
+}
+
+func TestGitHubSSORequired(t *testing.T) {
+	authURL := "https://github.com/orgs/acme/sso?authorization_request=abc123"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/api/v3/users/") {
+			// Only reached if the org error fell back to listing a user
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Not Found"}`))
+			return
+		}
+		w.Header().Set("X-GitHub-SSO", "required; url="+authURL)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"Resource protected by organization SAML enforcement."}`))
+	}))
+	defer server.Close()
+
+	p, err := NewGitHubProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitHubProvider error = %v", err)
+	}
+
+	_, err = p.ListGroupProjects(context.Background(), "acme")
+	var ssoErr *SSORequiredError
+	if !errors.As(err, &ssoErr) {
+		t.Fatalf("ListGroupProjects() error = %v, want SSORequiredError", err)
+	}
+	if ssoErr.Org != "acme" || ssoErr.URL != authURL {
+		t.Errorf("SSORequiredError = %+v, want org acme and URL %q", ssoErr, authURL)
+	}
+	want := "failed to list repositories for acme: GitHub org acme enforces SAML single sign-on and the token is not authorized for it; authorize the token at " + authURL
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+	if IsUnauthorized(err) {
+		t.Error("IsUnauthorized(SSO error) = true, want false")
+	}
+
+	if _, err := p.GetProject(context.Background(), "acme/api"); asSSORequired(err) == nil {
+		t.Errorf("GetProject() error = %v, want SSORequiredError", err)
+	}
+	if err := p.TestConnection(context.Background()); asSSORequired(err) == nil || !strings.Contains(err.Error(), authURL) {
+		t.Errorf("TestConnection() error = %v, want SSORequiredError with the authorization URL", err)
+	}
+}
+
+func TestDebugOutput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	var log bytes.Buffer
+	DebugOutput = &log
+	defer func() { DebugOutput = nil }()
+
+	p, err := NewGitHubProvider("secret-token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitHubProvider error = %v", err)
+	}
+	if _, err := p.ListGroupProjects(context.Background(), "acme"); err != nil {
+		t.Fatalf("ListGroupProjects() error = %v", err)
+	}
+
+	got := log.String()
+	if !strings.Contains(got, "GET "+server.URL+"/api/v3/orgs/acme/repos") || !strings.Contains(got, "-> 200") {
+		t.Errorf("debug log = %q, want the org repos request with status 200", got)
+	}
+	if strings.Contains(got, "secret-token") {
+		t.Errorf("debug log leaks the token: %q", got)
+	}
+
+	u, _ := url.Parse("https://gitlab.example.com/api/v4/projects?private_token=abc&page=2")
+	if got := redactURL(u); strings.Contains(got, "abc") || !strings.Contains(got, "page=2") {
+		t.Errorf("redactURL() = %q, want token redacted and other params kept", got)
+	}
+}
+
+func TestGitHubHasUnprotectedEnvironment_StopsEarly(t *testing.T) {
+	var server *httptest.Server
+	pages := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/acme/app/environments", func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Link", `<`+server.URL+`/api/v3/repos/acme/app/environments?page=2>; rel="next"`)
+		w.Write([]byte(`{"total_count":2,"environments":[
+			{"name":"prod","protection_rules":[{"type":"required_reviewers"}]},
+			{"name":"dev","protection_rules":[]}]}`))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := NewGitHubProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitHubProvider error = %v", err)
+	}
+
+	unprotected, err := p.HasUnprotectedEnvironment(context.Background(), "acme/app")
+	if err != nil || !unprotected {
+		t.Fatalf("HasUnprotectedEnvironment() = %v, %v; want true", unprotected, err)
+	}
+	if pages != 1 {
+		t.Errorf("fetched %d pages, want 1", pages)
+	}
+}
+
+func TestGitHubWebhooks(t *testing.T) {
+	var config map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/acme/api/hooks", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id": 3, "active": true, "events": ["push", "pull_request"],
+			"config": {"url": "https://ci.example.com/hook", "content_type": "json", "secret": "********"}}]`))
+	})
+	mux.HandleFunc("PATCH /api/v3/repos/acme/api/hooks/3/config", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&config)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p, err := NewGitHubProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitHubProvider error = %v", err)
+	}
+	ctx := context.Background()
+	hooks, err := p.ListWebhooks(ctx, "acme/api")
+	if err != nil || len(hooks) != 1 {
+		t.Fatalf("ListWebhooks() = %+v, %v; want 1 hook", hooks, err)
+	}
+	if h := hooks[0]; h.ID != 3 || h.URL != "https://ci.example.com/hook" || !h.Active || len(h.Events) != 2 {
+		t.Errorf("hook = %+v", h)
+	}
+
+	// Only the secret is sent, so the rest of the configuration is kept
+	if err := p.UpdateWebhook(ctx, "acme/api", hooks[0], WebhookUpdate{Secret: "s3cret"}); err != nil {
+		t.Fatalf("UpdateWebhook() error = %v", err)
+	}
+	if len(config) != 1 || config["secret"] != "s3cret" {
+		t.Errorf("config update = %v, want only the new secret", config)
+	}
+}
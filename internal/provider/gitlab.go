@@ -2,8 +2,12 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -34,7 +38,11 @@ func NewGitLabProvider(token, baseURL string) (*GitLabProvider, error) {
 	}
 
 	// Create client with base URL
-	client, err = gitlab.NewClient(token, gitlab.WithBaseURL(apiURL))
+	options := []gitlab.ClientOptionFunc{gitlab.WithBaseURL(apiURL)}
+	if httpClient := debugHTTPClient(); httpClient != nil {
+		options = append(options, gitlab.WithHTTPClient(httpClient))
+	}
+	client, err = gitlab.NewClient(token, options...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GitLab client: %w", err)
 	}
@@ -45,11 +53,29 @@ func NewGitLabProvider(token, baseURL string) (*GitLabProvider, error) {
 	}, nil
 }
 
+// isGitLabUnauthorized reports whether err wraps a 401 response from the GitLab API
+func isGitLabUnauthorized(err error) bool {
+	var errResp *gitlab.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusUnauthorized
+}
+
+// isGitLabNotFound reports whether err wraps a 404 response from the GitLab API, which
+// the client returns as gitlab.ErrNotFound
+func isGitLabNotFound(err error) bool {
+	return errors.Is(err, gitlab.ErrNotFound)
+}
+
 // Name returns the provider name
 func (p *GitLabProvider) Name() string {
 	return "gitlab"
 }
 
+// Capabilities reports the optional features of GitLab. Environment protection has
+// access levels and approvals but no wait timers, deployment branches, or reviewers.
+func (p *GitLabProvider) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{Environments: true, Subgroups: true, Releases: true}
+}
+
 // TestConnection tests the API connection and token validity
 func (p *GitLabProvider) TestConnection(ctx context.Context) error {
 	_, _, err := p.client.Users.CurrentUser(gitlab.WithContext(ctx))
@@ -68,46 +94,186 @@ func (p *GitLabProvider) GetCurrentUser(ctx context.Context) (string, error) {
 	return user.Username, nil
 }
 
+// TokenScopes returns the scopes of a personal, project, or group access token.
+// OAuth tokens and GitLab versions before 15.5 cannot report them.
+func (p *GitLabProvider) TokenScopes(ctx context.Context) ([]string, bool, error) {
+	token, resp, err := p.client.PersonalAccessTokens.GetSinglePersonalAccessToken(gitlab.WithContext(ctx))
+	if err != nil {
+		if resp != nil && resp.StatusCode == 404 {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get token details: %w", err)
+	}
+	return token.Scopes, true, nil
+}
+
 // ListGroupProjects lists all projects in a group including subgroups
 func (p *GitLabProvider) ListGroupProjects(ctx context.Context, groupPath string) ([]Repository, error) {
 	var repos []Repository
+	err := p.ListGroupProjectsStream(ctx, groupPath, func(repo Repository) error {
+		repos = append(repos, repo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// ListGroupProjectsStream calls fn for each project in a group (including subgroups) as pages arrive
+func (p *GitLabProvider) ListGroupProjectsStream(ctx context.Context, groupPath string, fn func(Repository) error) error {
+	return p.eachGroupProject(ctx, groupPath, 100, fn)
+}
 
-	// URL encode the group path
-	encodedPath := url.PathEscape(groupPath)
+// ProbeGroup checks that a group can be listed, fetching at most one project
+func (p *GitLabProvider) ProbeGroup(ctx context.Context, groupPath string) (bool, error) {
+	return probeFirst(func(fn func(Repository) error) error {
+		return p.eachGroupProject(ctx, groupPath, 1, fn)
+	})
+}
 
+// eachGroupProject calls fn for each project in a group (including subgroups), fetching
+// perPage projects per request
+func (p *GitLabProvider) eachGroupProject(ctx context.Context, groupPath string, perPage int, fn func(Repository) error) error {
 	opts := &gitlab.ListGroupProjectsOptions{
 		IncludeSubGroups: gitlab.Ptr(true),
 		ListOptions: gitlab.ListOptions{
-			PerPage: 100,
+			PerPage: int64(perPage),
 		},
 	}
 
 	for {
-		projects, resp, err := p.client.Groups.ListGroupProjects(encodedPath, opts, gitlab.WithContext(ctx))
+		projects, resp, err := p.client.Groups.ListGroupProjects(groupPath, opts, gitlab.WithContext(ctx))
 		if err != nil {
-			return nil, fmt.Errorf("failed to list projects for group %s: %w", groupPath, err)
+			return fmt.Errorf("failed to list projects for group %s: %w", groupPath, err)
 		}
 
 		for _, project := range projects {
-			var lastUpdated time.Time
-			if project.LastActivityAt != nil {
-				lastUpdated = *project.LastActivityAt
+			repo := convertGitLabProject(project)
+			if err := fn(repo); err != nil {
+				return err
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nil
+}
+
+// SearchRepositories returns projects matching a GitLab search query
+func (p *GitLabProvider) SearchRepositories(ctx context.Context, query string) ([]Repository, error) {
+	var repos []Repository
+
+	opts := &gitlab.SearchOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	retries := 0
+	for {
+		projects, resp, err := p.client.Search.Projects(query, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			if retries >= maxRateLimitRetries {
+				return nil, fmt.Errorf("failed to search projects: %w", err)
 			}
-			var size int64
-			if project.Statistics != nil {
-				size = project.Statistics.RepositorySize
+			if waitErr := waitForGitLabRateLimit(ctx, err); waitErr != nil {
+				return nil, fmt.Errorf("failed to search projects: %w", waitErr)
 			}
-			repos = append(repos, Repository{
-				ID:            int64(project.ID),
-				Name:          project.Name,
-				FullPath:      project.PathWithNamespace,
-				CloneURL:      project.HTTPURLToRepo,
-				SSHUrl:        project.SSHURLToRepo,
-				DefaultBranch: project.DefaultBranch,
-				Archived:      project.Archived,
-				LastUpdated:   lastUpdated,
-				Size:          size,
-			})
+			retries++
+			continue
+		}
+		retries = 0
+
+		for _, project := range projects {
+			repos = append(repos, convertGitLabProject(project))
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return repos, nil
+}
+
+// waitForGitLabRateLimit sleeps until a GitLab rate limit resets. The client retries a
+// 429 a few times within seconds, but search is limited per minute, so a busy search
+// can outlast those retries. Returns err if it is not a rate limit error, or ctx.Err()
+// if cancelled.
+func waitForGitLabRateLimit(ctx context.Context, err error) error {
+	var errResp *gitlab.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil || errResp.Response.StatusCode != http.StatusTooManyRequests {
+		return err
+	}
+
+	// GitLab sends RateLimit-Reset (a Unix time) and usually Retry-After (seconds)
+	wait := time.Minute
+	header := errResp.Response.Header
+	if reset, _ := strconv.ParseInt(header.Get("RateLimit-Reset"), 10, 64); reset > 0 {
+		wait = time.Until(time.Unix(reset, 0)) + time.Second
+	} else if seconds, _ := strconv.Atoi(header.Get("Retry-After")); seconds > 0 {
+		wait = time.Duration(seconds) * time.Second
+	}
+	if wait < time.Second {
+		wait = time.Second
+	}
+	fmt.Printf("  ! GitLab rate limit reached, waiting %s...\n", wait.Round(time.Second))
+	return sleepContext(ctx, wait)
+}
+
+// ListOrgMembers lists the usernames of a group's direct members
+func (p *GitLabProvider) ListOrgMembers(ctx context.Context, groupPath string) ([]string, error) {
+	var members []string
+
+	opts := &gitlab.ListGroupMembersOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	for {
+		groupMembers, resp, err := p.client.Groups.ListGroupMembers(groupPath, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list members of %s: %w", groupPath, err)
+		}
+
+		for _, member := range groupMembers {
+			members = append(members, member.Username)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return members, nil
+}
+
+// ListUserProjects lists projects in a user's personal namespace
+func (p *GitLabProvider) ListUserProjects(ctx context.Context, username string) ([]Repository, error) {
+	var repos []Repository
+
+	opts := &gitlab.ListProjectsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	for {
+		projects, resp, err := p.client.Projects.ListUserProjects(username, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list projects for %s: %w", username, err)
+		}
+
+		for _, project := range projects {
+			repos = append(repos, convertGitLabProject(project))
 		}
 
 		if resp.NextPage == 0 {
@@ -152,32 +318,227 @@ func (p *GitLabProvider) ListGroups(ctx context.Context) ([]Group, error) {
 	return groups, nil
 }
 
-// GetProject gets a single project by path
-func (p *GitLabProvider) GetProject(ctx context.Context, projectPath string) (*Repository, error) {
-	encodedPath := url.PathEscape(projectPath)
-
-	project, _, err := p.client.Projects.GetProject(encodedPath, nil, gitlab.WithContext(ctx))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get project %s: %w", projectPath, err)
+// projectNamespace returns the full path of the group or user namespace a project is in,
+// taken from its own path when the API leaves the namespace out
+func projectNamespace(project *gitlab.Project) string {
+	if project.Namespace != nil && project.Namespace.FullPath != "" {
+		return project.Namespace.FullPath
 	}
+	if i := strings.LastIndex(project.PathWithNamespace, "/"); i >= 0 {
+		return project.PathWithNamespace[:i]
+	}
+	return ""
+}
 
-	return &Repository{
+// convertGitLabProject converts a GitLab API project to a Repository. The size is only
+// known when statistics were requested and the token has Reporter access or higher.
+func convertGitLabProject(project *gitlab.Project) Repository {
+	var lastUpdated time.Time
+	if project.LastActivityAt != nil {
+		lastUpdated = *project.LastActivityAt
+	}
+	var size int64
+	if project.Statistics != nil {
+		size = project.Statistics.RepositorySize
+	}
+	return Repository{
 		ID:            int64(project.ID),
 		Name:          project.Name,
 		FullPath:      project.PathWithNamespace,
+		Owner:         projectOwner(project),
+		Namespace:     projectNamespace(project),
+		Description:   project.Description,
 		CloneURL:      project.HTTPURLToRepo,
 		SSHUrl:        project.SSHURLToRepo,
 		DefaultBranch: project.DefaultBranch,
 		Archived:      project.Archived,
-	}, nil
+		Private:       project.Visibility != gitlab.PublicVisibility,
+		LastUpdated:   lastUpdated,
+		Size:          size,
+		SizeKnown:     project.Statistics != nil,
+	}
+}
+
+// projectOwner returns the top-level group or user that owns a project
+func projectOwner(project *gitlab.Project) string {
+	owner, _, _ := strings.Cut(projectNamespace(project), "/")
+	return owner
+}
+
+// GetProject gets a single project by path
+func (p *GitLabProvider) GetProject(ctx context.Context, projectPath string) (*Repository, error) {
+	project, _, err := p.client.Projects.GetProject(projectPath, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project %s: %w", projectPath, err)
+	}
+
+	repo := convertGitLabProject(project)
+	return &repo, nil
+}
+
+// CreateRepository creates an empty project in a group or user namespace
+func (p *GitLabProvider) CreateRepository(ctx context.Context, namespace string, repo Repository) (*Repository, error) {
+	ns, _, err := p.client.Namespaces.GetNamespace(namespace, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+
+	visibility := gitlab.PublicVisibility
+	if repo.Private {
+		visibility = gitlab.PrivateVisibility
+	}
+	opts := &gitlab.CreateProjectOptions{
+		Name:        gitlab.Ptr(repo.Name),
+		Path:        gitlab.Ptr(repo.Name),
+		NamespaceID: gitlab.Ptr(ns.ID),
+		Description: gitlab.Ptr(repo.Description),
+		Visibility:  gitlab.Ptr(visibility),
+	}
+	project, _, err := p.client.Projects.CreateProject(opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project %s/%s: %w", namespace, repo.Name, err)
+	}
+
+	created := convertGitLabProject(project)
+	return &created, nil
+}
+
+// BranchCommitDate returns the committed date of the branch's head commit
+func (p *GitLabProvider) BranchCommitDate(ctx context.Context, projectPath, branch string) (time.Time, error) {
+	b, _, err := p.client.Branches.GetBranch(projectPath, branch, gitlab.WithContext(ctx))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get branch %s of %s: %w", branch, projectPath, err)
+	}
+	if b.Commit == nil || b.Commit.CommittedDate == nil {
+		return time.Time{}, fmt.Errorf("branch %s of %s has no commit date", branch, projectPath)
+	}
+	return *b.Commit.CommittedDate, nil
+}
+
+// PrimaryLanguage returns the language with the largest share of a project's code
+func (p *GitLabProvider) PrimaryLanguage(ctx context.Context, projectPath string) (string, error) {
+	languages, _, err := p.client.Projects.GetProjectLanguages(projectPath, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to get languages of %s: %w", projectPath, err)
+	}
+	if languages == nil {
+		return "", nil
+	}
+
+	var primary string
+	var share float32
+	for name, pct := range *languages {
+		// Ties are broken by name so the result is stable
+		if pct > share || (pct == share && name < primary) {
+			primary, share = name, pct
+		}
+	}
+	return primary, nil
+}
+
+// RepositorySize returns a project's repository size from its statistics
+func (p *GitLabProvider) RepositorySize(ctx context.Context, projectPath string) (int64, error) {
+	opts := &gitlab.GetProjectOptions{Statistics: gitlab.Ptr(true)}
+	project, _, err := p.client.Projects.GetProject(projectPath, opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get project %s: %w", projectPath, err)
+	}
+	if project.Statistics == nil {
+		return 0, fmt.Errorf("size of %s is not available (statistics need Reporter access)", projectPath)
+	}
+	return project.Statistics.RepositorySize, nil
+}
+
+// ListReleases lists all releases and their asset links for a project.
+// Auto-generated source archives are skipped since the clone already contains the source.
+func (p *GitLabProvider) ListReleases(ctx context.Context, projectPath string) ([]Release, error) {
+	var releases []Release
+
+	opts := &gitlab.ListReleasesOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	for {
+		glReleases, resp, err := p.client.Releases.ListReleases(projectPath, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list releases for %s: %w", projectPath, err)
+		}
+
+		for _, r := range glReleases {
+			release := Release{TagName: r.TagName}
+			for _, link := range r.Assets.Links {
+				assetURL := link.DirectAssetURL
+				if assetURL == "" {
+					assetURL = link.URL
+				}
+				release.Assets = append(release.Assets, ReleaseAsset{
+					ID:   link.ID,
+					Name: link.Name,
+					URL:  assetURL,
+				})
+			}
+			releases = append(releases, release)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return releases, nil
+}
+
+// DownloadReleaseAsset writes a release asset link to w.
+// The token is only sent when the link points at this GitLab instance.
+func (p *GitLabProvider) DownloadReleaseAsset(ctx context.Context, projectPath string, asset ReleaseAsset, w io.Writer) error {
+	assetURL, err := url.Parse(asset.URL)
+	if err != nil {
+		return fmt.Errorf("invalid asset URL for %s: %w", asset.Name, err)
+	}
+
+	base, err := url.Parse(p.baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	if assetURL.Host == base.Host {
+		req, err := p.client.NewRequestToURL(http.MethodGet, assetURL, nil, []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)})
+		if err != nil {
+			return fmt.Errorf("failed to download asset %s: %w", asset.Name, err)
+		}
+		if _, err := p.client.Do(req, w); err != nil {
+			return fmt.Errorf("failed to download asset %s: %w", asset.Name, err)
+		}
+		return nil
+	}
+
+	// External link: download without credentials
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download asset %s: %w", asset.Name, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download asset %s: %w", asset.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download asset %s: HTTP %d", asset.Name, resp.StatusCode)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download asset %s: %w", asset.Name, err)
+	}
+	return nil
 }
 
 // ListEnvironments lists all environments for a project
 func (p *GitLabProvider) ListEnvironments(ctx context.Context, projectPath string) ([]Environment, error) {
 	var envs []Environment
 
-	encodedPath := url.PathEscape(projectPath)
-
 	opts := &gitlab.ListEnvironmentsOptions{
 		ListOptions: gitlab.ListOptions{
 			PerPage: 100,
@@ -185,7 +546,7 @@ func (p *GitLabProvider) ListEnvironments(ctx context.Context, projectPath strin
 	}
 
 	for {
-		gitlabEnvs, resp, err := p.client.Environments.ListEnvironments(encodedPath, opts, gitlab.WithContext(ctx))
+		gitlabEnvs, resp, err := p.client.Environments.ListEnvironments(projectPath, opts, gitlab.WithContext(ctx))
 		if err != nil {
 			return nil, fmt.Errorf("failed to list environments for %s: %w", projectPath, err)
 		}
@@ -195,6 +556,7 @@ func (p *GitLabProvider) ListEnvironments(ctx context.Context, projectPath strin
 				ID:    int64(e.ID),
 				Name:  e.Name,
 				State: e.State,
+				Tier:  e.Tier,
 			})
 		}
 
@@ -207,8 +569,11 @@ func (p *GitLabProvider) ListEnvironments(ctx context.Context, projectPath strin
 	// Check which environments are protected
 	protectedEnvs, err := p.listProtectedEnvironments(ctx, projectPath)
 	if err != nil {
-		// Non-fatal: some projects may not have this feature
-		return envs, nil
+		if protectedEnvironmentsUnavailable(err) {
+			// Non-fatal: some projects do not have this feature
+			return envs, nil
+		}
+		return nil, fmt.Errorf("failed to list protected environments for %s: %w", projectPath, err)
 	}
 
 	protectedSet := make(map[string]bool)
@@ -225,9 +590,7 @@ func (p *GitLabProvider) ListEnvironments(ctx context.Context, projectPath strin
 
 // listProtectedEnvironments returns names of protected environments
 func (p *GitLabProvider) listProtectedEnvironments(ctx context.Context, projectPath string) ([]string, error) {
-	encodedPath := url.PathEscape(projectPath)
-
-	protectedEnvs, _, err := p.client.ProtectedEnvironments.ListProtectedEnvironments(encodedPath, nil, gitlab.WithContext(ctx))
+	protectedEnvs, _, err := p.client.ProtectedEnvironments.ListProtectedEnvironments(projectPath, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -240,10 +603,53 @@ func (p *GitLabProvider) listProtectedEnvironments(ctx context.Context, projectP
 	return names, nil
 }
 
+// protectedEnvironmentsUnavailable reports whether listing protected environments failed
+// because the project does not have the feature (GitLab Free) or the token may not see
+// it: a 403 or 404 response. Environments then count as unprotected. Other errors, such
+// as an expired token or a server error, leave their protection unknown.
+func protectedEnvironmentsUnavailable(err error) bool {
+	var errResp *gitlab.ErrorResponse
+	return isGitLabNotFound(err) ||
+		errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusForbidden
+}
+
+// HasUnprotectedEnvironment reports whether a project has an environment that is not
+// protected. Environments are checked one at a time, stopping at the first unprotected
+// one, so neither the remaining environments nor the full protected list is fetched.
+func (p *GitLabProvider) HasUnprotectedEnvironment(ctx context.Context, projectPath string) (bool, error) {
+	opts := &gitlab.ListEnvironmentsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	for {
+		gitlabEnvs, resp, err := p.client.Environments.ListEnvironments(projectPath, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return false, fmt.Errorf("failed to list environments for %s: %w", projectPath, err)
+		}
+
+		for _, e := range gitlabEnvs {
+			_, _, err := p.client.ProtectedEnvironments.GetProtectedEnvironment(projectPath, e.Name, gitlab.WithContext(ctx))
+			if err == nil {
+				continue
+			}
+			// Not protected, or the project lacks the feature (as in ListEnvironments)
+			if protectedEnvironmentsUnavailable(err) {
+				return true, nil
+			}
+			return false, fmt.Errorf("failed to check protection status for %s: %w", e.Name, err)
+		}
+
+		if resp.NextPage == 0 {
+			return false, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
 // ProtectEnvironment protects an environment with the given rules
 func (p *GitLabProvider) ProtectEnvironment(ctx context.Context, projectPath, envName string, rule ProtectionRule) error {
-	encodedPath := url.PathEscape(projectPath)
-
 	opts := &gitlab.ProtectRepositoryEnvironmentsOptions{
 		Name: gitlab.Ptr(envName),
 		DeployAccessLevels: &[]*gitlab.EnvironmentAccessOptions{
@@ -262,7 +668,7 @@ func (p *GitLabProvider) ProtectEnvironment(ctx context.Context, projectPath, en
 		}
 	}
 
-	_, _, err := p.client.ProtectedEnvironments.ProtectRepositoryEnvironments(encodedPath, opts, gitlab.WithContext(ctx))
+	_, _, err := p.client.ProtectedEnvironments.ProtectRepositoryEnvironments(projectPath, opts, gitlab.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to protect environment %s: %w", envName, err)
 	}
@@ -272,9 +678,7 @@ func (p *GitLabProvider) ProtectEnvironment(ctx context.Context, projectPath, en
 
 // IsEnvironmentProtected checks if an environment is protected
 func (p *GitLabProvider) IsEnvironmentProtected(ctx context.Context, projectPath, envName string) (bool, error) {
-	encodedPath := url.PathEscape(projectPath)
-
-	_, resp, err := p.client.ProtectedEnvironments.GetProtectedEnvironment(encodedPath, envName, gitlab.WithContext(ctx))
+	_, resp, err := p.client.ProtectedEnvironments.GetProtectedEnvironment(projectPath, envName, gitlab.WithContext(ctx))
 	if err != nil {
 		if resp != nil && resp.StatusCode == 404 {
 			return false, nil
@@ -284,3 +688,84 @@ func (p *GitLabProvider) IsEnvironmentProtected(ctx context.Context, projectPath
 
 	return true, nil
 }
+
+// ListWebhooks lists the webhooks of a project
+func (p *GitLabProvider) ListWebhooks(ctx context.Context, projectPath string) ([]Webhook, error) {
+	var hooks []Webhook
+
+	opts := &gitlab.ListProjectHooksOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	for {
+		gitlabHooks, resp, err := p.client.Projects.ListProjectHooks(projectPath, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list webhooks for %s: %w", projectPath, err)
+		}
+
+		for _, h := range gitlabHooks {
+			hooks = append(hooks, Webhook{
+				ID:     h.ID,
+				URL:    h.URL,
+				Events: gitlabHookEvents(h),
+				Active: h.AlertStatus == "" || h.AlertStatus == "executable",
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return hooks, nil
+}
+
+// gitlabHookEvents returns the names of the events a project hook is triggered by
+func gitlabHookEvents(h *gitlab.ProjectHook) []string {
+	var events []string
+	for _, e := range []struct {
+		name string
+		on   bool
+	}{
+		{"push", h.PushEvents},
+		{"tag_push", h.TagPushEvents},
+		{"issues", h.IssuesEvents},
+		{"confidential_issues", h.ConfidentialIssuesEvents},
+		{"merge_requests", h.MergeRequestsEvents},
+		{"note", h.NoteEvents},
+		{"confidential_note", h.ConfidentialNoteEvents},
+		{"job", h.JobEvents},
+		{"pipeline", h.PipelineEvents},
+		{"wiki_page", h.WikiPageEvents},
+		{"deployment", h.DeploymentEvents},
+		{"releases", h.ReleasesEvents},
+		{"milestone", h.MilestoneEvents},
+		{"feature_flag", h.FeatureFlagEvents},
+		{"repository_update", h.RepositoryUpdateEvents},
+		{"resource_access_token", h.ResourceAccessTokenEvents},
+	} {
+		if e.on {
+			events = append(events, e.name)
+		}
+	}
+	return events
+}
+
+// UpdateWebhook changes the settings of a project webhook. GitLab requires the URL on
+// every edit, so the hook's current URL is sent back unchanged.
+func (p *GitLabProvider) UpdateWebhook(ctx context.Context, projectPath string, hook Webhook, update WebhookUpdate) error {
+	opts := &gitlab.EditProjectHookOptions{
+		URL: gitlab.Ptr(hook.URL),
+	}
+	if update.Secret != "" {
+		opts.Token = gitlab.Ptr(update.Secret)
+	}
+
+	if _, _, err := p.client.Projects.EditProjectHook(projectPath, hook.ID, opts, gitlab.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to update webhook %d of %s: %w", hook.ID, projectPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestGitLabNestedPathsEncodedOnce(t *testing.T) {
+	var mu sync.Mutex
+	var uris []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		uris = append(uris, r.RequestURI)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(r.URL.Path, "/projects/") && !strings.HasSuffix(r.URL.Path, "/projects") {
+			w.Write([]byte(`{"path_with_namespace":"company/infra/terraform"}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	p, err := NewGitLabProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitLabProvider error = %v", err)
+	}
+	ctx := context.Background()
+	if _, err := p.ListGroupProjects(ctx, "company/infra"); err != nil {
+		t.Fatalf("ListGroupProjects error = %v", err)
+	}
+	if _, err := p.ListOrgMembers(ctx, "company/infra"); err != nil {
+		t.Fatalf("ListOrgMembers error = %v", err)
+	}
+	repo, err := p.GetProject(ctx, "company/infra/terraform")
+	if err != nil {
+		t.Fatalf("GetProject error = %v", err)
+	}
+	if repo.Owner != "company" || repo.Namespace != "company/infra" {
+		t.Errorf("GetProject() owner, namespace = %q, %q; want company, company/infra", repo.Owner, repo.Namespace)
+	}
+
+	// The client escapes IDs itself; escaping them first would send %252F, which GitLab does not find
+	want := []string{
+		"/api/v4/groups/company%2Finfra/projects?",
+		"/api/v4/groups/company%2Finfra/members?",
+		"/api/v4/projects/company%2Finfra%2Fterraform",
+	}
+	if len(uris) != len(want) {
+		t.Fatalf("requests = %v, want %d", uris, len(want))
+	}
+	for i, uri := range uris {
+		if !strings.HasPrefix(uri, want[i]) {
+			t.Errorf("request %d = %s, want prefix %s", i, uri, want[i])
+		}
+	}
+}
+
+func TestGitLabProjectNamespace(t *testing.T) {
+	tests := []struct {
+		project           gitlab.Project
+		wantOwner, wantNS string
+	}{
+		{gitlab.Project{PathWithNamespace: "a/b/c", Namespace: &gitlab.ProjectNamespace{FullPath: "a/b"}}, "a", "a/b"},
+		{gitlab.Project{PathWithNamespace: "alice/tools"}, "alice", "alice"},
+		{gitlab.Project{PathWithNamespace: "tools"}, "", ""},
+	}
+	for _, tt := range tests {
+		if owner, ns := projectOwner(&tt.project), projectNamespace(&tt.project); owner != tt.wantOwner || ns != tt.wantNS {
+			t.Errorf("%s: owner, namespace = %q, %q; want %q, %q", tt.project.PathWithNamespace, owner, ns, tt.wantOwner, tt.wantNS)
+		}
+	}
+}
+
+func TestConvertGitLabProject(t *testing.T) {
+	updated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	project := &gitlab.Project{
+		ID:                7,
+		Name:              "api",
+		PathWithNamespace: "devops/platform/api",
+		Namespace:         &gitlab.ProjectNamespace{FullPath: "devops/platform"},
+		Archived:          true,
+		Visibility:        gitlab.InternalVisibility,
+		LastActivityAt:    &updated,
+	}
+
+	repo := convertGitLabProject(project)
+	if repo.Owner != "devops" || repo.Namespace != "devops/platform" || !repo.Archived || !repo.Private || !repo.LastUpdated.Equal(updated) {
+		t.Errorf("convertGitLabProject() = %+v", repo)
+	}
+	if repo.SizeKnown {
+		t.Errorf("SizeKnown = true without statistics, want unknown")
+	}
+
+	// Statistics report the size, even a size of 0
+	project.Statistics = &gitlab.Statistics{RepositorySize: 0}
+	if repo := convertGitLabProject(project); !repo.SizeKnown || repo.Size != 0 {
+		t.Errorf("with statistics: Size, SizeKnown = %d, %v; want 0, true", repo.Size, repo.SizeKnown)
+	}
+}
+
+func TestGitLabWebhooks(t *testing.T) {
+	var edit map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v4/projects/g/p/hooks":
+			w.Write([]byte(`[{"id": 7, "url": "https://ci.example.com/hook", "push_events": true,
+				"merge_requests_events": true, "alert_status": "executable"},
+				{"id": 8, "url": "https://old.example.com", "alert_status": "permanently_disabled"}]`))
+		case r.Method == http.MethodPut && r.URL.Path == "/api/v4/projects/g/p/hooks/7":
+			json.NewDecoder(r.Body).Decode(&edit)
+			w.Write([]byte(`{"id": 7}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p, err := NewGitLabProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitLabProvider error = %v", err)
+	}
+	ctx := context.Background()
+	hooks, err := p.ListWebhooks(ctx, "g/p")
+	if err != nil || len(hooks) != 2 {
+		t.Fatalf("ListWebhooks() = %+v, %v; want 2 hooks", hooks, err)
+	}
+	if h := hooks[0]; h.ID != 7 || !h.Active || strings.Join(h.Events, ",") != "push,merge_requests" {
+		t.Errorf("hooks[0] = %+v", h)
+	}
+	if hooks[1].Active {
+		t.Errorf("hooks[1] = %+v, want inactive", hooks[1])
+	}
+
+	// GitLab requires the URL on every edit
+	if err := p.UpdateWebhook(ctx, "g/p", hooks[0], WebhookUpdate{Secret: "s3cret"}); err != nil {
+		t.Fatalf("UpdateWebhook() error = %v", err)
+	}
+	if edit["url"] != "https://ci.example.com/hook" || edit["token"] != "s3cret" {
+		t.Errorf("edit request = %v, want the hook's URL and the new token", edit)
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v4/projects/acme/gone":
+			w.WriteHeader(http.StatusNotFound)
+		case "/api/v4/projects/acme/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		w.Write([]byte(`{"message":"error"}`))
+	}))
+	defer server.Close()
+
+	p, _ := NewGitLabProvider("token", server.URL)
+	ctx := context.Background()
+	for path, want := range map[string]bool{"acme/gone": true, "acme/forbidden": false, "acme/secret": false} {
+		if _, err := p.GetProject(ctx, path); err == nil || IsNotFound(err) != want {
+			t.Errorf("GetProject(%s) error = %v, IsNotFound = %v; want %v", path, err, IsNotFound(err), want)
+		}
+	}
+	if !IsNotFound(fmt.Errorf("acme/api: %w", ErrNotFound)) {
+		t.Errorf("IsNotFound(wrapped ErrNotFound) = false, want true")
+	}
+}
+
+func TestGitLabHasUnprotectedEnvironment(t *testing.T) {
+	tests := []struct {
+		name            string
+		protectedStatus int // Status of the protected environments listing
+		want            bool
+		wantErr         bool
+	}{
+		{"all protected", http.StatusOK, false, false},
+		{"feature not available", http.StatusForbidden, true, false},
+		{"protected environments not found", http.StatusNotFound, true, false},
+		{"expired token", http.StatusUnauthorized, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/api/v4/projects/g/p/environments":
+					w.Write([]byte(`[{"id": 1, "name": "prod"}]`))
+				case "/api/v4/projects/g/p/protected_environments":
+					w.WriteHeader(tt.protectedStatus)
+					if tt.protectedStatus == http.StatusOK {
+						w.Write([]byte(`[{"name": "prod"}]`))
+						return
+					}
+					w.Write([]byte(`{"message":"error"}`))
+				case "/api/v4/projects/g/p/protected_environments/prod":
+					w.WriteHeader(tt.protectedStatus)
+					if tt.protectedStatus == http.StatusOK {
+						w.Write([]byte(`{"name": "prod"}`))
+						return
+					}
+					w.Write([]byte(`{"message":"error"}`))
+				default:
+					t.Errorf("unexpected request %s", r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			p, err := NewGitLabProvider("token", server.URL)
+			if err != nil {
+				t.Fatalf("NewGitLabProvider error = %v", err)
+			}
+			ctx := context.Background()
+			got, err := p.HasUnprotectedEnvironment(ctx, "g/p")
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("HasUnprotectedEnvironment() = %v, %v; want %v, error %v", got, err, tt.want, tt.wantErr)
+			}
+			if tt.wantErr && !IsUnauthorized(err) {
+				t.Errorf("HasUnprotectedEnvironment() error = %v, want the 401", err)
+			}
+
+			envs, err := p.ListEnvironments(ctx, "g/p")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ListEnvironments() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && (len(envs) != 1 || envs[0].Protected == tt.want) {
+				t.Errorf("ListEnvironments() = %+v, want prod protected = %v", envs, !tt.want)
+			}
+		})
+	}
+}
+
+func TestGitLabHasUnprotectedEnvironment_StopsAtFirstMatch(t *testing.T) {
+	var checked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v4/projects/g/p/environments":
+			w.Write([]byte(`[{"id": 1, "name": "prod"}, {"id": 2, "name": "dev"}, {"id": 3, "name": "test"}]`))
+		case "/api/v4/projects/g/p/protected_environments/prod":
+			checked = append(checked, "prod")
+			w.Write([]byte(`{"name": "prod"}`))
+		case "/api/v4/projects/g/p/protected_environments/dev":
+			checked = append(checked, "dev")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"404 Not found"}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p, err := NewGitLabProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitLabProvider error = %v", err)
+	}
+	got, err := p.HasUnprotectedEnvironment(context.Background(), "g/p")
+	if err != nil || !got {
+		t.Fatalf("HasUnprotectedEnvironment() = %v, %v; want true", got, err)
+	}
+	if strings.Join(checked, ",") != "prod,dev" {
+		t.Errorf("checked %v, want prod and dev only", checked)
+	}
+}
+
+func TestGitLabProbeGroup(t *testing.T) {
+	var perPage []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v4/groups/devops/projects":
+			perPage = append(perPage, r.URL.Query().Get("per_page"))
+			w.Header().Set("X-Next-Page", "2")
+			w.Write([]byte(`[{"id": 1, "path_with_namespace": "devops/api"}]`))
+		case "/api/v4/groups/empty/projects":
+			w.Write([]byte(`[]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"404 Group Not Found"}`))
+		}
+	}))
+	defer server.Close()
+
+	p, err := NewGitLabProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitLabProvider error = %v", err)
+	}
+	ctx := context.Background()
+
+	// One project is enough: no more than one is requested, and the next page is not fetched
+	if ok, err := p.ProbeGroup(ctx, "devops"); !ok || err != nil {
+		t.Errorf("ProbeGroup(devops) = %v, %v; want true", ok, err)
+	}
+	if len(perPage) != 1 || perPage[0] != "1" {
+		t.Errorf("per_page of requests = %v, want one request of 1", perPage)
+	}
+	if ok, err := p.ProbeGroup(ctx, "empty"); ok || err != nil {
+		t.Errorf("ProbeGroup(empty) = %v, %v; want false, nil", ok, err)
+	}
+	if _, err := p.ProbeGroup(ctx, "missing"); !IsNotFound(err) {
+		t.Errorf("ProbeGroup(missing) error = %v, want not found", err)
+	}
+}
+
+func TestGitLabSearchRateLimit(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		// Outlast the client's own retries, so the search has to wait for the reset
+		if requests <= 6 {
+			w.Header().Set("RateLimit-Reset", fmt.Sprint(time.Now().Unix()))
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"message":"429 Too Many Requests"}`))
+			return
+		}
+		w.Write([]byte(`[{"id": 1, "path_with_namespace": "devops/api"}]`))
+	}))
+	defer server.Close()
+
+	p, err := NewGitLabProvider("token", server.URL)
+	if err != nil {
+		t.Fatalf("NewGitLabProvider error = %v", err)
+	}
+	repos, err := p.SearchRepositories(context.Background(), "api")
+	if err != nil {
+		t.Fatalf("SearchRepositories() error = %v, want the search to wait and retry", err)
+	}
+	if len(repos) != 1 || repos[0].FullPath != "devops/api" {
+		t.Errorf("SearchRepositories() = %+v, want devops/api", repos)
+	}
+
+	// A canceled context stops the wait
+	requests = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.SearchRepositories(ctx, "api"); err == nil {
+		t.Error("SearchRepositories() error = nil, want the context error")
+	}
+}
@@ -1,8 +1,10 @@
-// Package provider defines the interface for Git hosting providers (GitLab, GitHub)
+// Package provider defines the interface for Git hosting providers (GitLab, GitHub, Bitbucket)
 package provider
 
 import (
 	"context"
+	"errors"
+	"io"
 	"strings"
 	"time"
 )
@@ -12,12 +14,18 @@ type Repository struct {
 	ID            int64
 	Name          string
 	FullPath      string // e.g., "group/subgroup/repo" or "org/repo"
+	Owner         string // User, organization, top-level group, or workspace that owns the repo
+	Namespace     string // FullPath without the repo name, e.g., "group/subgroup" or "org"
+	Description   string
 	CloneURL      string // HTTPS clone URL
 	SSHUrl        string // SSH clone URL
 	DefaultBranch string
 	Archived      bool
+	Private       bool      // Not publicly visible (private or internal)
 	LastUpdated   time.Time // Last activity/push date
-	Size          int64     // Size in bytes
+	Size          int64     // Size in bytes; 0 if unknown (see RepositorySize)
+	SizeKnown     bool      // The provider reported Size, so a 0 means the repo is empty
+	Language      string    // Primary language; empty if unknown or not listed (see PrimaryLanguage)
 }
 
 // Group represents a group/organization from any provider
@@ -33,12 +41,58 @@ type Environment struct {
 	Name      string
 	State     string // available, stopped, etc.
 	Protected bool
+	Tier      string // GitLab deployment tier (production, staging, testing, development, other); empty if not reported
+}
+
+// Release represents a published release and its downloadable assets
+type Release struct {
+	TagName string
+	Assets  []ReleaseAsset
+}
+
+// ReleaseAsset is a single downloadable file attached to a release
+type ReleaseAsset struct {
+	ID   int64
+	Name string
+	Size int64  // Size in bytes (0 if the provider does not report it)
+	URL  string // Download URL
 }
 
 // ProtectionRule defines environment protection settings
 type ProtectionRule struct {
 	AccessLevel       int // 30=developer, 40=maintainer, 60=admin
 	RequiredApprovals int
+
+	// GitHub only
+	WaitTimer      int      // Minutes to wait before a deployment proceeds (0 = none)
+	DeployBranches []string // Branch name patterns allowed to deploy; "protected" = protected branches only
+
+	Reviewers []Reviewer // Users or teams that must approve deployments (GitHub only)
+}
+
+// Reviewer types for ProtectionRule.Reviewers, as named by the GitHub API
+const (
+	ReviewerUser = "User"
+	ReviewerTeam = "Team"
+)
+
+// Reviewer is a user or team allowed to approve deployments to an environment
+type Reviewer struct {
+	Type string // ReviewerUser or ReviewerTeam
+	ID   int64
+	Name string // Login or org/team slug, for messages
+}
+
+// DeployBranchesProtected restricts deployments to protected branches (ProtectionRule.DeployBranches)
+const DeployBranchesProtected = "protected"
+
+// ProviderCapabilities reports which optional features a provider supports, so commands
+// can say a feature is unavailable instead of failing on every project
+type ProviderCapabilities struct {
+	Environments    bool // Deployment environments and their protection
+	DeploymentRules bool // Wait timers, deployment branches, and reviewers in ProtectionRule
+	Subgroups       bool // Groups can be nested; a multi-segment path may name a group
+	Releases        bool // ListReleases and DownloadReleaseAsset
 }
 
 // Provider is the interface that all git hosting providers must implement
@@ -46,36 +100,158 @@ type Provider interface {
 	// Name returns the provider name (e.g., "gitlab", "github")
 	Name() string
 
+	// Capabilities reports the optional features this provider supports
+	Capabilities() ProviderCapabilities
+
 	// TestConnection tests the API connection and token validity
 	TestConnection(ctx context.Context) error
 
 	// GetCurrentUser returns the authenticated user's username
 	GetCurrentUser(ctx context.Context) (string, error)
 
+	// TokenScopes returns the scopes granted to the token. ok is false if the provider
+	// does not report them for this kind of token (e.g. GitHub fine-grained tokens).
+	TokenScopes(ctx context.Context) (scopes []string, ok bool, err error)
+
 	// ListGroupProjects lists all projects/repos in a group/org (including subgroups)
 	ListGroupProjects(ctx context.Context, groupPath string) ([]Repository, error)
 
+	// ListGroupProjectsStream calls fn for each repo of a group/org as pages arrive,
+	// stopping at the first error fn returns. Providers without paged listing can use StreamList.
+	ListGroupProjectsStream(ctx context.Context, groupPath string, fn func(Repository) error) error
+
+	// SearchRepositories lists repos matching a provider search query
+	SearchRepositories(ctx context.Context, query string) ([]Repository, error)
+
+	// ListOrgMembers lists the usernames of a group/org's members
+	ListOrgMembers(ctx context.Context, orgName string) ([]string, error)
+
+	// ListUserProjects lists repos owned by a user account
+	ListUserProjects(ctx context.Context, username string) ([]Repository, error)
+
 	// ListGroups lists all accessible groups/orgs
 	ListGroups(ctx context.Context) ([]Group, error)
 
 	// GetProject gets a single project by path
 	GetProject(ctx context.Context, projectPath string) (*Repository, error)
 
-	// Environment operations (may not be supported by all providers)
+	// CreateRepository creates an empty repository named repo.Name in a group or org
+	// (or the authenticated user's account), with repo's description and visibility
+	CreateRepository(ctx context.Context, namespace string, repo Repository) (*Repository, error)
+
+	// BranchCommitDate returns the commit date of the last commit on a branch
+	BranchCommitDate(ctx context.Context, projectPath, branch string) (time.Time, error)
+
+	// PrimaryLanguage returns a repo's primary language, or "" if it has none.
+	// GitHub lists it with the repo; GitLab needs this extra call.
+	PrimaryLanguage(ctx context.Context, projectPath string) (string, error)
+
+	// RepositorySize returns a repo's size in bytes from the provider's statistics,
+	// for repos listed without one. GitLab reports it only to members with Reporter access.
+	RepositorySize(ctx context.Context, projectPath string) (int64, error)
+
+	// Release operations
+	ListReleases(ctx context.Context, projectPath string) ([]Release, error)
+	DownloadReleaseAsset(ctx context.Context, projectPath string, asset ReleaseAsset, w io.Writer) error
+
+	// Environment operations (see ProviderCapabilities.Environments)
 	ListEnvironments(ctx context.Context, projectPath string) ([]Environment, error)
+	HasUnprotectedEnvironment(ctx context.Context, projectPath string) (bool, error) // Stops at the first unprotected environment
 	ProtectEnvironment(ctx context.Context, projectPath, envName string, rule ProtectionRule) error
 	IsEnvironmentProtected(ctx context.Context, projectPath, envName string) (bool, error)
 }
 
+// ReviewerResolver is implemented by providers with DeploymentRules that can look up
+// users and teams by name for ProtectionRule.Reviewers
+type ReviewerResolver interface {
+	ResolveReviewers(ctx context.Context, names []string) ([]Reviewer, error)
+}
+
+// Webhook is a project or repo webhook
+type Webhook struct {
+	ID     int64
+	URL    string
+	Events []string // What triggers it, e.g. push or merge_requests
+	Active bool     // False if disabled, or (GitLab) disabled after failed deliveries
+}
+
+// WebhookUpdate holds the webhook settings to change; empty fields are left as they are
+type WebhookUpdate struct {
+	Secret string // Secret token sent with each delivery (GitLab X-Gitlab-Token, GitHub signature key)
+}
+
+// GroupProber is implemented by providers that can check access to a group/org without
+// fetching a full page of repos
+type GroupProber interface {
+	// ProbeGroup reports whether a group/org has any repo visible to the token,
+	// fetching at most one; err is set if the group cannot be listed
+	ProbeGroup(ctx context.Context, groupPath string) (hasRepos bool, err error)
+}
+
+// errProbeStop ends a probe listing at its first repo
+var errProbeStop = errors.New("first repo listed")
+
+// probeFirst runs a listing until its first repo and reports whether there was one
+func probeFirst(list func(fn func(Repository) error) error) (bool, error) {
+	err := list(func(Repository) error { return errProbeStop })
+	if errors.Is(err, errProbeStop) {
+		return true, nil
+	}
+	return false, err
+}
+
+// WebhookManager is implemented by providers that can list and update project webhooks
+type WebhookManager interface {
+	ListWebhooks(ctx context.Context, projectPath string) ([]Webhook, error)
+	UpdateWebhook(ctx context.Context, projectPath string, hook Webhook, update WebhookUpdate) error
+}
+
+// ErrNotFound can be wrapped by providers (e.g. registered ones) to report a missing
+// project; IsNotFound also recognizes the 404 responses of the built-in providers
+var ErrNotFound = errors.New("not found")
+
+// IsNotFound reports whether err comes from a 404 response of the GitHub, GitLab, or
+// Bitbucket API, or wraps ErrNotFound: the project does not exist, or the token cannot see it
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || isGitHubNotFound(err) || isGitLabNotFound(err) || isBitbucketNotFound(err)
+}
+
+// IsUnauthorized reports whether err comes from a 401 response of the GitHub,
+// GitLab, or Bitbucket API. After a successful connection test, this means the token expired
+// or was revoked during the run.
+func IsUnauthorized(err error) bool {
+	return isGitHubUnauthorized(err) || isGitLabUnauthorized(err) || isBitbucketUnauthorized(err)
+}
+
+// StreamList adapts a slice-returning listing to ListGroupProjectsStream:
+//
+//	return StreamList(p.ListGroupProjects(ctx, groupPath))(fn)
+func StreamList(repos []Repository, err error) func(fn func(Repository) error) error {
+	return func(fn func(Repository) error) error {
+		if err != nil {
+			return err
+		}
+		for _, repo := range repos {
+			if err := fn(repo); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
 // ProviderType represents the type of git hosting provider
 type ProviderType string
 
 const (
-	ProviderGitLab ProviderType = "gitlab"
-	ProviderGitHub ProviderType = "github"
+	ProviderGitLab    ProviderType = "gitlab"
+	ProviderGitHub    ProviderType = "github"
+	ProviderBitbucket ProviderType = "bitbucket"
 )
 
-// DetectProvider attempts to detect the provider type from a URL
+// DetectProvider attempts to detect the provider type from a URL. After the built-in
+// providers, a URL containing the name of a registered provider (see Register) is
+// detected as that provider, e.g. https://gitea.example.com as "gitea".
 func DetectProvider(url string) ProviderType {
 	if url == "" {
 		return ProviderGitHub // default to GitHub
@@ -88,6 +264,14 @@ func DetectProvider(url string) ProviderType {
 	if strings.Contains(url, "github") {
 		return ProviderGitHub
 	}
+	if strings.Contains(url, "bitbucket.org") {
+		return ProviderBitbucket
+	}
+	for _, name := range Registered() {
+		if strings.Contains(url, string(name)) {
+			return name
+		}
+	}
 
 	// Default to GitLab for self-hosted instances
 	return ProviderGitLab
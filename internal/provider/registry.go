@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Factory creates a provider client for a token and base URL. The token may be
+// empty (public access only) and baseURL is "" when none is configured.
+type Factory func(token, baseURL string) (Provider, error)
+
+// validName keeps provider types safe for use in config keys, env var names, and paths
+var validName = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[ProviderType]Factory{}
+	registered []ProviderType // Registration order: the built-in providers first
+)
+
+func init() {
+	Register(ProviderGitLab, func(token, baseURL string) (Provider, error) {
+		return NewGitLabProvider(token, baseURL)
+	})
+	Register(ProviderGitHub, func(token, baseURL string) (Provider, error) {
+		return NewGitHubProvider(token, baseURL)
+	})
+	Register(ProviderBitbucket, func(token, baseURL string) (Provider, error) {
+		return NewBitbucketProvider(token, baseURL)
+	})
+}
+
+// Register makes a provider type available to New, DetectProvider, and the CLI's
+// --provider flags. It is meant to be called from an init function of a package
+// that adds a provider; like database/sql.Register, it panics if the name is invalid
+// (lowercase letters, digits, and hyphens), already registered, or factory is nil.
+func Register(name ProviderType, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if !validName.MatchString(string(name)) {
+		panic(fmt.Sprintf("provider: invalid provider name %q", name))
+	}
+	if factory == nil {
+		panic(fmt.Sprintf("provider: Register factory for %q is nil", name))
+	}
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("provider: Register called twice for %q", name))
+	}
+	registry[name] = factory
+	registered = append(registered, name)
+}
+
+// IsRegistered reports whether a provider type has been registered
+func IsRegistered(name ProviderType) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, ok := registry[name]
+	return ok
+}
+
+// Registered returns the registered provider types in registration order, starting with the built-in ones
+func Registered() []ProviderType {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return slices.Clone(registered)
+}
+
+// RegisteredList returns the registered provider types quoted and comma-separated, for messages
+func RegisteredList() string {
+	var quoted []string
+	for _, name := range Registered() {
+		quoted = append(quoted, "'"+string(name)+"'")
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// New creates a client for a registered provider type
+func New(name ProviderType, token, baseURL string) (Provider, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown provider: %s (use one of %s)", name, RegisteredList())
+	}
+	return factory(token, baseURL)
+}
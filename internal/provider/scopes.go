@@ -0,0 +1,80 @@
+package provider
+
+import "slices"
+
+// Operations whose token scope requirements RequiredScopes knows
+const (
+	OperationMirror  = "mirror"
+	OperationProtect = "protect"
+)
+
+// requiredScopes lists the classic token scopes each operation needs. GitHub has no
+// read-only repo scope, so mirroring private repos already needs full repo access.
+var requiredScopes = map[ProviderType]map[string][]string{
+	ProviderGitHub: {
+		OperationMirror:  {"repo", "read:org"},
+		OperationProtect: {"repo", "read:org"},
+	},
+	ProviderGitLab: {
+		OperationMirror:  {"read_api", "read_repository"},
+		OperationProtect: {"api"},
+	},
+	ProviderBitbucket: {
+		OperationMirror: {"repository", "account"},
+	},
+}
+
+// impliedScopes lists scopes that grant the access of others
+var impliedScopes = map[string][]string{
+	// GitHub
+	"admin:org": {"write:org", "read:org"},
+	"write:org": {"read:org"},
+
+	// GitLab
+	"api":              {"read_api", "read_repository", "write_repository"},
+	"write_repository": {"read_repository"},
+
+	// Bitbucket
+	"repository:admin": {"repository:write", "repository"},
+	"repository:write": {"repository"},
+}
+
+// RequiredScopes returns the token scopes an operation needs on a provider,
+// or nil if the operation is unknown
+func RequiredScopes(pt ProviderType, operation string) []string {
+	return requiredScopes[pt][operation]
+}
+
+// MissingScopes returns the required scopes that granted does not include,
+// directly or through a broader scope
+func MissingScopes(granted, required []string) []string {
+	var missing []string
+	for _, scope := range required {
+		if !hasScope(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// ExcessScopes returns the granted scopes that required does not list: scopes an
+// operation never uses, or broader ones such as GitLab api where read_api would do
+func ExcessScopes(granted, required []string) []string {
+	var excess []string
+	for _, scope := range granted {
+		if !slices.Contains(required, scope) {
+			excess = append(excess, scope)
+		}
+	}
+	return excess
+}
+
+// hasScope reports whether granted includes scope or a scope that implies it
+func hasScope(granted []string, scope string) bool {
+	for _, g := range granted {
+		if g == scope || slices.Contains(impliedScopes[g], scope) {
+			return true
+		}
+	}
+	return false
+}
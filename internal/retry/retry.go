@@ -0,0 +1,102 @@
+// Package retry runs operations again after transient failures, waiting longer
+// after each attempt (exponential backoff with jitter)
+package retry
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Policy configures how Do retries an operation
+type Policy struct {
+	MaxAttempts int           // Attempts in total, including the first (< 1 = 1)
+	BaseDelay   time.Duration // Wait before the second attempt; doubled after each further attempt
+	MaxDelay    time.Duration // Longest single wait (0 = no limit)
+	Jitter      float64       // Fraction of each wait that is randomized, 0-1 (0.2 = ±20%)
+
+	// Retryable reports whether an error is worth another attempt. Nil retries every error.
+	Retryable func(error) bool
+
+	// RetryAfter returns the wait the server asked for in an error (such as a 429's
+	// Retry-After header), or 0 if it did not ask. A longer wait than the backoff is
+	// honoured even beyond MaxDelay. Nil always uses the backoff.
+	RetryAfter func(error) time.Duration
+}
+
+// Do calls fn until it succeeds, returns an error Retryable rejects, or MaxAttempts
+// is reached, and returns fn's last error. If ctx is done before an attempt or
+// during a wait, Do stops and returns ctx.Err().
+func Do(ctx context.Context, p Policy, fn func(context.Context) error) error {
+	attempts := max(p.MaxAttempts, 1)
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if p.Retryable != nil && !p.Retryable(err) {
+			return err
+		}
+		if attempt == attempts-1 {
+			break
+		}
+
+		wait := p.jittered(p.Delay(attempt))
+		if p.RetryAfter != nil {
+			wait = max(wait, p.RetryAfter(err))
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return err
+}
+
+// Delay returns the wait after the given failed attempt (0 for the first), before
+// jitter: BaseDelay doubled once per earlier retry, capped at MaxDelay. Without
+// MaxDelay it stops doubling at the longest time.Duration instead of overflowing.
+func (p Policy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < attempt; i++ {
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			break
+		}
+		if delay > math.MaxInt64/2 {
+			delay = math.MaxInt64
+			break
+		}
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// jittered spreads delay randomly by up to Jitter in either direction, so callers
+// retrying the same failure do not all come back at once. MaxDelay still applies.
+func (p Policy) jittered(delay time.Duration) time.Duration {
+	jitter := min(max(p.Jitter, 0), 1)
+	if jitter == 0 || delay <= 0 {
+		return delay
+	}
+	// Past the longest time.Duration the float conversion would overflow
+	spread := float64(delay) * (1 + jitter*(2*rand.Float64()-1))
+	if spread >= math.MaxInt64 {
+		delay = math.MaxInt64
+	} else {
+		delay = time.Duration(spread)
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
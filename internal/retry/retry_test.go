@@ -0,0 +1,158 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient")
+
+func TestDo_SucceedsAfterRetries(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), Policy{MaxAttempts: 5, BaseDelay: time.Millisecond}, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTransient
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Do() = %v after %d calls, want success after 3", err, calls)
+	}
+}
+
+func TestDo_StopsAtMaxAttempts(t *testing.T) {
+	for _, tt := range []struct {
+		maxAttempts int
+		wantCalls   int
+	}{
+		{3, 3},
+		{1, 1},
+		{0, 1}, // Always at least one attempt
+	} {
+		calls := 0
+		err := Do(context.Background(), Policy{MaxAttempts: tt.maxAttempts, BaseDelay: time.Millisecond}, func(context.Context) error {
+			calls++
+			return errTransient
+		})
+		if !errors.Is(err, errTransient) || calls != tt.wantCalls {
+			t.Errorf("MaxAttempts %d: Do() = %v after %d calls, want the last error after %d", tt.maxAttempts, err, calls, tt.wantCalls)
+		}
+	}
+}
+
+func TestDo_Retryable(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	calls := 0
+	policy := Policy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Millisecond,
+		Retryable:   func(err error) bool { return errors.Is(err, errTransient) },
+	}
+	err := Do(context.Background(), policy, func(context.Context) error {
+		calls++
+		if calls == 1 {
+			return errTransient
+		}
+		return errPermanent
+	})
+	if !errors.Is(err, errPermanent) || calls != 2 {
+		t.Errorf("Do() = %v after %d calls, want the permanent error on the 2nd call", err, calls)
+	}
+}
+
+func TestDo_ContextCancellation(t *testing.T) {
+	// Cancelled during a wait: returns promptly with ctx.Err()
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	start := time.Now()
+	err := Do(ctx, Policy{MaxAttempts: 3, BaseDelay: time.Hour}, func(context.Context) error {
+		calls++
+		cancel()
+		return errTransient
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("Do() = %v after %d calls, want context.Canceled after 1", err, calls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Do() took %s after cancellation", elapsed)
+	}
+
+	// Already done: fn is never called
+	calls = 0
+	if err := Do(ctx, Policy{MaxAttempts: 3}, func(context.Context) error {
+		calls++
+		return nil
+	}); !errors.Is(err, context.Canceled) || calls != 0 {
+		t.Errorf("Do() with a done context = %v after %d calls, want context.Canceled without calling fn", err, calls)
+	}
+}
+
+func TestPolicy_Delay(t *testing.T) {
+	p := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for attempt, w := range want {
+		if got := p.Delay(attempt); got != w*time.Millisecond {
+			t.Errorf("Delay(%d) = %s, want %s", attempt, got, w*time.Millisecond)
+		}
+	}
+
+	// Without MaxDelay, delays keep doubling up to the longest time.Duration
+	if got := (Policy{BaseDelay: time.Second}).Delay(10); got != 1024*time.Second {
+		t.Errorf("Delay(10) = %s, want 1024s", got)
+	}
+	for _, attempt := range []int{40, 63, 64, 1000} {
+		if got := (Policy{BaseDelay: time.Second}).Delay(attempt); got != math.MaxInt64 {
+			t.Errorf("Delay(%d) = %s, want the longest duration", attempt, got)
+		}
+	}
+	if got := (Policy{BaseDelay: time.Second, Jitter: 0.5}).jittered(math.MaxInt64); got <= 0 {
+		t.Errorf("jittered(longest duration) = %s, want a positive duration", got)
+	}
+}
+
+func TestDo_RetryAfter(t *testing.T) {
+	// The server's wait is used when longer than the backoff, even beyond MaxDelay
+	policy := Policy{
+		MaxAttempts: 2,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+		RetryAfter:  func(error) time.Duration { return 50 * time.Millisecond },
+	}
+	start := time.Now()
+	_ = Do(context.Background(), policy, func(context.Context) error { return errTransient })
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Do() retried after %s, want the 50ms the server asked for", elapsed)
+	}
+
+	// A shorter wait than the backoff does not shorten it
+	policy = Policy{
+		MaxAttempts: 2,
+		BaseDelay:   50 * time.Millisecond,
+		RetryAfter:  func(error) time.Duration { return time.Millisecond },
+	}
+	start = time.Now()
+	_ = Do(context.Background(), policy, func(context.Context) error { return errTransient })
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Do() retried after %s, want the 50ms backoff", elapsed)
+	}
+}
+
+func TestPolicy_Jitter(t *testing.T) {
+	p := Policy{Jitter: 0.5, MaxDelay: 1200 * time.Millisecond}
+	for i := 0; i < 1000; i++ {
+		got := p.jittered(time.Second)
+		if got < 500*time.Millisecond || got > 1200*time.Millisecond {
+			t.Fatalf("jittered(1s) = %s, want within [500ms, 1.2s]", got)
+		}
+	}
+	if got := (Policy{}).jittered(time.Second); got != time.Second {
+		t.Errorf("jittered(1s) without Jitter = %s, want 1s", got)
+	}
+	if got := (Policy{Jitter: 5}).jittered(time.Second); got < 0 || got > 2*time.Second {
+		t.Errorf("jittered(1s) with Jitter clamped to 1 = %s, want within [0, 2s]", got)
+	}
+}